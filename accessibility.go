@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Accessibility mode trades decoration for plain, predictable output: no
+// animations, the terminal's own colors, spelled out role labels and no box
+// drawing characters for screen readers to announce.
+
+const (
+	accessiblePromptPrefix   = "User: "
+	accessibleResponsePrefix = "Assistant: "
+	accessiblePlaceholder    = "waiting for reply"
+	accessibleTextareaPrompt = "> "
+	accessibleTabSeparator   = " | "
+)
+
+// ApplyAccessibility replaces the theme and icons in accessibility mode.
+func ApplyAccessibility() {
+	if !cfg.Accessible {
+		return
+	}
+
+	cfg.Theme.PromptPrefix = accessiblePromptPrefix
+	cfg.Theme.ResponsePrefix = accessibleResponsePrefix
+	icons = asciiIcons
+}
+
+// Border is the border of all panes, blank in accessibility mode so the
+// layout stays the same, and none in compact mode.
+func Border() lipgloss.Border {
+	if Compact() {
+		return lipgloss.Border{}
+	}
+	if cfg.Accessible {
+		return lipgloss.HiddenBorder()
+	}
+	return lipgloss.RoundedBorder()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// HandleGlobalKey runs bindings that work regardless of the focused pane.
+func HandleGlobalKey(m *model, msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return Quit(m), true
+	case key.Matches(msg, m.keys.Focus):
+		m.NextFocus()
+	case key.Matches(msg, m.keys.Help):
+		m.help.ShowAll = !m.help.ShowAll
+	case key.Matches(msg, m.keys.Cancel):
+		// The first press shows a reply being typed out in full
+		if m.typing != nil {
+			m.FinishTyping()
+		} else {
+			CancelRequest(m)
+		}
+	case key.Matches(msg, m.keys.Undo):
+		UndoSend(m)
+	case key.Matches(msg, m.keys.Copy):
+		CopyLastReply(m)
+	case key.Matches(msg, m.keys.Select):
+		m.StartSelection()
+	case key.Matches(msg, m.keys.Jump):
+		m.StartJump()
+	case key.Matches(msg, m.keys.Palette):
+		m.OpenPalette()
+	case key.Matches(msg, m.keys.Sidebar):
+		ToggleSidebar(m)
+	case key.Matches(msg, m.keys.Inspector):
+		m.inspector.visible = !m.inspector.visible
+	case key.Matches(msg, m.keys.RawText):
+		return m.ToggleRawText(), true
+	case key.Matches(msg, m.keys.NewTab):
+		return m.NewTab(), true
+	case key.Matches(msg, m.keys.NextTab):
+		return m.SwitchTab(1), true
+	case key.Matches(msg, m.keys.PrevTab):
+		return m.SwitchTab(-1), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// Quit saves the draft and ends the program, printing the draft so it is not
+// lost from the terminal either.
+func Quit(m *model) tea.Cmd {
+	fmt.Println(m.textarea.Value())
+	m.SaveDraft()
+	m.quitting = true
+	return tea.Quit
+}
+
+func ToggleSidebar(m *model) {
+	m.sidebar.visible = !m.sidebar.visible
+	if m.sidebar.visible {
+		m.sidebar.Refresh()
+		m.SetFocus(focusSidebar)
+	} else if m.focus == focusSidebar {
+		m.SetFocus(focusInput)
+	}
+}
+
+// CancelRequest aborts the in-flight completion and drops its placeholder.
+func CancelRequest(m *model) {
+	if !m.request.InFlight() || m.cancelRequest == nil {
+		return
+	}
+
+	m.cancelRequest()
+	m.cancelRequest = nil
+	m.SetRequest(requestCancelled)
+
+	m.messages = m.messages[:len(m.messages)-1]
+	AddNotice(m, "Request cancelled")
+	BroadcastShare(shareEvent{Type: shareError, Content: "request cancelled"})
+	m.DropQueued()
+}
+
+// CopyLastReply puts the latest assistant reply on the system clipboard, or
+// the corrected text of /proofread until the next message is sent.
+func CopyLastReply(m *model) {
+	if m.proofread != "" {
+		if err := clipboard.WriteAll(m.proofread); err != nil {
+			AddNotice(m, "Could not copy: "+err.Error())
+			return
+		}
+		AddNotice(m, "Copied corrected text to clipboard")
+		return
+	}
+
+	reply := lastReply()
+	if reply == "" {
+		AddNotice(m, "Nothing to copy yet")
+		return
+	}
+
+	if err := clipboard.WriteAll(reply); err != nil {
+		AddNotice(m, "Could not copy: "+err.Error())
+		return
+	}
+	AddNotice(m, "Copied last reply to clipboard")
+}
+
+func lastReply() string {
+	for i := len(chatMessages) - 1; i >= 0; i-- {
+		msg := chatMessages[i]
+		if msg.Role == openai.ChatMessageRoleAssistant && msg.Content != "" {
+			return msg.Content
+		}
+	}
+	return ""
+}
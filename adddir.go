@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	maxFileSize  = 256 * 1024
+	maxDirBytes  = 4 * 1024 * 1024
+	maxDirFiles  = 500
+	binarySniffN = 512
+)
+
+type addDirMsg struct {
+	root     string
+	files    int
+	skipped  int
+	chunks   []chunk
+	usage    openai.Usage
+	embedErr error
+	err      error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "add-dir",
+		usage:       "/add-dir <path>",
+		description: "Index a directory so questions can draw on its files",
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				AddNotice(m, "Usage: /add-dir <path>")
+				return nil
+			}
+			AddNotice(m, "Indexing "+args+"…")
+			return AddDirCmd(args)
+		},
+	})
+}
+
+func AddDirCmd(root string) tea.Cmd {
+	return func() tea.Msg {
+		msg := walkDir(root)
+		if vectors != nil && msg.err == nil && len(msg.chunks) > 0 {
+			msg.usage, msg.embedErr = EmbedChunks(ctx, msg.chunks)
+		}
+		return msg
+	}
+}
+
+// HandleAddDir adds the chunks of an indexed directory to the index.
+func HandleAddDir(m *model, msg addDirMsg) {
+	if msg.root == kbRoot {
+		m.kbIndexing = false
+	}
+	if msg.err != nil {
+		AddNotice(m, "Could not index "+msg.root+": "+msg.err.Error())
+		return
+	}
+
+	docIndex = append(docIndex, msg.chunks...)
+	AddNotice(m, fmt.Sprintf("Indexed %d files (%d chunks) from %s, skipped %d", msg.files, len(msg.chunks), msg.root, msg.skipped))
+	if vectors != nil {
+		if m.statusBar.Record(cfg.EmbeddingModel, msg.usage, openai.RateLimitHeaders{}) {
+			m.modal = BudgetWarning(m.statusBar.daily.Cost)
+		}
+		if msg.embedErr != nil {
+			AddNotice(m, "Could not embed them, questions draw on them by shared words: "+msg.embedErr.Error())
+		}
+	}
+}
+
+func walkDir(root string) addDirMsg {
+	result := addDirMsg{root: root}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if !info.IsDir() {
+		result.err = fmt.Errorf("%s is not a directory", root)
+		return result
+	}
+
+	ignore := loadGitignore(root)
+	totalBytes := 0
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || ignore.match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if ignore.match(rel, false) || !d.Type().IsRegular() {
+			return nil
+		}
+
+		if result.files >= maxDirFiles {
+			result.skipped++
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize || totalBytes+int(info.Size()) > maxDirBytes {
+			result.skipped++
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || isBinary(content) {
+			result.skipped++
+			return nil
+		}
+
+		totalBytes += len(content)
+		result.files++
+		result.chunks = append(result.chunks, ChunkText(rel, string(content))...)
+
+		return nil
+	})
+
+	result.err = err
+	return result
+}
+
+func isBinary(content []byte) bool {
+	sniff := content
+	if len(sniff) > binarySniffN {
+		sniff = sniff[:binarySniffN]
+	}
+	return bytes.IndexByte(sniff, 0) != -1
+}
+
+type gitignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+type gitignore []gitignoreRule
+
+// loadGitignore reads the .gitignore at the root of the directory. Only the
+// common subset of the syntax is supported: negation, trailing slashes for
+// directories, leading slashes for anchoring and "**/" prefixes.
+func loadGitignore(root string) gitignore {
+	file, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var rules gitignore
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		rule.pattern = line
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func (g gitignore) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if rule.anchored {
+			matched, _ = filepath.Match(rule.pattern, rel)
+		} else {
+			matched, _ = filepath.Match(rule.pattern, filepath.Base(rel))
+		}
+
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
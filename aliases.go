@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const aliasesFileName = "aliases.json"
+
+// Aliases expand to longer prompts when followed by a space, e.g. "!rev "
+// becomes "Review this code for bugs and style issues: ". They come from the
+// config and from aliases.json, which /aliases manages and which wins for
+// names in both.
+var aliases = map[string]string{}
+
+func init() {
+	registerCommand(command{
+		name:        "aliases",
+		usage:       "/aliases [add <name> <text> | remove <name>]",
+		description: "List, add or remove prompt aliases",
+		run: func(m *model, args string) tea.Cmd {
+			action, rest, _ := strings.Cut(args, " ")
+			name, text, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			text = strings.TrimSpace(text)
+
+			switch {
+			case action == "":
+				AddNotice(m, AliasList())
+			case action == "add" && name != "" && text != "":
+				saved := savedAliases()
+				saved[name] = text
+				if err := saveAliases(saved); err != nil {
+					AddNotice(m, "Could not save alias: "+err.Error())
+					return nil
+				}
+				aliases[name] = text
+				AddNotice(m, fmt.Sprintf("%s now expands to %q", name, text))
+			case action == "remove" && name != "":
+				saved := savedAliases()
+				if _, ok := saved[name]; !ok {
+					if _, ok := cfg.Aliases[name]; ok {
+						AddNotice(m, name+" is set in config.json, remove it there")
+					} else {
+						AddNotice(m, "No alias "+name)
+					}
+					return nil
+				}
+				delete(saved, name)
+				if err := saveAliases(saved); err != nil {
+					AddNotice(m, "Could not save aliases: "+err.Error())
+					return nil
+				}
+				LoadAliases()
+				AddNotice(m, "Removed "+name)
+			default:
+				AddNotice(m, "Usage: /aliases [add <name> <text> | remove <name>]")
+			}
+
+			return nil
+		},
+	})
+}
+
+func aliasesPath() string {
+	return filepath.Join(configDir(), aliasesFileName)
+}
+
+func savedAliases() map[string]string {
+	saved := map[string]string{}
+
+	data, err := os.ReadFile(aliasesPath())
+	if err != nil {
+		return saved
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("Error parsing %s: %v", aliasesPath(), err)
+	}
+	return saved
+}
+
+func saveAliases(saved map[string]string) error {
+	if err := os.MkdirAll(configDir(), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(aliasesPath(), data, 0o644)
+}
+
+// LoadAliases merges the aliases from the config and aliases.json.
+func LoadAliases() {
+	aliases = map[string]string{}
+	for name, text := range cfg.Aliases {
+		aliases[name] = text
+	}
+	for name, text := range savedAliases() {
+		aliases[name] = text
+	}
+}
+
+func AliasList() string {
+	if len(aliases) == 0 {
+		return "No aliases yet, add one with /aliases add <name> <text>"
+	}
+
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + " → " + aliases[name]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExpandAlias replaces an alias at the end of the draft once a space is typed
+// after it.
+func ExpandAlias(ta *textarea.Model, msg tea.Msg) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || keyMsg.Type != tea.KeySpace || len(aliases) == 0 {
+		return
+	}
+
+	draft := ta.Value()
+	if !strings.HasSuffix(draft, " ") {
+		return
+	}
+
+	before := strings.TrimSuffix(draft, " ")
+	start := strings.LastIndexAny(before, " \t\n") + 1
+	text, ok := aliases[before[start:]]
+	if !ok {
+		return
+	}
+
+	ta.SetValue(before[:start] + text + " ")
+	ta.CursorEnd()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	archiveDirName  = "archive"
+	pruneReportRows = 8
+)
+
+// pruneMsg carries the sessions the prune policy would delete, for
+// confirmation.
+type pruneMsg struct {
+	sessions []session
+}
+
+type prunedMsg struct {
+	count int
+	err   error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "archive",
+		usage:       "/archive",
+		description: "Move this session out of the session list and start a new one",
+		run: func(m *model, args string) tea.Cmd {
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the current reply before archiving")
+				return nil
+			}
+			if len(chatMessages) == 0 {
+				AddNotice(m, "Nothing to archive yet")
+				return nil
+			}
+
+			SaveCurrentSession()
+			if err := ArchiveSession(currentSession.ID); err != nil {
+				AddNotice(m, "Could not archive: "+err.Error())
+				return nil
+			}
+
+			title := currentSession.Title
+			currentSession = NewSession()
+			chatMessages = nil
+			m.SetRequest(requestIdle)
+			RebuildTranscript(m)
+			m.sidebar.Refresh()
+			AddNotice(m, "Archived "+title+" to "+archiveDir())
+			return nil
+		},
+	})
+
+	registerCommand(command{
+		name:        "prune",
+		usage:       "/prune",
+		description: "Delete saved sessions outside the prune policy, after confirming",
+		run: func(m *model, args string) tea.Cmd {
+			if cfg.PruneDays <= 0 && cfg.PruneKeep <= 0 {
+				AddNotice(m, "No prune policy, set prune_days or prune_keep in the config")
+				return nil
+			}
+
+			candidates := PruneCandidates(ListSessions(), m.openSessionIDs(), time.Now())
+			if len(candidates) == 0 {
+				AddNotice(m, "Nothing to prune")
+				return nil
+			}
+
+			m.modal = PruneReport(candidates)
+			m.confirmAction = PruneCmd(candidates)
+			return nil
+		},
+	})
+}
+
+func archiveDir() string {
+	return filepath.Join(sessionsDir(), archiveDirName)
+}
+
+// ArchiveSession moves a saved session into the archive directory, where
+// ListSessions no longer sees it.
+func ArchiveSession(id string) error {
+	if err := os.MkdirAll(archiveDir(), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(sessionPath(id), filepath.Join(archiveDir(), id+".json"))
+}
+
+func (m model) openSessionIDs() map[string]bool {
+	ids := map[string]bool{currentSession.ID: true}
+	for _, t := range m.tabs {
+		ids[t.session.ID] = true
+	}
+	return ids
+}
+
+// PruneCandidates returns the sessions, newest first, that are older than
+// prune_days or beyond the newest prune_keep. Open sessions are kept.
+func PruneCandidates(sessions []session, open map[string]bool, now time.Time) []session {
+	cutoff := now.AddDate(0, 0, -cfg.PruneDays)
+
+	var candidates []session
+	for i, s := range sessions {
+		if open[s.ID] {
+			continue
+		}
+
+		tooOld := cfg.PruneDays > 0 && s.Updated.Before(cutoff)
+		tooMany := cfg.PruneKeep > 0 && i >= cfg.PruneKeep
+		if tooOld || tooMany {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
+
+// PruneCheckCmd looks for sessions to prune at startup.
+func PruneCheckCmd() tea.Cmd {
+	if cfg.PruneDays <= 0 && cfg.PruneKeep <= 0 {
+		return nil
+	}
+
+	return func() tea.Msg {
+		return pruneMsg{PruneCandidates(ListSessions(), map[string]bool{currentSession.ID: true}, time.Now())}
+	}
+}
+
+// PruneReport lists what the prune policy is about to delete.
+func PruneReport(sessions []session) string {
+	var policy []string
+	if cfg.PruneDays > 0 {
+		policy = append(policy, fmt.Sprintf("older than %d days", cfg.PruneDays))
+	}
+	if cfg.PruneKeep > 0 {
+		policy = append(policy, fmt.Sprintf("beyond the newest %d", cfg.PruneKeep))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Delete %d saved sessions %s?\n\n", len(sessions), strings.Join(policy, " or "))
+	for _, s := range sessions[:min(len(sessions), pruneReportRows)] {
+		fmt.Fprintf(&b, "%s %s\n", s.Updated.Format("2006-01-02"), truncate(s.Title, viewportTextWidth/2-16))
+	}
+	if len(sessions) > pruneReportRows {
+		fmt.Fprintf(&b, "…and %d more\n", len(sessions)-pruneReportRows)
+	}
+	b.WriteString("\nPress y to delete them, any other key to keep them. Archived sessions are never pruned.")
+
+	return b.String()
+}
+
+func PruneCmd(sessions []session) tea.Cmd {
+	return func() tea.Msg {
+		var msg prunedMsg
+		for _, s := range sessions {
+			if err := os.Remove(sessionPath(s.ID)); err != nil {
+				msg.err = err
+				continue
+			}
+			msg.count++
+		}
+		return msg
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	// Newest first, as ListSessions returns them
+	sessions := []session{
+		{ID: "a", Updated: daysAgo(0)},
+		{ID: "b", Updated: daysAgo(5)},
+		{ID: "c", Updated: daysAgo(20)},
+		{ID: "d", Updated: daysAgo(40)},
+		{ID: "e", Updated: daysAgo(90)},
+	}
+
+	tests := []struct {
+		name string
+		days int
+		keep int
+		open map[string]bool
+		want []string
+	}{
+		{"no policy", 0, 0, nil, nil},
+		{"older than 30 days", 30, 0, nil, []string{"d", "e"}},
+		{"beyond the newest 2", 0, 2, nil, []string{"c", "d", "e"}},
+		{"either rule", 30, 4, nil, []string{"d", "e"}},
+		{"both rules", 10, 4, nil, []string{"c", "d", "e"}},
+		{"open sessions are kept", 30, 0, map[string]bool{"e": true}, []string{"d"}},
+	}
+
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.PruneDays, cfg.PruneKeep = tt.days, tt.keep
+
+			var got []string
+			for _, s := range PruneCandidates(sessions, tt.open, now) {
+				got = append(got, s.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PruneCandidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
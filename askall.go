@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type modelAnswer struct {
+	model   string
+	content string
+	usage   openai.Usage
+	err     error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "ask-all",
+		usage:       "/ask-all <prompt>",
+		description: "Ask every model in ask_all_models and compare the answers",
+		run: func(m *model, args string) tea.Cmd {
+			switch {
+			case args == "":
+				AddNotice(m, "Usage: /ask-all <prompt>")
+				return nil
+			case len(cfg.AskAllModels) == 0:
+				AddNotice(m, "Set ask_all_models in the config first")
+				return nil
+			case m.request.InFlight():
+				AddNotice(m, "Wait for the current reply first")
+				return nil
+			case joinAddr != "":
+				AddNotice(m, "/ask-all is not available in a joined session")
+				return nil
+			}
+
+			requestCtx := BeginExchange(m, args, nil, requestOverrides{})
+
+			return tea.Batch(m.StartSpinner(), AskAllCmd(requestCtx, m.ActiveTabID(), chatMessages, cfg.AskAllModels))
+		},
+	})
+}
+
+// AskAllCmd sends history to every model at once. The answers come back as a
+// single reply with a labeled section per model, so follow-up questions can
+// refer to any of them.
+func AskAllCmd(ctx context.Context, tab int, history []openai.ChatCompletionMessage, models []string) tea.Cmd {
+	history = WithReplyLanguage(slices.Clip(history), cfg.ReplyLanguage)
+
+	return func() tea.Msg {
+		start := time.Now()
+		answers := make([]modelAnswer, len(models))
+
+		var wg sync.WaitGroup
+		for i, model := range models {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				req := openai.ChatCompletionRequest{Model: model, Messages: history}
+				resp, err := callChat(ctx, req)
+				if err == nil && len(resp.Choices) == 0 {
+					err = errNoChoices
+				}
+
+				answers[i] = modelAnswer{model: model, err: err}
+				if err == nil {
+					answers[i].content = resp.Choices[0].Message.Content
+					answers[i].usage = resp.Usage
+				}
+			}()
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return responseMsg{tab: tab, err: ctx.Err()}
+		}
+
+		usage := map[string]openai.Usage{}
+		for _, a := range answers {
+			u := usage[a.model]
+			u.PromptTokens += a.usage.PromptTokens
+			u.CompletionTokens += a.usage.CompletionTokens
+			u.TotalTokens += a.usage.TotalTokens
+			usage[a.model] = u
+		}
+
+		combined := CombineAnswers(answers)
+		return responseMsg{
+			tab:          tab,
+			replies:      []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleAssistant, Content: combined}},
+			message:      combined,
+			usageByModel: usage,
+			latency:      time.Since(start),
+		}
+	}
+}
+
+func CombineAnswers(answers []modelAnswer) string {
+	sections := make([]string, len(answers))
+	for i, a := range answers {
+		if a.err != nil {
+			sections[i] = fmt.Sprintf("**%s** failed: %v", a.model, a.err)
+			continue
+		}
+		sections[i] = fmt.Sprintf("**%s**\n\n%s", a.model, strings.TrimSpace(a.content))
+	}
+	return strings.Join(sections, "\n\n---\n\n")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func init() {
+	registerCommand(command{
+		name:        "model",
+		usage:       "/model [name]",
+		description: "Show or switch the model for the next messages",
+		run: func(m *model, args string) tea.Cmd {
+			// Ollama lists what is pulled and offers to pull the rest
+			if usingOllama() {
+				if args != "" && m.pull != nil {
+					AddNotice(m, "Wait for "+m.pull.model+" to finish pulling")
+					return nil
+				}
+				return OllamaModelsCmd(args)
+			}
+
+			if args == "" {
+				AddNotice(m, "Model: "+modelName+"\nKnown: "+strings.Join(knownModels(), ", "))
+				return nil
+			}
+
+			AddNotice(m, "Switched to "+args)
+			return SwitchModel(m, args)
+		},
+	})
+}
+
+// SwitchModel changes the model used for the next requests of every tab,
+// warming it up on local servers.
+func SwitchModel(m *model, name string) tea.Cmd {
+	modelName = name
+	m.header.modelName = name
+
+	cmd := WarmUpCmd(name)
+	m.header.warming = cmd != nil
+	return cmd
+}
+
+func knownModels() []string {
+	names := make([]string, 0, len(builtinModels)+len(cfg.Models))
+	for name := range builtinModels {
+		names = append(names, name)
+	}
+	for name := range cfg.Models {
+		if _, ok := builtinModels[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RecordReplyModel notes which model wrote the message at index i of the
+// session. It reports whether this is the first reply from a second model,
+// after which every reply is labeled.
+func (s *session) RecordReplyModel(i int, model string) bool {
+	if model == "" {
+		return false
+	}
+	if s.ReplyModels == nil {
+		s.ReplyModels = map[int]string{}
+	}
+
+	mixed := s.MixedModels()
+	s.ReplyModels[i] = model
+	return !mixed && s.MixedModels()
+}
+
+// MixedModels reports whether replies in the session come from more than one
+// model.
+func (s session) MixedModels() bool {
+	first := ""
+	for _, model := range s.ReplyModels {
+		if first == "" {
+			first = model
+		} else if model != first {
+			return true
+		}
+	}
+	return false
+}
+
+// LabelReplies renders the replies of the transcript again with their labels
+// and current content, keeping the notices between them.
+func LabelReplies(m *model) {
+	for j := range m.messages {
+		msg := &m.messages[j]
+		if msg.Kind != kindReply || msg.Index < 0 || msg.Index >= len(chatMessages) {
+			continue
+		}
+		msg.Content = chatMessages[msg.Index].Content
+		msg.Rendered = m.RenderReply(currentSession, msg.Content, msg.Index)
+	}
+
+	UpdateViewport(m)
+}
+
+// RenderReply renders a reply followed by a dim label with its model when
+// the session mixes models, whether the user edited or injected it or it came
+// from the cache, its timings with reply_metrics set, the sources it cites
+// and whether they support it. Claims they do not support are listed below.
+func (m model) RenderReply(s session, message string, i int) string {
+	rendered := m.RenderResponse(message)
+
+	var label []string
+	if model := s.ReplyModels[i]; model != "" && s.MixedModels() {
+		label = append(label, model)
+	}
+	if s.EditedReplies[i] {
+		label = append(label, "edited")
+	}
+	if s.InjectedMessages[i] {
+		label = append(label, "injected")
+	}
+	if s.CachedReplies[i] {
+		label = append(label, "cached")
+	}
+	if metrics, ok := s.Metrics[i]; ok && cfg.ReplyMetrics {
+		label = append(label, metrics.String())
+	}
+	label = append(label, CitationLabel(message, s.Citations[i])...)
+	g, checked := s.Groundings[i]
+	if checked && len(g.Unsupported) == 0 {
+		label = append(label, "grounded")
+	}
+	if len(label) == 0 && len(g.Unsupported) == 0 {
+		return rendered
+	}
+
+	labeled := rendered
+	if len(label) > 0 {
+		indent := strings.Repeat(" ", lipgloss.Width(ResponsePrefix()))
+		labeled += "\n" + m.noticeStyle.Render(indent+strings.Join(label, " · "))
+	}
+	if len(g.Unsupported) > 0 {
+		labeled += "\n" + m.RenderUnsupported(g.Unsupported)
+	}
+
+	return labeled
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var auditMu sync.Mutex
+
+// auditEntry is one line of the audit log. The log is append only and kept
+// separately from sessions, so deleting a session does not remove it.
+type auditEntry struct {
+	Time       time.Time                      `json:"time"`
+	Model      string                         `json:"model"`
+	Parameters map[string]any                 `json:"parameters,omitempty"`
+	Messages   []openai.ChatCompletionMessage `json:"messages"`
+	Response   *openai.ChatCompletionMessage  `json:"response,omitempty"`
+	Usage      openai.Usage                   `json:"usage"`
+	Error      string                         `json:"error,omitempty"`
+}
+
+// AuditRequest appends a completion request and its outcome to the audit log
+// when cfg.AuditLog is set.
+func AuditRequest(req openai.ChatCompletionRequest, resp openai.ChatCompletionResponse, err error) {
+	if cfg.AuditLog == "" {
+		return
+	}
+
+	entry := auditEntry{
+		Time:       time.Now().UTC(),
+		Model:      req.Model,
+		Parameters: requestParameters(req),
+		Messages:   req.Messages,
+		Usage:      resp.Usage,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if len(resp.Choices) > 0 {
+		entry.Response = &resp.Choices[0].Message
+	}
+
+	line, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		log.Printf("Error encoding audit entry: %v", jsonErr)
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(cfg.AuditLog), 0o700); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		return
+	}
+
+	file, err := os.OpenFile(cfg.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("Error writing audit log: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// requestParameters returns every request field set besides the model and
+// messages.
+func requestParameters(req openai.ChatCompletionRequest) map[string]any {
+	req.Messages = nil
+
+	var params map[string]any
+	data, _ := json.Marshal(req)
+	json.Unmarshal(data, &params)
+
+	delete(params, "model")
+	delete(params, "messages")
+	return params
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const apiKeyEnv = "OPENAI_API_KEY"
+
+// Where the API key came from, for diagnosing rejected requests.
+const (
+	sourceEnv      = "environment"
+	sourceDotEnv   = ".env file"
+	sourceAPIKeys  = "api_keys in the config"
+	sourceOAuth    = "OAuth token from /login"
+	sourceNoAuth   = "none, the provider needs no key"
+	sourceReplayed = "none, requests are replayed"
+)
+
+var (
+	credentialSource = sourceReplayed
+	credentialKey    string
+)
+
+// MaskKey keeps enough of a key to tell keys apart, e.g. sk-proj…3f9a.
+func MaskKey(key string) string {
+	if len(key) < 16 {
+		return strings.Repeat("•", len(key))
+	}
+	return key[:7] + "…" + key[len(key)-4:]
+}
+
+// IsAuthError reports whether the API rejected the credentials.
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// AuthDiagnostics explains a 401 or 403: which credential was sent, from
+// where, and to which endpoint.
+func AuthDiagnostics(err error) string {
+	status, code := errorDetails(err)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Authentication failed: %d %s", status, http.StatusText(status))
+	if code != "" {
+		b.WriteString(" (" + code + ")")
+	}
+	b.WriteString("\n\n")
+
+	source, key := credentialSource, credentialKey
+	if len(apiKeys.keys) > 1 {
+		i, active := apiKeys.Active()
+		source = fmt.Sprintf("%s, key %d of %d", source, i+1, len(apiKeys.keys))
+		key = active
+	}
+	if _, ok := cfg.Headers["Authorization"]; ok {
+		source += ", replaced by the Authorization header in headers"
+	}
+
+	fmt.Fprintf(&b, "Credential: %s\n", source)
+	if key != "" {
+		fmt.Fprintf(&b, "Key:        %s\n", MaskKey(key))
+	}
+	fmt.Fprintf(&b, "Endpoint:   %s/chat/completions\n", baseURL)
+	fmt.Fprintf(&b, "Model:      %s\n\n", modelName)
+
+	switch {
+	case status == http.StatusForbidden:
+		b.WriteString("The key was accepted but may not use this model or endpoint. Check its project and permissions, or switch with /model.")
+	case credentialSource == sourceOAuth:
+		b.WriteString("The token was refused, sign in again with /login.")
+	case credentialSource == sourceDotEnv:
+		b.WriteString("Check " + apiKeyEnv + " in .env. A key set in the environment would take precedence.")
+	case credentialSource == sourceEnv:
+		b.WriteString("Check " + apiKeyEnv + " in the environment, it takes precedence over .env.")
+	default:
+		b.WriteString("Check that the key is current and meant for this endpoint.")
+	}
+	b.WriteString("\n\nPress any key to close.")
+
+	return b.String()
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const defaultBatchConcurrency = 4
+
+// batchEntry is one prompt of a batch. Plain text files have one prompt per
+// line, JSONL files {"id": "...", "prompt": "..."} objects.
+type batchEntry struct {
+	ID     string `json:"id,omitempty"`
+	Prompt string `json:"prompt"`
+}
+
+// batchResult is written to the output as one JSON line per prompt, in the
+// order of the prompts.
+type batchResult struct {
+	Index            int     `json:"index"`
+	ID               string  `json:"id,omitempty"`
+	Prompt           string  `json:"prompt"`
+	Model            string  `json:"model"`
+	Answer           string  `json:"answer,omitempty"`
+	Error            string  `json:"error,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	LatencyMs        int64   `json:"latency_ms"`
+}
+
+// RunBatch answers every prompt of a file without the UI, for
+// `bubblechat batch <prompts> [--out answers.jsonl]`. Prompts are independent
+// and run a few at a time, or with --chain one after the other as a single
+// conversation.
+func RunBatch(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	out := flags.String("out", "", "write the answers to this JSONL file instead of stdout")
+	model := flags.String("model", modelName, "model to answer with")
+	system := flags.String("system", "", "system prompt sent ahead of every prompt")
+	concurrency := flags.Int("concurrency", defaultBatchConcurrency, "prompts answered at the same time")
+	chain := flags.Bool("chain", false, "answer the prompts in order as one conversation")
+
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: bubblechat batch [--out answers.jsonl] [--model name] [--system prompt] [--concurrency n] [--chain] <prompts>")
+	}
+	if *concurrency < 1 || *chain {
+		*concurrency = 1
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	entries, err := ParseBatch(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	initializeClient()
+
+	var history []openai.ChatCompletionMessage
+	if *system != "" {
+		history = append(history, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: *system})
+	}
+
+	writer := newBatchWriter(w, len(entries))
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		messages := append(slices.Clip(history), openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: entry.Prompt})
+		run := func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := CompleteOnce(openai.ChatCompletionRequest{Model: *model, Messages: messages})
+			result.Index, result.ID, result.Prompt = i, entry.ID, entry.Prompt
+			writer.Write(result)
+		}
+
+		// Chained prompts see the answers before them, so they wait for each
+		// and stop at the first that fails
+		if *chain {
+			run()
+			answer, ok := writer.Answer(i)
+			if !ok {
+				break
+			}
+			history = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: answer})
+			continue
+		}
+		go run()
+	}
+	wg.Wait()
+
+	fmt.Fprintln(os.Stderr)
+	if writer.err != nil {
+		return writer.err
+	}
+	if writer.failed > 0 {
+		return fmt.Errorf("%d of %d prompts failed", writer.failed, len(entries))
+	}
+	if writer.done < len(entries) {
+		return fmt.Errorf("%d of %d prompts answered", writer.done, len(entries))
+	}
+	return nil
+}
+
+// parseInterspersed parses flags given before, between or after the
+// positional arguments, which it returns.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// ParseBatch reads the prompts of a batch file: JSONL entries when the first
+// line is a JSON object, otherwise one prompt per non-empty line.
+func ParseBatch(data []byte) ([]batchEntry, error) {
+	var entries []batchEntry
+	jsonl := bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		entry := batchEntry{Prompt: text}
+		if jsonl {
+			entry = batchEntry{}
+			if err := json.Unmarshal([]byte(text), &entry); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if entry.Prompt == "" {
+				return nil, fmt.Errorf("line %d: no prompt", line)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no prompts")
+	}
+	return entries, nil
+}
+
+// CompleteOnce requests one completion without the UI, for the batch and run
+// subcommands. Its cost is returned to be added to today's spend.
+func CompleteOnce(req openai.ChatCompletionRequest) batchResult {
+	RequestParameters()(&req)
+	start := time.Now()
+	resp, err := callChat(ctx, req)
+	if err == nil && len(resp.Choices) == 0 {
+		err = errNoChoices
+	}
+
+	result := batchResult{Model: req.Model, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Answer = resp.Choices[0].Message.Content
+	result.PromptTokens = resp.Usage.PromptTokens
+	result.CompletionTokens = resp.Usage.CompletionTokens
+	result.Cost = RequestCost(req.Model, resp.Usage)
+	return result
+}
+
+// batchWriter writes results in the order of the prompts as they finish, and
+// reports the progress on stderr.
+type batchWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	total   int
+	next    int
+	pending map[int]batchResult
+	answers map[int]string
+	done    int
+	failed  int
+	cost    float64
+	daily   dailyUsage
+	err     error
+}
+
+func newBatchWriter(w io.Writer, total int) *batchWriter {
+	return &batchWriter{
+		w:       w,
+		total:   total,
+		pending: map[int]batchResult{},
+		answers: map[int]string{},
+		daily:   LoadDailyUsage(),
+	}
+}
+
+func (b *batchWriter) Write(result batchResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done++
+	if result.Error != "" {
+		b.failed++
+	}
+	b.cost += result.Cost
+	b.daily.Add(result.Cost)
+	if result.Error == "" {
+		b.answers[result.Index] = result.Answer
+	}
+	b.pending[result.Index] = result
+
+	for {
+		next, ok := b.pending[b.next]
+		if !ok {
+			break
+		}
+		delete(b.pending, b.next)
+		b.next++
+
+		line, _ := json.Marshal(next)
+		if _, err := b.w.Write(append(line, '\n')); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%d/%d answered, %d failed, $%.4f", b.done, b.total, b.failed, b.cost)
+}
+
+// Answer is the answer to a finished prompt, not ok when it failed.
+func (b *batchWriter) Answer(index int) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	answer, ok := b.answers[index]
+	return answer, ok
+}
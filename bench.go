@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// `bubblechat bench` is a hidden subcommand for performance work: it times
+// the rendering hot paths on a synthetic transcript, like the benchmarks in
+// bench_test.go, and reports how long frames take to render. Nothing is
+// sent.
+
+const (
+	defaultBenchMessages = 1000
+	defaultBenchFrames   = 50
+)
+
+// benchTopics are the prompts of the synthetic transcript, filled into the
+// canned replies of the fake provider.
+var benchTopics = []string{
+	"flaky integration tests",
+	"caching HTTP responses",
+	"structuring a Go module",
+	"choosing a message queue",
+	"profiling a slow terminal UI",
+}
+
+// BenchTranscript is a synthetic conversation of n messages, prompts taking
+// turns with the canned replies of the fake provider, which have lists, code
+// and tables to render.
+func BenchTranscript(n int) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, n)
+	for i := 0; len(messages) < n; i++ {
+		topic := benchTopics[i%len(benchTopics)]
+		messages = append(messages, UserMessage(fmt.Sprintf("How should I approach %s? (%d)", topic, i+1)))
+		if len(messages) < n {
+			reply := fmt.Sprintf(fakeReplies[i%len(fakeReplies)], topic)
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply})
+		}
+	}
+	return messages
+}
+
+// NewBenchModel is the UI of a terminal of width × height showing
+// transcript, rendered once.
+func NewBenchModel(transcript []openai.ChatCompletionMessage, width, height int) model {
+	m := initialModel()
+	m.windowWidth, m.windowHeight = width, height
+	m.ResizeLayout(width, height)
+
+	currentSession = session{}
+	chatMessages = transcript
+	RebuildTranscript(&m)
+	return m
+}
+
+// RunBench renders a synthetic transcript and prints the time per frame of
+// each hot path.
+func RunBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	messages := flags.Int("messages", defaultBenchMessages, "messages in the synthetic transcript")
+	frames := flags.Int("frames", defaultBenchFrames, "frames timed per path")
+	width := flags.Int("width", viewportWidth, "terminal width")
+	height := flags.Int("height", 40, "terminal height")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 || *messages < 1 || *frames < 1 {
+		return errors.New("usage: bubblechat bench [--messages n] [--frames n] [--width cols] [--height rows]")
+	}
+
+	transcript := BenchTranscript(*messages)
+	start := time.Now()
+	m := NewBenchModel(transcript, *width, *height)
+	open := []time.Duration{time.Since(start)}
+
+	timeFrames := func(frame func()) []time.Duration {
+		times := make([]time.Duration, *frames)
+		for i := range times {
+			start := time.Now()
+			frame()
+			times[i] = time.Since(start)
+		}
+		return times
+	}
+
+	results := []struct {
+		name  string
+		times []time.Duration
+	}{
+		{"open (markdown, cold)", open},
+		{"update viewport", timeFrames(func() { UpdateViewport(&m) })},
+		{"view", timeFrames(func() { _ = m.View() })},
+		{"scroll and view", timeFrames(func() {
+			m.viewport.LineUp(3)
+			_ = m.View()
+		})},
+		{"reply and view", timeFrames(func() {
+			reply := fmt.Sprintf(fakeReplies[len(m.messages)%len(fakeReplies)], "benchmarks")
+			m.messages = append(m.messages, Message{Kind: kindReply, Content: reply, Index: -1, Rendered: m.RenderResponse(reply)})
+			UpdateViewport(&m)
+			_ = m.View()
+		})},
+	}
+
+	fmt.Printf("%d messages, %d lines, %d×%d\n\n", len(transcript), len(m.viewportLines), *width, *height)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "path\tframes\tmean\tp50\tp95\tmax\t")
+	for _, r := range results {
+		mean, p50, p95, slowest := frameStats(r.times)
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t\n", r.name, len(r.times),
+			formatFrame(mean), formatFrame(p50), formatFrame(p95), formatFrame(slowest))
+	}
+	return w.Flush()
+}
+
+// frameStats summarizes the render times of frames.
+func frameStats(times []time.Duration) (mean, p50, p95, slowest time.Duration) {
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, t := range sorted {
+		total += t
+	}
+	percentile := func(p int) time.Duration {
+		return sorted[min(len(sorted)*p/100, len(sorted)-1)]
+	}
+	return total / time.Duration(len(sorted)), percentile(50), percentile(95), sorted[len(sorted)-1]
+}
+
+func formatFrame(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
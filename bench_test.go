@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The benchmarks render a synthetic transcript of a thousand messages in the
+// default config, with a config directory of their own like --demo, e.g.
+// `go test -run '^$' -bench . -benchmem`.
+
+const benchTranscriptSize = 1000
+
+func TestMain(m *testing.M) {
+	if err := StartDemo(); err != nil {
+		panic(err)
+	}
+	cfg = DefaultConfig()
+	ApplyDemoConfig()
+
+	code := m.Run()
+	os.RemoveAll(demoConfigDir)
+	os.Exit(code)
+}
+
+func newBenchModel(b *testing.B) model {
+	b.Helper()
+	return NewBenchModel(BenchTranscript(benchTranscriptSize), viewportWidth, 40)
+}
+
+// BenchmarkUpdateViewport lays out the transcript with every message in the
+// render cache, as on most frames.
+func BenchmarkUpdateViewport(b *testing.B) {
+	m := newBenchModel(b)
+	b.ResetTimer()
+	for range b.N {
+		UpdateViewport(&m)
+	}
+}
+
+// BenchmarkRenderMessagesCold renders every message through markdown, as
+// after a resize or theme switch.
+func BenchmarkRenderMessagesCold(b *testing.B) {
+	m := newBenchModel(b)
+	b.ResetTimer()
+	for range b.N {
+		m.renderCache = renderCache{}
+		RenderMessages(&m)
+	}
+}
+
+// BenchmarkMarkdown renders single replies through glamour.
+func BenchmarkMarkdown(b *testing.B) {
+	m := newBenchModel(b)
+	var replies []string
+	for _, msg := range BenchTranscript(2 * len(fakeReplies)) {
+		if msg.Role == openai.ChatMessageRoleAssistant {
+			replies = append(replies, m.RenderResponse(msg.Content))
+		}
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := m.renderer.Render(replies[i%len(replies)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderResponse wraps and styles single replies, tables included.
+func BenchmarkRenderResponse(b *testing.B) {
+	m := newBenchModel(b)
+	transcript := BenchTranscript(2 * len(fakeReplies))
+	b.ResetTimer()
+	for i := range b.N {
+		m.RenderResponse(transcript[2*(i%len(fakeReplies))+1].Content)
+	}
+}
+
+// BenchmarkView draws a whole frame.
+func BenchmarkView(b *testing.B) {
+	m := newBenchModel(b)
+	b.ResetTimer()
+	for range b.N {
+		_ = m.View()
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// With cache set, chat completions are kept on disk by their request, model,
+// messages and parameters alike, and an identical request to the same
+// endpoint is answered from there at once and for free. Replies from the cache
+// are labeled "cached". The entries are sealed like sessions when encryption
+// is on, expire after cacheMaxAge and the oldest are evicted past
+// cacheMaxSize.
+
+const (
+	cacheDirName = "cache"
+	cacheMaxAge  = 30 * 24 * time.Hour
+	cacheMaxSize = 64 << 20
+)
+
+// cacheHitKey carries a *bool in a request context, which CacheMiddleware
+// sets to whether the answer came from the cache.
+type cacheHitKey struct{}
+
+func init() {
+	registerCommand(command{
+		name:        "cache",
+		usage:       "/cache [clear]",
+		description: "Show or clear the response cache",
+		complete: func(prefix string) []string {
+			if strings.HasPrefix("clear", prefix) {
+				return []string{"clear"}
+			}
+			return nil
+		},
+		run: func(m *model, args string) tea.Cmd {
+			switch args {
+			case "":
+				entries, size := CacheStats()
+				state := "off, set cache in the config to turn it on"
+				if cfg.Cache {
+					state = "on"
+				}
+				AddNotice(m, fmt.Sprintf("Response cache %s: %d replies, %s", state, entries, formatSize(size)))
+			case "clear":
+				if err := os.RemoveAll(cacheDir()); err != nil {
+					AddNotice(m, "Could not clear the cache: "+err.Error())
+					return nil
+				}
+				AddNotice(m, "Cleared the response cache")
+			default:
+				AddNotice(m, "Usage: /cache [clear]")
+			}
+			return nil
+		},
+	})
+}
+
+func cacheDir() string {
+	return filepath.Join(configDir(), cacheDirName)
+}
+
+// cacheKey hashes everything in a request that shapes its answer, including
+// the provider it goes to.
+func cacheKey(req openai.ChatCompletionRequest) string {
+	data, _ := json.Marshal(struct {
+		Provider string                       `json:"provider"`
+		BaseURL  string                       `json:"base_url"`
+		Request  openai.ChatCompletionRequest `json:"request"`
+	}{cfg.Provider, baseURL, req})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// WithCacheReport returns a context for a request, and the flag telling
+// whether its answer came from the cache once it is sent.
+func WithCacheReport(ctx context.Context) (context.Context, *bool) {
+	hit := new(bool)
+	return context.WithValue(ctx, cacheHitKey{}, hit), hit
+}
+
+// CacheMiddleware answers requests from the cache when it is on, and keeps
+// the answers to the others. Cached answers come without usage, as they cost
+// nothing.
+func CacheMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		hit, _ := ctx.Value(cacheHitKey{}).(*bool)
+		if hit != nil {
+			*hit = false
+		}
+		if !cfg.Cache {
+			return next(ctx, req)
+		}
+
+		path := filepath.Join(cacheDir(), cacheKey(req)+".json")
+		if resp, ok := readCached(path); ok {
+			if hit != nil {
+				*hit = true
+			}
+			return resp, nil
+		}
+
+		resp, err := next(ctx, req)
+		if err == nil {
+			writeCached(path, resp)
+			EvictCached()
+		}
+		return resp, err
+	}
+}
+
+func readCached(path string) (openai.ChatCompletionResponse, bool) {
+	var resp openai.ChatCompletionResponse
+	if info, err := os.Stat(path); err != nil || time.Since(info.ModTime()) > cacheMaxAge {
+		return resp, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return resp, false
+	}
+	if data, err = OpenBytes(data); err != nil {
+		return resp, false
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || len(resp.Choices) == 0 {
+		return resp, false
+	}
+	resp.Usage = openai.Usage{}
+	return resp, true
+}
+
+func writeCached(path string, resp openai.ChatCompletionResponse) {
+	data, err := json.Marshal(resp)
+	if err == nil {
+		data, err = SealBytes(data)
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		log.Printf("Error writing to the response cache: %v", err)
+	}
+}
+
+// EvictCached removes the entries older than cacheMaxAge, and then the oldest
+// ones until the cache fits in cacheMaxSize.
+func EvictCached() {
+	files, _ := os.ReadDir(cacheDir())
+
+	var kept []os.FileInfo
+	var size int64
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if time.Since(info.ModTime()) > cacheMaxAge {
+			removeCached(info.Name())
+			continue
+		}
+		kept = append(kept, info)
+		size += info.Size()
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].ModTime().Before(kept[j].ModTime())
+	})
+	for _, info := range kept {
+		if size <= cacheMaxSize {
+			break
+		}
+		removeCached(info.Name())
+		size -= info.Size()
+	}
+}
+
+func removeCached(name string) {
+	if err := os.Remove(filepath.Join(cacheDir(), name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error evicting from the response cache: %v", err)
+	}
+}
+
+// CacheStats counts the cached replies and their size on disk.
+func CacheStats() (entries int, size int64) {
+	files, _ := os.ReadDir(cacheDir())
+	for _, file := range files {
+		if info, err := file.Info(); err == nil && info.Mode().IsRegular() {
+			entries++
+			size += info.Size()
+		}
+	}
+	return entries, size
+}
+
+// MarkCached labels the reply at index i as answered from the cache.
+func (s *session) MarkCached(i int) {
+	if s.CachedReplies == nil {
+		s.CachedReplies = map[int]bool{}
+	}
+	s.CachedReplies[i] = true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// A cassette records API responses keyed by a hash of the request so they can
+// be served back later without network access, e.g. for demos and UI tests.
+type cassette struct {
+	path      string
+	replay    bool
+	transport http.RoundTripper
+
+	mu      sync.Mutex
+	entries map[string]cassetteEntry
+}
+
+type cassetteEntry struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+func NewCassette(path string, replay bool) (*cassette, error) {
+	c := &cassette{
+		path:      path,
+		replay:    replay,
+		transport: http.DefaultTransport,
+		entries:   map[string]cassetteEntry{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if replay || !os.IsNotExist(err) {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func requestKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := requestKey(req, body)
+
+	if c.replay {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		c.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL)
+		}
+		return entry.response(req), nil
+	}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := cassetteEntry{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(respBody),
+	}
+	if err := c.save(key, entry); err != nil {
+		return nil, err
+	}
+
+	return entry.response(req), nil
+}
+
+func (c *cassette) save(key string, entry cassetteEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+func (e cassetteEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(e.Body))),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
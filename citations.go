@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// The chunks retrieved for a prompt are numbered in the context sent with it
+// and the model cites them as [1], [2]. The sources a reply cites are listed
+// below it, and in selection mode a number key opens the chunk cited with it
+// in an overlay.
+
+// citation is a retrieved chunk a reply may cite, by its number less one.
+type citation struct {
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// citationOverlay is a cited chunk shown over the transcript until a key is
+// pressed.
+type citationOverlay struct {
+	number   int
+	citation citation
+}
+
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+var citationKey = key.NewBinding(key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"))
+
+// RecordCitations keeps the chunks retrieved for the reply at index i.
+func (s *session) RecordCitations(i int, chunks []chunk) {
+	if len(chunks) == 0 {
+		return
+	}
+	if s.Citations == nil {
+		s.Citations = map[int][]citation{}
+	}
+	citations := make([]citation, len(chunks))
+	for n, c := range chunks {
+		citations[n] = citation{Source: c.source, Text: c.text}
+	}
+	s.Citations[i] = citations
+}
+
+// CitedNumbers lists the numbers of citations a reply cites, in order.
+func CitedNumbers(reply string, citations []citation) []int {
+	var cited []int
+	for _, match := range citationMarker.FindAllStringSubmatch(reply, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err == nil && n >= 1 && n <= len(citations) && !slices.Contains(cited, n) {
+			cited = append(cited, n)
+		}
+	}
+	slices.Sort(cited)
+	return cited
+}
+
+// CitationLabel lists the sources a reply cites, e.g. "[1] docs/setup.md".
+func CitationLabel(reply string, citations []citation) []string {
+	var label []string
+	for _, n := range CitedNumbers(reply, citations) {
+		label = append(label, fmt.Sprintf("[%d] %s", n, citations[n-1].Source))
+	}
+	return label
+}
+
+// OpenCitation shows the chunk the reply at block i of m.messages cites as
+// [n].
+func OpenCitation(m *model, i, n int) {
+	reply := m.replyIndex(i)
+	if reply < 0 {
+		AddNotice(m, "Only replies cite sources")
+		return
+	}
+	citations := currentSession.Citations[reply]
+	if n > len(citations) {
+		AddNotice(m, fmt.Sprintf("The reply has no source [%d]", n))
+		return
+	}
+	m.citation = &citationOverlay{number: n, citation: citations[n-1]}
+}
+
+func (c citationOverlay) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(Color(inspectorTitleColor)).Bold(true)
+	width, height, maxHeight := OverlaySize()
+
+	text := wordwrap.String(strings.TrimSpace(c.citation.Text), width-2)
+	body := titleStyle.Render(fmt.Sprintf("[%d] %s", c.number, c.citation.Source)) + "\n\n" + text
+
+	return lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(focusColor)).
+		Padding(0, 1).
+		Width(width).
+		Height(height).
+		MaxHeight(maxHeight).
+		Render(body)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var noColor bool
+
+// Hand picked 256 and 16 color equivalents of the palette. termenv's nearest
+// match is fine for 256 colors but turns most pastels into plain white or
+// grey with 16.
+var palette = map[string]lipgloss.CompleteColor{
+	"#cda9d6": {TrueColor: "#cda9d6", ANSI256: "182", ANSI: "13"},
+	"#fcfcfc": {TrueColor: "#fcfcfc", ANSI256: "231", ANSI: "15"},
+	"#b7e4cf": {TrueColor: "#b7e4cf", ANSI256: "152", ANSI: "14"},
+	"#e2cdb5": {TrueColor: "#e2cdb5", ANSI256: "187", ANSI: "11"},
+	"#636363": {TrueColor: "#636363", ANSI256: "241", ANSI: "8"},
+	"#e88388": {TrueColor: "#e88388", ANSI256: "174", ANSI: "9"},
+	"#FF00FF": {TrueColor: "#FF00FF", ANSI256: "201", ANSI: "5"},
+}
+
+// Color returns the palette entry for a hex color, degrading to its chosen
+// equivalents on limited terminals. Other colors are left to termenv.
+// Accessibility mode keeps the terminal's own, high contrast, colors.
+func Color(hex string) lipgloss.TerminalColor {
+	if cfg.Accessible {
+		return lipgloss.NoColor{}
+	}
+	if c, ok := palette[hex]; ok {
+		return c
+	}
+	return lipgloss.Color(hex)
+}
+
+// ApplyColorProfile turns colors off for --no-color. NO_COLOR and the
+// terminal's color support are already picked up by lipgloss.
+func ApplyColorProfile() {
+	if noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
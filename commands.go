@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	commandPrefix = "/"
+
+	noticeColor  = "#636363"
+	noticePrefix = "· "
+)
+
+// A command is a slash command typed into the textarea, e.g. "/add-dir ./src".
+// run may update the model directly and return a tea.Cmd for async work.
+// complete, if set, suggests values for the first argument.
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(m *model, args string) tea.Cmd
+	complete    func(prefix string) []string
+}
+
+var commands = map[string]command{}
+
+func registerCommand(c command) {
+	commands[c.name] = c
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isCommand(input string) bool {
+	return strings.HasPrefix(input, commandPrefix)
+}
+
+func RunCommand(m *model, input string) tea.Cmd {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, commandPrefix), " ")
+	args = strings.TrimSpace(args)
+
+	c, ok := commands[name]
+	if !ok {
+		AddNotice(m, "Unknown command: "+commandPrefix+name)
+		return nil
+	}
+
+	return c.run(m, args)
+}
+
+// AddNotice appends a dim informational line to the transcript. Notices are
+// only shown in the viewport and never sent to the model.
+func AddNotice(m *model, text string) {
+	AddMessage(m, m.NoticeMessage(text))
+}
+
+// AddBlock appends an already styled block to the transcript.
+func AddBlock(m *model, block string) {
+	AddMessage(m, BlockMessage(block))
+}
+
+// AddMessage appends msg to the transcript.
+func AddMessage(m *model, msg Message) {
+	// Keep the pending response placeholder last while waiting
+	if m.request.InFlight() {
+		last := len(m.messages) - 1
+		m.messages = append(m.messages[:last], msg, m.messages[last])
+	} else {
+		m.messages = append(m.messages, msg)
+	}
+
+	UpdateViewport(m)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Compact mode fits bubblechat into terminals narrower than the full layout,
+// e.g. a tmux split: no borders, one character role prefixes and a one line
+// header, with the conversation filling the window. The sidebar takes the
+// whole window while it is open.
+
+// Rows besides the viewport: tab bar, header, completion, input, status line
+// and help
+const compactChromeHeight = 6
+
+// compactWidth and compactHeight are the viewport size in compact mode, zero
+// in the full layout.
+var compactWidth, compactHeight int
+
+// Compact reports whether the compact layout is in use.
+func Compact() bool {
+	return compactWidth > 0
+}
+
+// textWidth is the width of the viewport text.
+func textWidth() int {
+	if Compact() {
+		return compactWidth - viewportPadding
+	}
+	return viewportTextWidth
+}
+
+// bodyWidth and bodyHeight are the size of the viewport, which the inspector,
+// palette and modals cover.
+func bodyWidth() int {
+	if Compact() {
+		return compactWidth
+	}
+	return viewportWidth
+}
+
+func bodyHeight() int {
+	if Compact() {
+		return compactHeight
+	}
+	return viewportHeight + 2
+}
+
+// OverlaySize is the size of the inspector and the palette, which in the full
+// layout have room for their borders around the viewport's size.
+func OverlaySize() (width, height, maxHeight int) {
+	if Compact() {
+		return compactWidth, compactHeight, compactHeight
+	}
+	return viewportWidth, viewportHeight + 2, viewportHeight + 4
+}
+
+// PromptPrefix and ResponsePrefix are the theme's role prefixes, cut to their
+// first character in compact mode. Accessibility labels stay spelled out.
+func PromptPrefix() string {
+	return shortPrefix(cfg.Theme.PromptPrefix)
+}
+
+func ResponsePrefix() string {
+	return shortPrefix(cfg.Theme.ResponsePrefix)
+}
+
+func shortPrefix(prefix string) string {
+	trimmed := strings.TrimSpace(prefix)
+	if !Compact() || cfg.Accessible || trimmed == "" {
+		return prefix
+	}
+	return string([]rune(trimmed)[:1]) + " "
+}
+
+// ResizeLayout switches between the full and the compact layout for a
+// window size, and resizes the compact layout to fill the window.
+func (m *model) ResizeLayout(width, height int) {
+	nextWidth, nextHeight := 0, 0
+	if width < viewportWidth {
+		nextWidth, nextHeight = width, max(height-compactChromeHeight, 3)
+	}
+	if nextWidth == compactWidth && nextHeight == compactHeight {
+		return
+	}
+	relayout := nextWidth != compactWidth
+	compactWidth, compactHeight = nextWidth, nextHeight
+
+	m.header.style = HeaderStyle()
+	m.viewport.Width, m.viewport.Height = bodyWidth(), bodyHeight()
+	m.viewport.Style = ViewportStyle()
+	m.textarea.FocusedStyle.Base, m.textarea.BlurredStyle.Base = TextareaStyles()
+	m.textarea.SetWidth(bodyWidth() - m.textarea.FocusedStyle.Base.GetHorizontalFrameSize())
+	m.help.Width = bodyWidth()
+	m.SetRenderWidth(viewportContentWidth(m.viewport))
+
+	if !relayout {
+		UpdateViewport(m)
+		return
+	}
+
+	// Messages are wrapped when rendered, so render them again at the new
+	// width, like when switching themes
+	m.RerenderTabs()
+}
+
+// CompactView stacks the parts of the compact layout, with an open
+// completion list drawn over the bottom of the body instead of growing the
+// view past the window.
+func (m model) CompactView(body, completion, status, footer string) string {
+	if m.sidebar.visible {
+		body = lipgloss.NewStyle().MaxHeight(compactHeight).Render(m.sidebar.View())
+	} else if extra := lipgloss.Height(completion) - 1; extra > 0 {
+		lines := strings.Split(body, "\n")
+		body = strings.Join(lines[:max(len(lines)-extra, 0)], "\n")
+	}
+
+	clip := lipgloss.NewStyle().MaxWidth(m.windowWidth)
+	return clip.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.TabBarView(),
+		m.header.View(),
+		body,
+		completion,
+		UnderlineMisspelled(m.textarea.View(), m.textarea.Value()),
+		status,
+		footer,
+	))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	mentionPrefix      = "@"
+	maxCompletions     = 8
+	mentionContextHead = "Contents of %s, mentioned by the user:\n\n```\n%s\n```"
+)
+
+var mention = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// completion suggests slash commands and file paths for the word being typed
+// at the end of the draft.
+type completion struct {
+	items     []string
+	selected  int
+	start     int
+	dismissed string
+}
+
+type completionKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Accept key.Binding
+	Close  key.Binding
+}
+
+var completionKeys = completionKeyMap{
+	Up:     key.NewBinding(key.WithKeys("up")),
+	Down:   key.NewBinding(key.WithKeys("down")),
+	Accept: key.NewBinding(key.WithKeys("tab")),
+	Close:  key.NewBinding(key.WithKeys("esc")),
+}
+
+func (c completion) Visible() bool {
+	return len(c.items) > 0
+}
+
+// Refresh updates the suggestions for draft. Commands are completed while
+// they are the only word, then their first argument if they can complete it,
+// and paths after an @ anywhere.
+func (c *completion) Refresh(draft string) {
+	c.start = strings.LastIndexAny(draft, " \t\n") + 1
+	word := draft[c.start:]
+
+	var items []string
+	switch {
+	case word == "" || word == c.dismissed:
+	case c.start == 0 && isCommand(word):
+		for _, name := range commandNames() {
+			if strings.HasPrefix(commandPrefix+name, word) {
+				items = append(items, commandPrefix+name+" ")
+			}
+		}
+	case argumentCompleter(draft[:c.start]) != nil:
+		items = argumentCompleter(draft[:c.start])(word)
+	case strings.HasPrefix(word, mentionPrefix):
+		items = completePath(strings.TrimPrefix(word, mentionPrefix))
+		for i := range items {
+			items[i] = mentionPrefix + items[i]
+		}
+	}
+
+	if c.dismissed != word {
+		c.dismissed = ""
+	}
+	// Nothing to offer once the word is complete
+	if len(items) == 1 && strings.TrimSpace(items[0]) == word {
+		items = nil
+	}
+	if len(items) > maxCompletions {
+		items = items[:maxCompletions]
+	}
+
+	c.items = items
+	c.selected = min(c.selected, max(len(items)-1, 0))
+}
+
+// argumentCompleter returns the completion of the command in head, when head
+// is a command followed by its first argument.
+func argumentCompleter(head string) func(prefix string) []string {
+	name, ok := strings.CutPrefix(strings.TrimSpace(head), commandPrefix)
+	if !ok || name == "" || strings.ContainsAny(name, " \t\n") {
+		return nil
+	}
+	return commands[name].complete
+}
+
+// completePath lists the entries of the directory in prefix that start with
+// its last element. Directories end in a slash so they can be descended into.
+func completePath(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+
+	entries, err := os.ReadDir(filepath.Join(".", dir))
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		paths = append(paths, dir+name)
+		if len(paths) == maxCompletions {
+			break
+		}
+	}
+
+	return paths
+}
+
+// HandleCompletionKey moves through and accepts suggestions while they are
+// shown. Other keys go on to the textarea.
+func HandleCompletionKey(m *model, msg tea.KeyMsg) bool {
+	c := &m.completion
+	if !c.Visible() || m.focus != focusInput {
+		return false
+	}
+
+	switch {
+	case key.Matches(msg, completionKeys.Up):
+		c.selected = max(c.selected-1, 0)
+	case key.Matches(msg, completionKeys.Down):
+		c.selected = min(c.selected+1, len(c.items)-1)
+	case key.Matches(msg, completionKeys.Accept):
+		draft := m.textarea.Value()
+		m.textarea.SetValue(draft[:c.start] + c.items[c.selected])
+		m.textarea.CursorEnd()
+		c.selected = 0
+		c.Refresh(m.textarea.Value())
+	case key.Matches(msg, completionKeys.Close):
+		draft := m.textarea.Value()
+		c.dismissed = draft[c.start:]
+		c.items = nil
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (c completion) View() string {
+	if !c.Visible() {
+		return ""
+	}
+
+	selected := lipgloss.NewStyle().Foreground(Color(focusColor))
+	lines := make([]string, len(c.items))
+	for i, item := range c.items {
+		if i == c.selected {
+			lines[i] = selected.Render("▸ " + item)
+		} else {
+			lines[i] = "  " + item
+		}
+	}
+
+	return lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(noticeColor)).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// MentionAttachments returns the files mentioned with @path in message as
+// context messages to send ahead of it. Files with a summary are attached as
+// the summary.
+func MentionAttachments(m *model, message string, summaries map[string]string) []openai.ChatCompletionMessage {
+	var attachments []openai.ChatCompletionMessage
+	for _, match := range mention.FindAllStringSubmatch(message, -1) {
+		path := match[1]
+
+		if summary, ok := summaries[path]; ok {
+			attachments = append(attachments, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: fmt.Sprintf(summaryContextHead, path, summary),
+			})
+			AddNotice(m, "Attached a summary of "+path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.Size() > maxFileSize {
+			AddNotice(m, fmt.Sprintf("Not attaching %s, it is larger than %d KB", path, maxFileSize/1024))
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || isBinary(content) {
+			AddNotice(m, "Could not attach "+path)
+			continue
+		}
+
+		attachments = append(attachments, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: fmt.Sprintf(mentionContextHead, path, content),
+		})
+		AddNotice(m, "Attached "+path)
+	}
+	return attachments
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appName        = "bubblechat"
+	configFileName = "config.json"
+)
+
+// Config holds user settings read from config.json in the user config
+// directory, e.g. ~/.config/bubblechat/config.json. Missing fields keep their
+// defaults.
+type Config struct {
+	// Let the model fetch web pages on its own through a tool call
+	FetchTool bool `json:"fetch_tool"`
+
+	// Warn once today's estimated spend in USD passes this, 0 disables
+	DailyBudget float64 `json:"daily_budget"`
+
+	// Key bindings per action, see keyMap
+	Keys map[string][]string `json:"keys"`
+
+	// Textarea editing bindings, "default" or "emacs"
+	InputMode string `json:"input_mode"`
+
+	// Words expanded to prompts when followed by a space, e.g. "!rev"
+	Aliases map[string]string `json:"aliases"`
+
+	// Offer to delete saved sessions older than this many days or beyond
+	// this many, 0 disables either
+	PruneDays int `json:"prune_days"`
+	PruneKeep int `json:"prune_keep"`
+
+	// Prompts listed in the sidebar for quick reuse
+	PinnedPrompts []string `json:"pinned_prompts"`
+
+	// Markdown style: "auto", a glamour style name or a JSON stylesheet path
+	GlamourStyle string `json:"glamour_style"`
+
+	// Inline images: "auto", "kitty" or "none" for links only
+	Images string `json:"images"`
+
+	// Check messages with the moderations endpoint before sending
+	Moderation bool `json:"moderation"`
+
+	// Mask secrets and personal data in outgoing messages
+	Redact         bool     `json:"redact"`
+	RedactPatterns []string `json:"redact_patterns"`
+
+	// Sequences that end a reply, at most 4, and biases from -100 to 100 by
+	// token ID, for every request
+	Stop      []string       `json:"stop"`
+	LogitBias map[string]int `json:"logit_bias"`
+
+	// Sample with this seed for reproducible replies, see /reseed
+	Seed *int `json:"seed"`
+
+	// Server preset: "openai", "lmstudio" or "llamacpp", see providerPresets
+	Provider string `json:"provider"`
+
+	// Address of the OpenAI compatible API, replacing the provider's default
+	BaseURL string `json:"base_url"`
+
+	// Load the model on local servers at startup and on /model with a one
+	// token request, so the first prompt is not slowed by it
+	WarmUp bool `json:"warm_up"`
+
+	// API keys rotated through on 429 and 401, values expand $ENV. Replaces
+	// OPENAI_API_KEY
+	APIKeys []string `json:"api_keys"`
+
+	// Extra headers sent with every API request, values expand $ENV
+	Headers map[string]string `json:"headers"`
+
+	// Sign in with /login instead of an API key, see OAuthConfig
+	OAuth OAuthConfig `json:"oauth"`
+
+	// Append every request and response to this JSONL file
+	AuditLog string `json:"audit_log"`
+
+	// Send a request again this many times on server errors and rate limits
+	Retries int `json:"retries"`
+
+	// Show how fast each reply came below it
+	ReplyMetrics bool `json:"reply_metrics"`
+
+	// Answer identical requests from the response cache, see /cache
+	Cache bool `json:"cache"`
+
+	// Role prefixes and colors of the transcript
+	Theme Theme `json:"theme"`
+
+	// Icon set: "auto", "nerd", "default" or "ascii"
+	Icons string `json:"icons"`
+
+	// Models /ask-all sends the prompt to
+	AskAllModels []string `json:"ask_all_models"`
+
+	// Model of `bubblechat embed` and the vector store
+	EmbeddingModel string `json:"embedding_model"`
+
+	// Check replies drawing on retrieved excerpts against them with a
+	// second request
+	VerifyGrounding bool `json:"verify_grounding"`
+
+	// Where /add-dir keeps embeddings: "memory", a file path or a URL like
+	// qdrant://localhost:6333/notes. Empty matches by shared words instead
+	VectorStore string `json:"vector_store"`
+
+	// Language replies are written in, as a code like "sv" or a name, see
+	// /lang
+	ReplyLanguage string `json:"reply_language"`
+
+	// Few-shot example sets by name, attached with --examples
+	Examples map[string][]exampleMessage `json:"examples"`
+
+	// Model metadata by name, replacing the built-in entries, see modelInfo
+	Models map[string]modelInfo `json:"models"`
+
+	// Seconds after sending during which ctrl+z takes the message back
+	UndoSendSeconds float64 `json:"undo_send_seconds"`
+
+	// On terminals at least 160 columns wide, show attachments, tool calls
+	// and token stats in a pane right of the conversation
+	DetailsPane bool `json:"details_pane"`
+
+	// Ask before sending a message or attached file taking more than this
+	// share of the context window, 0 never asks
+	AttachmentShare float64 `json:"attachment_share"`
+
+	// Reveal replies at this many characters per second, 0 shows them whole
+	TypewriterSpeed int `json:"typewriter_speed"`
+
+	// Pause between messages of /replay, and how fast it types replies out
+	// in characters per second, 0 shows them whole
+	ReplayDelaySeconds float64 `json:"replay_delay_seconds"`
+	ReplayTypingSpeed  int     `json:"replay_typing_speed"`
+
+	// Fold replies longer than this many lines, 0 disables
+	FoldLines int `json:"fold_lines"`
+
+	// No animations, high contrast, spelled out roles and no box drawing
+	Accessible bool `json:"accessible"`
+
+	// No spinners or animations, a static "…thinking" while waiting
+	Quiet bool `json:"quiet"`
+
+	// Underline words missing from the dictionary word list in the draft
+	Spellcheck bool   `json:"spellcheck"`
+	Dictionary string `json:"dictionary"`
+
+	// Encrypt saved sessions, see EncryptionConfig
+	Encryption EncryptionConfig `json:"encryption"`
+
+	// Where saved sessions are synced to, see SyncConfig
+	Sync SyncConfig `json:"sync"`
+
+	// Upload /share transcripts here instead of a GitHub gist
+	PasteURL string `json:"paste_url"`
+}
+
+var cfg = DefaultConfig()
+
+func DefaultConfig() Config {
+	return Config{
+		FetchTool:  false,
+		InputMode:  inputModeDefault,
+		Images:     imagesAuto,
+		Theme:      DefaultTheme(),
+		Icons:      iconsAuto,
+		Dictionary: defaultDictionary,
+
+		EmbeddingModel: defaultEmbeddingModel,
+
+		AttachmentShare:    defaultAttachmentShare,
+		ReplayDelaySeconds: defaultReplayDelaySeconds,
+		ReplayTypingSpeed:  defaultReplayTypingSpeed,
+	}
+}
+
+func configDir() string {
+	if demoConfigDir != "" {
+		return demoConfigDir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, appName)
+}
+
+func configPath() string {
+	return filepath.Join(configDir(), configFileName)
+}
+
+func LoadConfig() Config {
+	config, err := ReadConfig()
+	if err != nil {
+		log.Fatalf("Error reading config: %v", err)
+	}
+
+	loadedConfig = config
+	return config
+}
+
+// ReadConfig reads config.json over the defaults. A missing file is no error.
+func ReadConfig() (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(configPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("parsing %s: %w", configPath(), err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The details pane sits right of the conversation on wide terminals and lists
+// what the transcript only shows in passing: attached files and pages, tool
+// calls and token usage.
+
+const (
+	// Terminals at least this wide show the details pane when it is enabled
+	detailsMinWindowWidth = 160
+
+	// Tool calls listed, the most recent ones
+	detailsMaxToolCalls = 8
+)
+
+// ShowDetails reports whether the details pane is drawn.
+func (m model) ShowDetails() bool {
+	return cfg.DetailsPane && m.windowWidth >= detailsMinWindowWidth
+}
+
+// attachmentName is the file or URL a context message was made from, empty
+// for other messages.
+func attachmentName(msg openai.ChatCompletionMessage) string {
+	if msg.Role != openai.ChatMessageRoleSystem {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(msg.Content, "Contents of "); ok {
+		if name, _, found := strings.Cut(rest, ", mentioned by the user:"); found {
+			return name
+		}
+	}
+	if rest, ok := strings.CutPrefix(msg.Content, "Summary of "); ok {
+		if name, _, found := strings.Cut(rest, ", mentioned by the user:"); found {
+			return name + " (summary)"
+		}
+	}
+	if rest, ok := strings.CutPrefix(msg.Content, "Content fetched from "); ok {
+		if url, _, found := strings.Cut(rest, " ("); found {
+			return url
+		}
+	}
+	return ""
+}
+
+// DetailsLines lists the attachments, tool calls and token stats of the
+// current conversation, each section under a heading.
+func DetailsLines(history []openai.ChatCompletionMessage, stats sessionStats, draft string) []string {
+	var attachments, calls []string
+	for _, msg := range history {
+		if name := attachmentName(msg); name != "" {
+			attachments = append(attachments, name)
+		}
+		for _, call := range msg.ToolCalls {
+			calls = append(calls, fmt.Sprintf("%s %s", call.Function.Name, call.Function.Arguments))
+		}
+	}
+	if len(calls) > detailsMaxToolCalls {
+		calls = calls[len(calls)-detailsMaxToolCalls:]
+	}
+
+	lines := []string{"Attachments"}
+	if len(attachments) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, name := range attachments {
+		lines = append(lines, "  "+name)
+	}
+
+	lines = append(lines, "", "Tool calls")
+	if len(calls) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, call := range calls {
+		lines = append(lines, "  "+call)
+	}
+
+	lines = append(lines, "", "Tokens", "  context "+TokenGauge(modelName, ContextTokens(history, draft)))
+	models := make([]string, 0, len(stats))
+	for model := range stats {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		s := stats[model]
+		lines = append(lines,
+			"  "+model,
+			fmt.Sprintf("    %d req · %s in · %s out", s.Requests, formatCount(s.PromptTokens), formatCount(s.CompletionTokens)),
+			fmt.Sprintf("    $%.4f · avg %s", s.Cost, averageLatency(s)),
+		)
+	}
+
+	return lines
+}
+
+// DetailsView draws the details pane to fill width next to a column of the
+// given height.
+func (m model) DetailsView(width, height int) string {
+	style := lipgloss.NewStyle().Border(Border()).Padding(0, 1)
+	textWidth := width - style.GetHorizontalFrameSize()
+	textHeight := height - style.GetVerticalFrameSize()
+	if textWidth <= 0 || textHeight <= 0 {
+		return ""
+	}
+
+	lines := DetailsLines(chatMessages, currentSession.Stats, m.textarea.Value())
+	if len(lines) > textHeight {
+		lines = lines[:textHeight]
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, textWidth)
+	}
+
+	return style.Width(textWidth + 2).Height(textHeight).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Files too large for the context window are read in chunks instead. Each
+// chunk is condensed on its own into notes for the question asked (map), and
+// the question is then sent as usual, with the notes attached in place of the
+// file (reduce). As that is a request per chunk, the estimated count and cost
+// are confirmed first, and reading stops at the daily budget. The viewport
+// shows how many chunks of each file are read.
+
+const (
+	// Share of the context window for each chunk, leaving room for the prompt
+	// and the notes
+	chunkShare = 0.5
+
+	// Larger files are not read at all
+	maxDocumentSize = 16 << 20
+
+	chunkBarWidth = 20
+
+	chunkNotesPrompt = "This is part %d of %d of the file %s. Take notes on everything in it that helps answer the question below: " +
+		"facts, names, numbers, quotes and code identifiers, with where they occur. " +
+		"Reply with only \"Nothing relevant.\" if nothing does.\n\n" +
+		"Question: %s\n\nPart %d:\n\n```\n%s\n```"
+)
+
+// documentChunk is one part of a file, numbered from 1 among the parts of
+// that file.
+type documentChunk struct {
+	path  string
+	part  int
+	parts int
+	text  string
+}
+
+// chunkedRead is a message waiting for the notes on the chunks of the files
+// it mentions. notes has the notes of the first len(notes) chunks, and the
+// progress is shown at index at of m.messages.
+type chunkedRead struct {
+	id      int
+	tab     int
+	at      int
+	ctx     context.Context
+	message string
+	paths   []string
+	chunks  []documentChunk
+	notes   []string
+}
+
+// chunkReadConfirmedMsg starts a read once its cost is confirmed.
+type chunkReadConfirmedMsg struct {
+	read *chunkedRead
+}
+
+// Each read has its own id so the notes of a cancelled one are dropped.
+var chunkedReadCount int
+
+type chunkNotesMsg struct {
+	id    int
+	notes string
+	err   error
+}
+
+// OversizedDocuments lists the files mentioned in message that do not fit in
+// the context window next to the conversation, or are too large to attach.
+func OversizedDocuments(message string) []string {
+	window := contextWindow(modelName)
+	if window == 0 {
+		return nil
+	}
+	room := window - ContextTokens(chatMessages, message)
+
+	var paths []string
+	for _, match := range mention.FindAllStringSubmatch(message, -1) {
+		info, err := os.Stat(match[1])
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxDocumentSize {
+			continue
+		}
+		if info.Size() > maxFileSize || SizeTokens(info.Size()) > room {
+			paths = append(paths, match[1])
+		}
+	}
+	return paths
+}
+
+// ReadInChunks splits the files at paths into chunks and asks to confirm
+// taking notes on them for message, with the number of requests and their
+// estimated cost.
+func ReadInChunks(m *model, message string, paths []string) tea.Cmd {
+	size := int(chunkShare*float64(contextWindow(modelName))) * charsPerToken
+
+	var chunks []documentChunk
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil || isBinary(content) {
+			AddNotice(m, "Could not read "+path)
+			continue
+		}
+		parts := SplitText(string(content), size)
+		for i, text := range parts {
+			chunks = append(chunks, documentChunk{path: path, part: i + 1, parts: len(parts), text: text})
+		}
+	}
+	m.textarea.SetValue(message)
+	if len(chunks) == 0 {
+		return nil
+	}
+	if m.statusBar.OverBudget() {
+		AddNotice(m, "Today's spend is over the daily budget, not reading "+strings.Join(paths, ", ")+" in chunks")
+		return nil
+	}
+
+	read := &chunkedRead{message: message, paths: paths, chunks: chunks}
+	m.modal = ChunkReadPrompt(read)
+	m.confirmAction = func() tea.Msg { return chunkReadConfirmedMsg{read} }
+	return nil
+}
+
+// ChunkReadPrompt asks to confirm reading in chunks, with the number of
+// requests and the estimated cost of their prompts.
+func ChunkReadPrompt(r *chunkedRead) string {
+	tokens := 0
+	for _, chunk := range r.chunks {
+		prompt := fmt.Sprintf(chunkNotesPrompt, chunk.part, chunk.parts, chunk.path, r.message, chunk.part, chunk.text)
+		tokens += tokensPerMessage + EstimateTokens(prompt)
+	}
+
+	cost := "at an unknown cost"
+	if _, ok := LookupModel(modelName); ok {
+		cost = fmt.Sprintf("about $%.4f for the prompts", RequestCost(modelName, openai.Usage{PromptTokens: tokens}))
+	}
+
+	lines := []string{
+		"Read in chunks?",
+		"",
+		fmt.Sprintf("%s does not fit in the context window of %s.", strings.Join(r.paths, ", "), modelName),
+		fmt.Sprintf("Taking notes on it takes %d requests, ~%s tokens, %s, before the message is sent.",
+			len(r.chunks), formatCount(tokens), cost),
+	}
+	if cfg.DailyBudget > 0 {
+		lines = append(lines, fmt.Sprintf("Reading stops at the daily budget of $%.2f.", cfg.DailyBudget))
+	}
+	lines = append(lines, "", "Press y to read it, any other key to keep the message.")
+	return strings.Join(lines, "\n")
+}
+
+// StartChunkedRead starts taking notes on the chunks of a confirmed read,
+// showing the progress above a placeholder.
+func StartChunkedRead(m *model, r *chunkedRead) tea.Cmd {
+	if m.request.InFlight() {
+		AddNotice(m, "Wait for the current reply before reading in chunks")
+		return nil
+	}
+	m.textarea.Reset()
+
+	size := int(chunkShare*float64(contextWindow(modelName))) * charsPerToken
+	AddNotice(m, fmt.Sprintf("Reading %s in %d chunks of up to ~%s tokens",
+		strings.Join(r.paths, ", "), len(r.chunks), shortCount(size/charsPerToken)))
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	chunkedReadCount++
+	r.id = chunkedReadCount
+	r.tab = m.ActiveTabID()
+	r.at = len(m.messages)
+	r.ctx = requestCtx
+	m.chunked = r
+
+	m.messages = append(m.messages, m.ProgressMessage(r.Progress()), m.PlaceholderMessage())
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+
+	m.SetRequest(requestSending)
+	m.cancelRequest = cancel
+
+	return tea.Batch(m.StartSpinner(), m.chunked.Next())
+}
+
+// Next takes notes on the first chunk without any.
+func (r *chunkedRead) Next() tea.Cmd {
+	chunk := r.chunks[len(r.notes)]
+	prompt := fmt.Sprintf(chunkNotesPrompt, chunk.part, chunk.parts, chunk.path, r.message, chunk.part, chunk.text)
+	id, ctx, model := r.id, r.ctx, modelName
+
+	return func() tea.Msg {
+		req := openai.ChatCompletionRequest{
+			Model:    model,
+			Messages: []openai.ChatCompletionMessage{UserMessage(prompt)},
+		}
+		resp, err := callChat(ctx, req)
+		if err == nil && len(resp.Choices) == 0 {
+			err = errNoChoices
+		}
+		msg := chunkNotesMsg{id: id, err: err}
+		if err == nil {
+			msg.notes = strings.TrimSpace(resp.Choices[0].Message.Content)
+		}
+		return msg
+	}
+}
+
+// HandleChunkNotes records the notes on a chunk and moves on to the next,
+// sending the message with the notes attached after the last.
+func HandleChunkNotes(m *model, msg chunkNotesMsg) tea.Cmd {
+	r := m.chunked
+	if r == nil || msg.id != r.id {
+		return nil
+	}
+	if errors.Is(msg.err, context.Canceled) {
+		m.chunked = nil
+		return nil
+	}
+
+	// The message is only sent from the tab it was written in
+	if r.tab != m.ActiveTabID() {
+		m.chunked = nil
+		m.ShowResult(r.tab, m.NoticeMessage("Stopped reading in chunks after switching tabs: "+r.message), nil)
+		return nil
+	}
+
+	if msg.err != nil {
+		r.Stop(m, "Could not read in chunks: "+msg.err.Error())
+		return nil
+	}
+
+	r.notes = append(r.notes, msg.notes)
+	if r.at < len(m.messages)-1 {
+		m.messages[r.at] = m.ProgressMessage(r.Progress())
+		UpdateViewport(m)
+	}
+	if len(r.notes) < len(r.chunks) {
+		if m.statusBar.OverBudget() {
+			r.Stop(m, fmt.Sprintf("Stopped reading in chunks at the daily budget, after %d of %d", len(r.notes), len(r.chunks)))
+			return nil
+		}
+		return r.Next()
+	}
+
+	m.chunked = nil
+	m.SetRequest(requestDone)
+	m.cancelRequest = nil
+	m.messages = m.messages[:len(m.messages)-1]
+	return SendChecked(m, r.message, r.Notes())
+}
+
+// Stop ends the read without sending the message, which is put back in the
+// input.
+func (r *chunkedRead) Stop(m *model, notice string) {
+	m.chunked = nil
+	m.SetRequest(requestFailed)
+	m.cancelRequest = nil
+	m.messages = m.messages[:len(m.messages)-1]
+	AddNotice(m, notice)
+	m.textarea.SetValue(r.message)
+}
+
+// Notes joins the notes on the chunks of each file.
+func (r *chunkedRead) Notes() map[string]string {
+	notes := map[string]string{}
+	for i, chunk := range r.chunks {
+		part := fmt.Sprintf("Notes on part %d of %d:\n%s", chunk.part, chunk.parts, r.notes[i])
+		if notes[chunk.path] != "" {
+			part = notes[chunk.path] + "\n\n" + part
+		}
+		notes[chunk.path] = part
+	}
+	return notes
+}
+
+// Progress says how many chunks of each file have notes, a line per file.
+func (r *chunkedRead) Progress() string {
+	var lines []string
+	for i, chunk := range r.chunks {
+		if chunk.part != chunk.parts {
+			continue
+		}
+		done := min(chunk.parts, max(0, len(r.notes)-(i+1-chunk.parts)))
+		progress := fmt.Sprintf("chunk %d of %d", done, chunk.parts)
+		if !cfg.Accessible {
+			filled := chunkBarWidth * done / chunk.parts
+			progress = strings.Repeat("█", filled) + strings.Repeat("░", chunkBarWidth-filled) + " " + progress
+		}
+		lines = append(lines, chunk.path+" "+progress)
+	}
+	return strings.Join(lines, "\n")
+}
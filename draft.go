@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	draftFileName     = "draft.txt"
+	draftSaveInterval = 2 * time.Second
+)
+
+type draftTickMsg struct{}
+
+func draftPath() string {
+	return filepath.Join(configDir(), draftFileName)
+}
+
+// DraftTickCmd schedules the next draft autosave.
+func DraftTickCmd() tea.Cmd {
+	return tea.Tick(draftSaveInterval, func(time.Time) tea.Msg {
+		return draftTickMsg{}
+	})
+}
+
+// SaveDraft stores the unsent textarea content when it changed since the last
+// save. An empty draft removes the file.
+func (m *model) SaveDraft() {
+	draft := m.textarea.Value()
+	if draft == m.savedDraft {
+		return
+	}
+	m.savedDraft = draft
+
+	if draft == "" {
+		os.Remove(draftPath())
+		return
+	}
+
+	err := os.MkdirAll(configDir(), 0o755)
+	if err == nil {
+		err = os.WriteFile(draftPath(), []byte(draft), 0o600)
+	}
+	if err != nil {
+		log.Printf("Error saving draft: %v", err)
+	}
+}
+
+// LoadDraft puts the draft left by the last run back into the textarea.
+func (m *model) LoadDraft() {
+	data, err := os.ReadFile(draftPath())
+	if err != nil {
+		return
+	}
+
+	m.savedDraft = string(data)
+	m.textarea.SetValue(m.savedDraft)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Replies can be edited in $EDITOR from selection mode, to correct the
+// context before the conversation goes on. Edited replies are labeled as such
+// in the transcript, since the model did not write them as they stand.
+
+// replyEditedMsg carries the edited text of the reply at index in
+// chatMessages. original is the text it was opened with, to notice the
+// conversation changing while the editor was open.
+type replyEditedMsg struct {
+	index    int
+	original string
+	content  string
+	err      error
+}
+
+// editorCommand is the user's editor, with any arguments it is set up with.
+func editorCommand(path string) *exec.Cmd {
+	editor := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"))
+	return exec.Command(editor[0], append(editor[1:], path)...)
+}
+
+// replyIndex finds the chatMessages index of the reply shown as message i.
+// It returns -1 when message i is not a reply in chatMessages.
+func (m model) replyIndex(i int) int {
+	msg := m.messages[i]
+	if msg.Kind != kindReply || msg.Index < 0 || msg.Index >= len(chatMessages) ||
+		chatMessages[msg.Index].Role != openai.ChatMessageRoleAssistant {
+		return -1
+	}
+	return msg.Index
+}
+
+// EditReply opens the reply shown as message i in the editor.
+func EditReply(m *model, i int) tea.Cmd {
+	index := m.replyIndex(i)
+	if index < 0 {
+		AddNotice(m, "Only replies can be edited")
+		return nil
+	}
+	original := chatMessages[index].Content
+
+	file, err := os.CreateTemp("", "bubblechat-reply-*.md")
+	if err != nil {
+		AddNotice(m, "Could not edit the reply: "+err.Error())
+		return nil
+	}
+	_, err = file.WriteString(original)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		AddNotice(m, "Could not edit the reply: "+err.Error())
+		return nil
+	}
+
+	m.StopSelection()
+	return tea.ExecProcess(editorCommand(file.Name()), func(err error) tea.Msg {
+		defer os.Remove(file.Name())
+		if err != nil {
+			return replyEditedMsg{err: err}
+		}
+		content, err := os.ReadFile(file.Name())
+		return replyEditedMsg{index: index, original: original, content: string(content), err: err}
+	})
+}
+
+// HandleReplyEdited saves the edited reply into the conversation.
+func HandleReplyEdited(m *model, msg replyEditedMsg) {
+	if msg.err != nil {
+		AddNotice(m, "Could not edit the reply: "+msg.err.Error())
+		return
+	}
+
+	content := strings.TrimRight(msg.content, "\n")
+	switch {
+	case msg.index >= len(chatMessages) || chatMessages[msg.index].Content != msg.original:
+		AddNotice(m, "The conversation changed while editing, the edit was not saved")
+		return
+	case content == strings.TrimRight(msg.original, "\n"):
+		return
+	case strings.TrimSpace(content) == "":
+		AddNotice(m, "An empty reply was not saved")
+		return
+	}
+
+	chatMessages[msg.index].Content = content
+	currentSession.MarkEdited(msg.index)
+	SaveCurrentSession()
+	RebuildTranscript(m)
+	AddNotice(m, "Reply edited")
+}
+
+// MarkEdited notes that the reply at index i was changed by the user.
+func (s *session) MarkEdited(i int) {
+	if s.EditedReplies == nil {
+		s.EditedReplies = map[int]bool{}
+	}
+	s.EditedReplies[i] = true
+}
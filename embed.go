@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultEmbeddingModel = "text-embedding-3-small"
+
+	// Inputs sent per embeddings request
+	embeddingBatchSize = 64
+)
+
+// embeddingRecord is one chunk of a file with its embedding, written as one
+// JSON line by `bubblechat embed`.
+type embeddingRecord struct {
+	File      string    `json:"file"`
+	Chunk     int       `json:"chunk"`
+	Text      string    `json:"text,omitempty"`
+	Model     string    `json:"model"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// RunEmbed embeds the chunks of files without the UI, for
+// `bubblechat embed <files…> [--out embeddings.jsonl]`. It goes through the
+// configured provider like the chat does.
+func RunEmbed(args []string) error {
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	out := flags.String("out", "", "write the embeddings to this JSONL file instead of stdout")
+	model := flags.String("model", cfg.EmbeddingModel, "embedding model")
+	dimensions := flags.Int("dimensions", 0, "shorten the embeddings to this many dimensions, where the model supports it")
+	size := flags.Int("chunk-size", chunkSize, "split files into chunks of at most this many bytes")
+	noText := flags.Bool("no-text", false, "leave the text of each chunk out of the output")
+
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 || *size < 1 {
+		return errors.New("usage: bubblechat embed [--out embeddings.jsonl] [--model name] [--dimensions n] [--chunk-size bytes] [--no-text] <files…>")
+	}
+	if filepath.Ext(*out) == ".parquet" {
+		return errors.New("parquet output is not supported, write JSONL and convert it, e.g. with duckdb")
+	}
+
+	var records []embeddingRecord
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			return fmt.Errorf("%s: not a text file", path)
+		}
+		for i, text := range SplitText(string(content), *size) {
+			records = append(records, embeddingRecord{File: path, Chunk: i, Text: text, Model: *model})
+		}
+	}
+	if len(records) == 0 {
+		return errors.New("no text to embed")
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	initializeClient()
+
+	daily := LoadDailyUsage()
+	var cost float64
+	for start := 0; start < len(records); start += embeddingBatchSize {
+		batch := records[start:min(start+embeddingBatchSize, len(records))]
+		inputs := make([]string, len(batch))
+		for i, r := range batch {
+			inputs[i] = r.Text
+		}
+
+		vectors, usage, err := Embed(ctx, *model, *dimensions, inputs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("embedding %s: %w", batch[0].File, err)
+		}
+		batchCost := RequestCost(*model, usage)
+		cost += batchCost
+		daily.Add(batchCost)
+
+		for i, r := range batch {
+			r.Embedding = vectors[i]
+			if *noText {
+				r.Text = ""
+			}
+			line, _ := json.Marshal(r)
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\r%d/%d chunks embedded, $%.4f", start+len(batch), len(records), cost)
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+// Embed requests the embeddings of inputs, in their order. dimensions 0
+// keeps the model's own size.
+func Embed(ctx context.Context, model string, dimensions int, inputs []string) ([][]float32, openai.Usage, error) {
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input:      inputs,
+		Model:      openai.EmbeddingModel(model),
+		Dimensions: dimensions,
+	})
+	if err != nil {
+		return nil, openai.Usage{}, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, resp.Usage, fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), len(inputs))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(inputs) {
+			return nil, resp.Usage, fmt.Errorf("embedding index %d out of range", e.Index)
+		}
+		vectors[e.Index] = e.Embedding
+	}
+	return vectors, resp.Usage, nil
+}
+
+// normalize scales vector to length 1, leaving a zero vector as it is.
+func normalize(vector []float32) {
+	var sum float64
+	for _, x := range vector {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	for i := range vector {
+		vector[i] *= norm
+	}
+}
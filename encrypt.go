@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	encryptionFileName = "encryption.json"
+	passphraseEnv      = "BUBBLECHAT_PASSPHRASE"
+	lockedTitle        = "Encrypted session"
+
+	saltSize = 16
+	keySize  = 32
+
+	// OWASP's recommendation for PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600_000
+)
+
+// Sessions are sealed with AES-256-GCM under a key derived once at startup
+// from a passphrase or key file. The key check, a sealed known value, tells
+// a wrong passphrase apart from a damaged session.
+var (
+	sessionKey []byte
+
+	keyCheck = []byte(appName)
+)
+
+// EncryptionConfig turns on encryption of saved sessions and recovery
+// snapshots. Without a key file the passphrase is read from
+// BUBBLECHAT_PASSPHRASE.
+type EncryptionConfig struct {
+	Enabled bool   `json:"enabled"`
+	KeyFile string `json:"key_file"`
+}
+
+type encryptionParams struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// sealedSession is a session as stored when encrypted. Only what the session
+// list, tags, pruning and stats need stays readable, everything else is
+// decrypted when the session is opened.
+type sealedSession struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Created time.Time    `json:"created"`
+	Updated time.Time    `json:"updated"`
+	Tags    []string     `json:"tags,omitempty"`
+	Stats   sessionStats `json:"stats,omitempty"`
+	Sealed  []byte       `json:"sealed"`
+}
+
+func encryptionPath() string {
+	return filepath.Join(configDir(), encryptionFileName)
+}
+
+// LoadSessionKey derives the session key when encryption is enabled. The salt
+// and key check are created on first use.
+func LoadSessionKey() error {
+	if !cfg.Encryption.Enabled {
+		return nil
+	}
+
+	secret, err := readSecret()
+	if err != nil {
+		return err
+	}
+
+	var params encryptionParams
+	data, err := os.ReadFile(encryptionPath())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		params.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(params.Salt); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &params); err != nil {
+			return fmt.Errorf("%s: %w", encryptionPath(), err)
+		}
+	}
+
+	key := deriveKey(secret, params.Salt)
+
+	if params.Check != nil {
+		check, err := openWith(key, params.Check)
+		if err != nil || !bytes.Equal(check, keyCheck) {
+			return errors.New("wrong passphrase or key file")
+		}
+		sessionKey = key
+		return nil
+	}
+
+	params.Check, err = sealWith(key, keyCheck)
+	if err == nil {
+		data, err = json.Marshal(params)
+	}
+	if err == nil {
+		err = os.MkdirAll(configDir(), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(encryptionPath(), data, 0o600)
+	}
+	if err != nil {
+		return err
+	}
+
+	sessionKey = key
+	return nil
+}
+
+func readSecret() ([]byte, error) {
+	if cfg.Encryption.KeyFile != "" {
+		return os.ReadFile(cfg.Encryption.KeyFile)
+	}
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, fmt.Errorf("set %s or encryption.key_file", passphraseEnv)
+}
+
+// deriveKey stretches the secret into a key with PBKDF2-HMAC-SHA256.
+func deriveKey(secret, salt []byte) []byte {
+	return pbkdf2.Key(secret, salt, pbkdf2Iterations, keySize, sha256.New)
+}
+
+func sealWith(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openWith(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed data too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// EncodeSession serializes a session for disk, sealed when a key is loaded.
+func EncodeSession(s session) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil || sessionKey == nil {
+		return data, err
+	}
+
+	sealed, err := sealWith(sessionKey, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(sealedSession{
+		ID:      s.ID,
+		Model:   s.Model,
+		Created: s.Created,
+		Updated: s.Updated,
+		Tags:    s.Tags,
+		Stats:   s.Stats,
+		Sealed:  sealed,
+	}, "", "  ")
+}
+
+// DecodeSession reads a stored session. Sealed sessions come back locked,
+// with only their readable fields, until Unlock is called.
+func DecodeSession(data []byte) (session, error) {
+	var stored struct {
+		session
+		Sealed []byte `json:"sealed"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return session{}, err
+	}
+
+	s := stored.session
+	if stored.Sealed != nil {
+		s.Title = lockedTitle
+		s.sealed = stored.Sealed
+	}
+	return s, nil
+}
+
+func (s session) Locked() bool {
+	return s.sealed != nil
+}
+
+// Unlock decrypts a locked session.
+func (s session) Unlock() (session, error) {
+	if !s.Locked() {
+		return s, nil
+	}
+	if sessionKey == nil {
+		return s, errors.New("session is encrypted, enable encryption in the config to open it")
+	}
+
+	data, err := openWith(sessionKey, s.sealed)
+	if err != nil {
+		return s, fmt.Errorf("could not decrypt session %s: %w", s.ID, err)
+	}
+
+	var unlocked session
+	err = json.Unmarshal(data, &unlocked)
+	return unlocked, err
+}
+
+// SealBytes encrypts other files holding conversations, like the recovery
+// snapshot, when a key is loaded.
+func SealBytes(data []byte) ([]byte, error) {
+	if sessionKey == nil {
+		return data, nil
+	}
+	return sealWith(sessionKey, data)
+}
+
+// OpenBytes reverses SealBytes. Plain JSON passes through, so files written
+// before encryption was enabled stay readable.
+func OpenBytes(data []byte) ([]byte, error) {
+	if json.Valid(data) {
+		return data, nil
+	}
+	if sessionKey == nil {
+		return nil, errors.New("file is encrypted, enable encryption in the config to read it")
+	}
+	return openWith(sessionKey, data)
+}
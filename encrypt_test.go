@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func testSession() session {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return session{
+		ID:      "20240501-120000",
+		Title:   "Secret plans",
+		Model:   "gpt-4o",
+		Created: t0,
+		Updated: t0.Add(time.Minute),
+		Tags:    []string{"work"},
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: "the launch date is June 3"},
+			{Role: openai.ChatMessageRoleAssistant, Content: "Noted."},
+		},
+	}
+}
+
+// withSessionKey loads a random session key for the test.
+func withSessionKey(t *testing.T) {
+	t.Helper()
+	saved := sessionKey
+	t.Cleanup(func() { sessionKey = saved })
+
+	sessionKey = make([]byte, keySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncodeSessionPlain(t *testing.T) {
+	saved := sessionKey
+	t.Cleanup(func() { sessionKey = saved })
+	sessionKey = nil
+
+	data, err := EncodeSession(testSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := DecodeSession(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Locked() {
+		t.Error("session saved without a key is locked")
+	}
+	if s.Title != "Secret plans" || len(s.Messages) != 2 {
+		t.Errorf("decoded %+v, want the session as saved", s)
+	}
+}
+
+func TestEncodeSessionSealed(t *testing.T) {
+	withSessionKey(t)
+	original := testSession()
+
+	data, err := EncodeSession(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"launch date", "Secret plans"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("sealed session contains %q", secret)
+		}
+	}
+
+	locked, err := DecodeSession(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked.Locked() {
+		t.Fatal("sealed session is not locked")
+	}
+	if locked.Title != lockedTitle || locked.Messages != nil {
+		t.Errorf("locked session shows title %q and %d messages", locked.Title, len(locked.Messages))
+	}
+	if locked.ID != original.ID || !locked.Updated.Equal(original.Updated) || len(locked.Tags) != 1 {
+		t.Errorf("locked session lost its readable fields: %+v", locked)
+	}
+
+	unlocked, err := locked.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlocked.Locked() || unlocked.Title != original.Title {
+		t.Errorf("unlocked title = %q, want %q", unlocked.Title, original.Title)
+	}
+	if len(unlocked.Messages) != 2 || unlocked.Messages[0].Content != original.Messages[0].Content {
+		t.Errorf("unlocked messages = %v, want %v", unlocked.Messages, original.Messages)
+	}
+}
+
+func TestUnlockWithWrongKey(t *testing.T) {
+	withSessionKey(t)
+	data, err := EncodeSession(testSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := DecodeSession(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withSessionKey(t)
+	if _, err := locked.Unlock(); err == nil {
+		t.Error("Unlock with another key succeeded")
+	}
+
+	sessionKey = nil
+	if _, err := locked.Unlock(); err == nil {
+		t.Error("Unlock without a key succeeded")
+	}
+}
+
+func TestSealWithDetectsTampering(t *testing.T) {
+	key := make([]byte, keySize)
+	sealed, err := sealWith(key, keyCheck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := openWith(key, sealed)
+	if err != nil || !bytes.Equal(opened, keyCheck) {
+		t.Fatalf("openWith = %q, %v", opened, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := openWith(key, sealed); err == nil {
+		t.Error("openWith accepted tampered data")
+	}
+	if _, err := openWith(key, sealed[:4]); err == nil {
+		t.Error("openWith accepted data shorter than a nonce")
+	}
+}
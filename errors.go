@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const errorColor = "#e88388"
+
+// errNoChoices is a completion that came back without any choice to read.
+var errNoChoices = errors.New("the response has no choices")
+
+// errorDetails pulls the status and error code out of an API error, either
+// may be missing.
+func errorDetails(err error) (status int, code string) {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+		if apiErr.Code != nil {
+			code = fmt.Sprint(apiErr.Code)
+		} else {
+			code = apiErr.Type
+		}
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	return status, code
+}
+
+// Requests fail for a few causes the UI treats differently: a rejected key
+// opens the auth diagnostics, an unreachable endpoint takes the app offline
+// and the others are shown as error blocks. ClassifyError turns the errors of
+// the provider into the types below, at the end of the middleware chain and
+// when probing the endpoint, so the UI can tell them apart by type. Other
+// errors pass through as they are.
+
+// userError is an error with a title and a suggested fix to show.
+type userError interface {
+	error
+	Title() string
+	Suggestion() string
+}
+
+// AuthError is a request the API rejected for its credentials, 401 or 403.
+type AuthError struct {
+	Err    error
+	Status int
+	Model  string
+}
+
+func (e *AuthError) Error() string { return e.Err.Error() }
+func (e *AuthError) Unwrap() error { return e.Err }
+func (e *AuthError) Title() string { return "Authentication failed" }
+
+func (e *AuthError) Suggestion() string {
+	if e.Status == http.StatusForbidden {
+		return "The key has no access to " + e.Model + ", try another with /model"
+	}
+	return "Check the API key in OPENAI_API_KEY or api_keys"
+}
+
+// RateLimitError is a request refused for being over the rate limit, after
+// any retries.
+type RateLimitError struct {
+	Err error
+}
+
+func (e *RateLimitError) Error() string { return e.Err.Error() }
+func (e *RateLimitError) Unwrap() error { return e.Err }
+func (e *RateLimitError) Title() string { return "Rate limited" }
+
+func (e *RateLimitError) Suggestion() string {
+	if cfg.Retries == 0 {
+		return "Wait a moment and send again, or set retries to retry on their own"
+	}
+	return "Wait a moment and send again"
+}
+
+// ContextLengthError is a conversation too long for the context window of
+// the model.
+type ContextLengthError struct {
+	Err   error
+	Model string
+}
+
+func (e *ContextLengthError) Error() string { return e.Err.Error() }
+func (e *ContextLengthError) Unwrap() error { return e.Err }
+func (e *ContextLengthError) Title() string { return "Conversation too long" }
+
+func (e *ContextLengthError) Suggestion() string {
+	return "The conversation is too long for " + e.Model + ", start a new tab or switch with /model"
+}
+
+// NetworkError is a request that did not reach the endpoint at all.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string { return e.Err.Error() }
+func (e *NetworkError) Unwrap() error { return e.Err }
+func (e *NetworkError) Title() string { return "Endpoint unreachable" }
+
+func (e *NetworkError) Suggestion() string {
+	return "Check the connection and base_url in the config"
+}
+
+// ClassifyError wraps err of a request to model in the type of its cause,
+// when it has one of the types above.
+func ClassifyError(err error, model string) error {
+	var classified userError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+
+	status, code := errorDetails(err)
+	var netErr net.Error
+	switch {
+	case code == "context_length_exceeded" || strings.Contains(err.Error(), "maximum context length"):
+		return &ContextLengthError{Err: err, Model: model}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &AuthError{Err: err, Status: status, Model: model}
+	case status == http.StatusTooManyRequests && code != "insufficient_quota":
+		return &RateLimitError{Err: err}
+	case errors.As(err, &netErr):
+		return &NetworkError{Err: err}
+	}
+	return err
+}
+
+// ErrorMiddleware classifies the errors of requests with ClassifyError.
+func ErrorMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		resp, err := next(ctx, req)
+		return resp, ClassifyError(err, req.Model)
+	}
+}
+
+// ErrorSuggestion says what to try after a failed request, empty when there
+// is nothing better than reading the error.
+func ErrorSuggestion(err error) string {
+	var classified userError
+	if errors.As(err, &classified) {
+		return classified.Suggestion()
+	}
+
+	status, code := errorDetails(err)
+	switch {
+	case code == "insufficient_quota":
+		return "The account is out of credit, check its billing"
+	case code == "model_not_found":
+		return "Switch to a model the key can use with /model"
+	case status == http.StatusNotFound:
+		return "Check the model with /model and base_url in the config"
+	case status >= 500:
+		return "The API had a problem, send again in a moment"
+	}
+	return ""
+}
+
+// RenderError draws a failed request as a red block in the transcript, with
+// the status, error code and a suggested action.
+func RenderError(err error) string {
+	title := icons.Error + " Request failed"
+	var classified userError
+	if errors.As(err, &classified) {
+		title = icons.Error + " " + classified.Title()
+	}
+	if status, code := errorDetails(err); status > 0 {
+		title += fmt.Sprintf(": %d %s", status, http.StatusText(status))
+		if code != "" {
+			title += " (" + code + ")"
+		}
+	}
+
+	lines := []string{title, err.Error()}
+	if suggestion := ErrorSuggestion(err); suggestion != "" {
+		lines = append(lines, "→ "+suggestion)
+	}
+
+	text := wordwrap.String(strings.Join(lines, "\n"), textWidth()-6)
+	return lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(errorColor)).
+		Foreground(Color(errorColor)).
+		Padding(0, 1).
+		Render(text)
+}
+
+// ShowError replaces the reply placeholder of a failed request with the
+// error block.
+func ShowError(m *model, err error) {
+	m.messages = append(m.messages[:len(m.messages)-1], ErrorMessage(err))
+	UpdateViewport(m)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// evalSuite is a file of test cases for `bubblechat eval <cases.yaml>`. Each
+// case sends a prompt, or runs a `run` template, and checks the answer with
+// regular expressions, a judge model comparing it to an expected answer, or
+// both.
+type evalSuite struct {
+	Model      string         `json:"model"`
+	System     string         `json:"system"`
+	JudgeModel string         `json:"judge_model"`
+	Variables  map[string]any `json:"variables"`
+	Cases      []evalCase     `json:"cases"`
+}
+
+type evalCase struct {
+	Name string `json:"name"`
+
+	// The case sends either a prompt or the steps of a template file
+	Prompt    string         `json:"prompt"`
+	Template  string         `json:"template"`
+	Model     string         `json:"model"`
+	System    string         `json:"system"`
+	Variables map[string]any `json:"variables"`
+
+	// The expected answer, compared by the judge model
+	Expect string `json:"expect"`
+	// Patterns the answer must and must not match
+	Match    []string `json:"match"`
+	NotMatch []string `json:"not_match"`
+
+	template       runTemplate
+	match, exclude []*regexp.Regexp
+}
+
+// evalResult is the outcome of a case. Failures lists the checks it failed,
+// err tells why it could not be checked at all.
+type evalResult struct {
+	answer   string
+	failures []string
+	cost     float64
+	err      error
+}
+
+const judgeSystemPrompt = "You grade answers against an expected answer. " +
+	"The user sends a question, the expected answer and an answer to grade. The answer passes when it " +
+	"agrees with the expected answer on every point that matters, in any wording or order, without " +
+	"contradicting it. " +
+	`Reply with a JSON object: {"pass": true or false, "reason": "<one sentence>"}.`
+
+// RunEval runs the cases of a file and prints a pass/fail table. It fails
+// when any case does, for use in scripts and CI.
+func RunEval(args []string) error {
+	vars := runVars{}
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.Var(vars, "var", "set a variable, name=value (repeatable)")
+	judgeModel := flags.String("judge-model", "", "model judging the answers, replacing the file's judge_model")
+	concurrency := flags.Int("concurrency", defaultBatchConcurrency, "cases run at the same time")
+	verbose := flags.Bool("v", false, "print the answers of failed cases")
+
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: bubblechat eval [--var name=value] [--judge-model name] [--concurrency n] [-v] <cases.yaml>")
+	}
+	*concurrency = max(*concurrency, 1)
+
+	suite, err := LoadEvalSuite(files[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+	judge := cmp.Or(*judgeModel, suite.JudgeModel, modelName)
+
+	initializeClient()
+	daily := LoadDailyUsage()
+
+	results := make([]evalResult, len(suite.Cases))
+	var mu sync.Mutex
+	var done int
+	var cost float64
+
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i, c := range suite.Cases {
+		values := map[string]string{}
+		for _, variables := range []map[string]any{suite.Variables, c.template.Variables, c.Variables} {
+			for name, value := range variables {
+				values[name] = fmt.Sprint(value)
+			}
+		}
+		for name, value := range vars {
+			values[name] = value
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := c.Run(values, judge)
+			results[i] = result
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			cost += result.cost
+			daily.Add(result.cost)
+			fmt.Fprintf(os.Stderr, "\r%d/%d cases, $%.4f", done, len(suite.Cases), cost)
+		}()
+	}
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CASE\tRESULT\tDETAIL")
+	for i, c := range suite.Cases {
+		r := results[i]
+		switch {
+		case r.err != nil:
+			failed++
+			fmt.Fprintf(w, "%s\terror\t%s\n", c.Name, oneLine(r.err.Error()))
+		case len(r.failures) > 0:
+			failed++
+			fmt.Fprintf(w, "%s\tFAIL\t%s\n", c.Name, oneLine(strings.Join(r.failures, "; ")))
+		default:
+			fmt.Fprintf(w, "%s\tpass\t\n", c.Name)
+		}
+	}
+	w.Flush()
+
+	if *verbose {
+		for i, c := range suite.Cases {
+			if r := results[i]; r.answer != "" && (r.err != nil || len(r.failures) > 0) {
+				fmt.Printf("\n--- %s ---\n%s\n", c.Name, strings.TrimSpace(r.answer))
+			}
+		}
+	}
+
+	fmt.Printf("\n%d of %d passed, $%.4f\n", len(suite.Cases)-failed, len(suite.Cases), cost)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(suite.Cases))
+	}
+	return nil
+}
+
+// LoadEvalSuite reads a file of cases, their templates and patterns, and
+// checks that every case can run.
+func LoadEvalSuite(path string) (evalSuite, error) {
+	var suite evalSuite
+	if err := ReadTemplateFile(path, &suite); err != nil {
+		return suite, err
+	}
+	if len(suite.Cases) == 0 {
+		return suite, errors.New("no cases")
+	}
+
+	names := map[string]bool{}
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case%d", i+1)
+		}
+		if names[c.Name] {
+			return suite, fmt.Errorf("two cases are named %s", c.Name)
+		}
+		names[c.Name] = true
+
+		if err := c.load(suite, filepath.Dir(path)); err != nil {
+			return suite, fmt.Errorf("case %s: %w", c.Name, err)
+		}
+	}
+	return suite, nil
+}
+
+// load reads the template a case runs, or makes a single step one of its
+// prompt, and compiles its patterns.
+func (c *evalCase) load(suite evalSuite, dir string) error {
+	switch {
+	case c.Prompt != "" && c.Template != "":
+		return errors.New("set either prompt or template")
+	case c.Template != "":
+		path := c.Template
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		t, err := LoadRunTemplate(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Template, err)
+		}
+		c.template = t
+	case strings.TrimSpace(c.Prompt) != "":
+		c.template = runTemplate{Steps: []runStep{{Name: "answer", Prompt: c.Prompt}}}
+	default:
+		return errors.New("no prompt or template")
+	}
+	c.template.Model = cmp.Or(c.Model, c.template.Model, suite.Model)
+	c.template.System = cmp.Or(c.System, c.template.System, suite.System)
+
+	if c.Expect == "" && len(c.Match) == 0 && len(c.NotMatch) == 0 {
+		return errors.New("nothing to check, set expect, match or not_match")
+	}
+	for _, pattern := range c.Match {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("match: %w", err)
+		}
+		c.match = append(c.match, re)
+	}
+	for _, pattern := range c.NotMatch {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("not_match: %w", err)
+		}
+		c.exclude = append(c.exclude, re)
+	}
+	return nil
+}
+
+// Run answers the case and checks the answer.
+func (c evalCase) Run(values map[string]string, judge string) evalResult {
+	var result evalResult
+
+	var prompt string
+	for _, step := range c.template.Steps {
+		req, err := c.template.StepRequest(step, values)
+		if err != nil {
+			result.err = fmt.Errorf("step %s: %w", step.Name, err)
+			return result
+		}
+		prompt = req.Messages[len(req.Messages)-1].Content
+
+		answer := CompleteOnce(req)
+		result.cost += answer.Cost
+		if answer.Error != "" {
+			result.err = fmt.Errorf("step %s: %s", step.Name, answer.Error)
+			return result
+		}
+		result.answer = answer.Answer
+		values[step.Name] = answer.Answer
+	}
+
+	for _, re := range c.match {
+		if !re.MatchString(result.answer) {
+			result.failures = append(result.failures, fmt.Sprintf("does not match /%s/", re))
+		}
+	}
+	for _, re := range c.exclude {
+		if found := re.FindString(result.answer); found != "" {
+			result.failures = append(result.failures, fmt.Sprintf("matches /%s/ with %q", re, found))
+		}
+	}
+
+	if c.Expect != "" {
+		pass, reason, cost, err := Judge(judge, prompt, c.Expect, result.answer)
+		result.cost += cost
+		if err != nil {
+			result.err = fmt.Errorf("judge: %w", err)
+			return result
+		}
+		if !pass {
+			result.failures = append(result.failures, "judge: "+reason)
+		}
+	}
+	return result
+}
+
+// Judge asks a model whether answer agrees with the expected answer to
+// prompt.
+func Judge(model, prompt, expected, answer string) (pass bool, reason string, cost float64, err error) {
+	content := fmt.Sprintf("Question:\n\n%s\n\nExpected answer:\n\n%s\n\nAnswer to grade:\n\n%s", prompt, expected, answer)
+	req := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: judgeSystemPrompt},
+			UserMessage(content),
+		},
+	}
+	if SupportsJSONMode(model) {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+
+	result := CompleteOnce(req)
+	if result.Error != "" {
+		return false, "", result.Cost, errors.New(result.Error)
+	}
+
+	var verdict struct {
+		Pass   bool   `json:"pass"`
+		Reason string `json:"reason"`
+	}
+	trimmed := strings.TrimSpace(result.Answer)
+	trimmed = strings.TrimPrefix(strings.TrimSuffix(trimmed, "```"), "```json")
+	if err := json.Unmarshal([]byte(trimmed), &verdict); err != nil {
+		return false, "", result.Cost, errors.New("the judge did not reply with the expected JSON")
+	}
+	return verdict.Pass, verdict.Reason, result.Cost, nil
+}
+
+// oneLine joins the lines of text for a table cell.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
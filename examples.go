@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Few-shot example sets are message sequences in the config, attached to a
+// new session with --examples so they come before the first prompt. They are
+// sent with every request but shown as a single notice in the transcript.
+
+var examplesName string
+
+// exampleMessage is one message of an example set.
+type exampleMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ExampleMessages returns the example set name as chat messages.
+func ExampleMessages(name string) ([]openai.ChatCompletionMessage, error) {
+	examples, ok := cfg.Examples[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Examples))
+		for name := range cfg.Examples {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no example set %s, there are no examples in the config", name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("no example set %s, the config has %s", name, strings.Join(names, ", "))
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("example set %s is empty", name)
+	}
+
+	messages := make([]openai.ChatCompletionMessage, len(examples))
+	for i, example := range examples {
+		if !slices.Contains(injectRoles, example.Role) {
+			return nil, fmt.Errorf("example set %s: message %d has role %q, use %s", name, i+1, example.Role, strings.Join(injectRoles, ", "))
+		}
+		messages[i] = openai.ChatCompletionMessage{Role: example.Role, Content: example.Content}
+	}
+	return messages, nil
+}
+
+// AttachExamples starts the conversation with the example set name. It has
+// to be empty, so the examples come before the first prompt.
+func (m *model) AttachExamples(name string) error {
+	if len(chatMessages) > 0 {
+		return errors.New("--examples starts a new session, it can not be used with --continue")
+	}
+
+	messages, err := ExampleMessages(name)
+	if err != nil {
+		return err
+	}
+	chatMessages = messages
+	currentSession.Examples = name
+	currentSession.ExampleCount = len(messages)
+
+	RebuildTranscript(m)
+	return nil
+}
+
+// RenderExamples renders the notice standing in for the example messages.
+func (m model) RenderExamples(s session) string {
+	text := fmt.Sprintf("Few-shot examples %s: %d messages", s.Examples, s.ExampleCount)
+	return m.noticeStyle.Render(noticePrefix + text)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// experiment compares variants of a prompt, for
+// `bubblechat experiment <experiment.yaml>`. Every input is sent once with
+// each variant, and the answers are reported side by side.
+type experiment struct {
+	Name      string         `json:"name"`
+	Model     string         `json:"model"`
+	Variables map[string]any `json:"variables"`
+
+	// Template of the prompt sent for each input, {{.input}} by default
+	Prompt string   `json:"prompt"`
+	Inputs []string `json:"inputs"`
+	// File of further inputs, read like a batch file
+	InputsFile string `json:"inputs_file"`
+
+	Variants []experimentVariant `json:"variants"`
+}
+
+// experimentVariant is a system prompt or a set of parameters under test.
+type experimentVariant struct {
+	Name        string   `json:"name"`
+	Model       string   `json:"model"`
+	System      string   `json:"system"`
+	Prompt      string   `json:"prompt"`
+	Temperature *float32 `json:"temperature"`
+	TopP        *float32 `json:"top_p"`
+	MaxTokens   int      `json:"max_tokens"`
+}
+
+// RunExperiment sends the inputs of an experiment with each of its variants
+// and writes a Markdown report comparing the answers.
+func RunExperiment(args []string) error {
+	vars := runVars{}
+	flags := flag.NewFlagSet("experiment", flag.ContinueOnError)
+	flags.Var(vars, "var", "set a variable, name=value (repeatable)")
+	out := flags.String("out", "", "write the report to this file instead of stdout")
+	concurrency := flags.Int("concurrency", defaultBatchConcurrency, "requests sent at the same time")
+
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: bubblechat experiment [--var name=value] [--out report.md] [--concurrency n] <experiment.yaml>")
+	}
+	*concurrency = max(*concurrency, 1)
+
+	e, err := LoadExperiment(files[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+
+	values := map[string]string{}
+	for name, value := range e.Variables {
+		values[name] = fmt.Sprint(value)
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+
+	// Every request is expanded before any is sent, so a template error
+	// costs nothing
+	requests := make([][]openai.ChatCompletionRequest, len(e.Inputs))
+	for i, input := range e.Inputs {
+		values["input"] = input
+		for _, v := range e.Variants {
+			req, err := v.Request(e, values)
+			if err != nil {
+				return fmt.Errorf("variant %s, input %d: %w", v.Name, i+1, err)
+			}
+			requests[i] = append(requests[i], req)
+		}
+	}
+
+	initializeClient()
+	daily := LoadDailyUsage()
+
+	results := make([][]batchResult, len(e.Inputs))
+	for i := range results {
+		results[i] = make([]batchResult, len(e.Variants))
+	}
+
+	var mu sync.Mutex
+	var done, failed int
+	var cost float64
+	total := len(e.Inputs) * len(e.Variants)
+
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i := range requests {
+		for j, req := range requests[i] {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				result := CompleteOnce(req)
+				results[i][j] = result
+
+				mu.Lock()
+				defer mu.Unlock()
+				done++
+				if result.Error != "" {
+					failed++
+				}
+				cost += result.Cost
+				daily.Add(result.Cost)
+				fmt.Fprintf(os.Stderr, "\r%d/%d answered, %d failed, $%.4f", done, total, failed, cost)
+			}()
+		}
+	}
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if _, err := io.WriteString(w, ExperimentReport(e, results)); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", *out)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests failed", failed, total)
+	}
+	return nil
+}
+
+// LoadExperiment reads an experiment and its inputs and checks that it can
+// run.
+func LoadExperiment(path string) (experiment, error) {
+	var e experiment
+	if err := ReadTemplateFile(path, &e); err != nil {
+		return e, err
+	}
+
+	if e.InputsFile != "" {
+		file := e.InputsFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return e, fmt.Errorf("inputs_file: %w", err)
+		}
+		entries, err := ParseBatch(data)
+		if err != nil {
+			return e, fmt.Errorf("inputs_file: %w", err)
+		}
+		for _, entry := range entries {
+			e.Inputs = append(e.Inputs, entry.Prompt)
+		}
+	}
+	if len(e.Inputs) == 0 {
+		return e, errors.New("no inputs")
+	}
+
+	if len(e.Variants) < 2 {
+		return e, errors.New("an experiment needs at least two variants")
+	}
+	names := map[string]bool{}
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		if v.Name == "" {
+			v.Name = string(rune('A' + i))
+		}
+		if names[v.Name] {
+			return e, fmt.Errorf("two variants are named %s", v.Name)
+		}
+		names[v.Name] = true
+	}
+	return e, nil
+}
+
+// Request is the request for the current input, set in values, with this
+// variant.
+func (v experimentVariant) Request(e experiment, values map[string]string) (openai.ChatCompletionRequest, error) {
+	system, err := expandRunTemplate(v.System, values)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, fmt.Errorf("system: %w", err)
+	}
+	prompt, err := expandRunTemplate(cmp.Or(v.Prompt, e.Prompt, "{{.input}}"), values)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, fmt.Errorf("prompt: %w", err)
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if system != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: system})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: prompt})
+
+	req := openai.ChatCompletionRequest{Model: v.ModelName(e), Messages: messages, MaxTokens: v.MaxTokens}
+	if v.Temperature != nil {
+		req.Temperature = nonZero(*v.Temperature)
+	}
+	if v.TopP != nil {
+		req.TopP = nonZero(*v.TopP)
+	}
+	return req, nil
+}
+
+// ModelName is the model this variant is sent to.
+func (v experimentVariant) ModelName(e experiment) string {
+	return cmp.Or(v.Model, e.Model, modelName)
+}
+
+// ExperimentReport compares the variants in a summary table, followed by the
+// answers to each input side by side.
+func ExperimentReport(e experiment, results [][]batchResult) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", cmp.Or(e.Name, "Experiment"))
+
+	row := func(label string, cell func(j int, v experimentVariant) string) {
+		b.WriteString("| " + label)
+		for j, v := range e.Variants {
+			b.WriteString(" | " + markdownCell(cell(j, v)))
+		}
+		b.WriteString(" |\n")
+	}
+	param := func(value *float32) string {
+		if value == nil {
+			return "default"
+		}
+		return fmt.Sprint(*value)
+	}
+	sum := func(j int, field func(batchResult) float64) (total float64, answered int) {
+		for i := range results {
+			if results[i][j].Error == "" {
+				answered++
+			}
+			total += field(results[i][j])
+		}
+		return total, answered
+	}
+
+	row("", func(_ int, v experimentVariant) string { return v.Name })
+	b.WriteString("|---" + strings.Repeat("|---", len(e.Variants)) + "|\n")
+	row("model", func(_ int, v experimentVariant) string { return v.ModelName(e) })
+	row("system", func(_ int, v experimentVariant) string { return v.System })
+	if slices.ContainsFunc(e.Variants, func(v experimentVariant) bool { return v.Prompt != "" }) {
+		row("prompt", func(_ int, v experimentVariant) string { return cmp.Or(v.Prompt, e.Prompt, "{{.input}}") })
+	}
+	row("temperature", func(_ int, v experimentVariant) string { return param(v.Temperature) })
+	row("top_p", func(_ int, v experimentVariant) string { return param(v.TopP) })
+	row("answered", func(j int, _ experimentVariant) string {
+		_, answered := sum(j, func(batchResult) float64 { return 0 })
+		return fmt.Sprintf("%d/%d", answered, len(results))
+	})
+	row("tokens", func(j int, _ experimentVariant) string {
+		prompt, _ := sum(j, func(r batchResult) float64 { return float64(r.PromptTokens) })
+		completion, _ := sum(j, func(r batchResult) float64 { return float64(r.CompletionTokens) })
+		return fmt.Sprintf("%.0f in, %.0f out", prompt, completion)
+	})
+	row("cost", func(j int, _ experimentVariant) string {
+		cost, _ := sum(j, func(r batchResult) float64 { return r.Cost })
+		return fmt.Sprintf("$%.4f", cost)
+	})
+	row("mean latency", func(j int, _ experimentVariant) string {
+		latency, _ := sum(j, func(r batchResult) float64 { return float64(r.LatencyMs) })
+		mean := time.Duration(latency/float64(len(results))) * time.Millisecond
+		return mean.Round(time.Millisecond).String()
+	})
+
+	for i, input := range e.Inputs {
+		heading, _, _ := strings.Cut(strings.TrimSpace(input), "\n")
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, truncate(heading, 80))
+		if heading != strings.TrimSpace(input) || len([]rune(heading)) > 80 {
+			b.WriteString(quoteMarkdown(input) + "\n\n")
+		}
+
+		for j, v := range e.Variants {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString("| " + markdownCell(v.Name))
+		}
+		b.WriteString(" |\n" + strings.Repeat("|---", len(e.Variants)) + "|\n")
+		for j := range e.Variants {
+			r := results[i][j]
+			answer := r.Answer
+			if r.Error != "" {
+				answer = "**Failed:** " + r.Error
+			}
+			b.WriteString("| " + markdownCell(answer) + " ")
+		}
+		b.WriteString("|\n")
+	}
+	return b.String()
+}
+
+// markdownCell puts text into a Markdown table cell, with its line breaks as
+// <br> and its pipes escaped.
+func markdownCell(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+func quoteMarkdown(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
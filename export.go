@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+const codeStyle = "github"
+
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 48rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.5 system-ui, sans-serif; color: #222; background: #fafafa; }
+header { color: #636363; border-bottom: 1px solid #ddd; margin-bottom: 1.5rem; }
+.message { margin: 1rem 0; padding: 0.75rem 1rem; border-radius: 8px; border-left: 4px solid; }
+.user { background: #f6effa; border-color: #cda9d6; white-space: pre-wrap; }
+.assistant { background: #eff9f4; border-color: #b7e4cf; }
+.role { font-size: 0.8rem; color: #636363; text-transform: uppercase; letter-spacing: 0.05em; }
+pre { padding: 0.75rem; border-radius: 6px; overflow-x: auto; }
+code { font-family: ui-monospace, monospace; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25rem 0.5rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{.Model}} · {{.Date}}</p>
+</header>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="role">{{.Role}}{{with .Model}} · {{.}}{{end}}{{with .Seed}} · seed {{.}}{{end}}</div>
+{{.Body}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+type exportMessage struct {
+	Role  string
+	Model string
+	Seed  string
+	Body  template.HTML
+}
+
+func init() {
+	registerCommand(command{
+		name:        "export",
+		usage:       "/export html [path]",
+		description: "Save the conversation as a standalone HTML file",
+		run: func(m *model, args string) tea.Cmd {
+			format, path, _ := strings.Cut(args, " ")
+			if format != "html" {
+				AddNotice(m, "Usage: /export html [path]")
+				return nil
+			}
+
+			path = strings.TrimSpace(path)
+			if path == "" {
+				path = "bubblechat-" + currentSession.ID + ".html"
+			}
+
+			page, err := ExportHTML(currentSession, chatMessages)
+			if err == nil {
+				err = os.WriteFile(path, page, 0o644)
+			}
+			if err != nil {
+				AddNotice(m, "Could not export: "+err.Error())
+				return nil
+			}
+
+			AddNotice(m, "Exported to "+path)
+			return nil
+		},
+	})
+}
+
+// ExportHTML renders a conversation as a self-contained HTML page. Replies are
+// rendered as markdown with syntax highlighted code blocks, prompts as text.
+func ExportHTML(s session, history []openai.ChatCompletionMessage) ([]byte, error) {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(codeRenderer{}, 100),
+		)),
+	)
+
+	var messages []exportMessage
+	for i, msg := range history {
+		switch {
+		case msg.Role == openai.ChatMessageRoleUser:
+			messages = append(messages, exportMessage{
+				Role: msg.Role,
+				Body: template.HTML(template.HTMLEscapeString(msg.Content)),
+			})
+		case msg.Role == openai.ChatMessageRoleAssistant && msg.Content != "":
+			var body bytes.Buffer
+			if err := md.Convert([]byte(msg.Content), &body); err != nil {
+				return nil, err
+			}
+			message := exportMessage{Role: msg.Role, Body: template.HTML(body.String())}
+			if s.MixedModels() {
+				message.Model = s.ReplyModels[i]
+			}
+			if seed := s.Generations[i].Seed; seed != nil {
+				message.Seed = strconv.Itoa(*seed)
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	title := s.Title
+	if title == "" {
+		title = sessionTitle(history)
+	}
+	date := s.Updated
+	if date.IsZero() {
+		date = s.Created
+	}
+
+	var page bytes.Buffer
+	err := exportTemplate.Execute(&page, struct {
+		Title    string
+		Model    string
+		Date     string
+		Messages []exportMessage
+	}{title, s.Model, date.Format("2006-01-02 15:04"), messages})
+
+	return page.Bytes(), err
+}
+
+// codeRenderer highlights fenced code blocks with inline styles, so the page
+// needs no external stylesheet.
+type codeRenderer struct{}
+
+func (r codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCode)
+}
+
+func (r codeRenderer) renderFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	block := node.(*ast.FencedCodeBlock)
+
+	var code strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	lexer := lexers.Get(string(block.Language(source)))
+	if lexer == nil {
+		lexer = lexers.Analyse(code.String())
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	formatter := chromahtml.New(chromahtml.TabWidth(4))
+	if err := formatter.Format(w, styles.Get(codeStyle), tokens); err != nil {
+		return ast.WalkStop, err
+	}
+
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The fake provider answers chat requests from canned replies without a
+// server, for demos and trying the UI without a key. Its embeddings are
+// hashed words, so texts sharing words still come out close. Replies depend only on
+// the prompt and the seed, and take a fixed time, so recordings of a demo
+// come out the same every time.
+
+const (
+	fakeLatency     = 1200 * time.Millisecond
+	fakeFingerprint = "fp_demo"
+
+	// Seed --demo samples with, which picks among the canned replies
+	demoSeed = 42
+
+	fakeEmbeddingDimensions = 64
+)
+
+var fakeReplies = []string{
+	"Here is a short answer to **%s**:\n\n" +
+		"1. Start from the smallest example that shows the problem\n" +
+		"2. Change one thing at a time\n" +
+		"3. Keep notes of what you tried\n\n" +
+		"That usually narrows it down quickly.",
+
+	"About **%s**, a sketch in Go:\n\n" +
+		"```go\n" +
+		"func main() {\n" +
+		"\tfor i := range 3 {\n" +
+		"\t\tfmt.Println(\"step\", i+1)\n" +
+		"\t}\n" +
+		"}\n" +
+		"```\n\n" +
+		"Run it with `go run .` and adjust from there.",
+
+	"Comparing the options for **%s**:\n\n" +
+		"| Option | Speed | Effort |\n" +
+		"|--------|-------|--------|\n" +
+		"| Simple | fast  | low    |\n" +
+		"| Robust | slower | medium |\n" +
+		"| Custom | varies | high  |\n\n" +
+		"The simple one is a good default until it is not.",
+
+	"Good question. **%s** comes down to two things: what you need today, " +
+		"and what you expect to change. Pick the approach that keeps the " +
+		"second cheap, even if the first takes a little longer.",
+}
+
+var (
+	demoMode bool
+
+	// Empty config directory used instead of the user's with --demo
+	demoConfigDir string
+)
+
+// StartDemo gives --demo a config directory of its own, so it runs with the
+// default config and theme and nothing of the user's sessions, drafts or
+// usage shows up in the recording.
+func StartDemo() error {
+	dir, err := os.MkdirTemp("", appName+"-demo-")
+	if err != nil {
+		return err
+	}
+	demoConfigDir = dir
+	return nil
+}
+
+// ApplyDemoConfig switches the loaded config to the fake provider and the
+// demo seed.
+func ApplyDemoConfig() {
+	cfg.Provider = providerFake
+	seed := demoSeed
+	cfg.Seed = &seed
+}
+
+// fakeTransport serves the API from the canned replies.
+type fakeTransport struct{}
+
+func (fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/models"):
+		return fakeResponse(req, http.StatusOK, openai.ModelsList{
+			Models: []openai.Model{{ID: defaultModel, Object: "model", OwnedBy: "demo"}},
+		})
+
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions"):
+		var chat openai.ChatCompletionRequest
+		if err := json.NewDecoder(req.Body).Decode(&chat); err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(fakeLatency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return fakeResponse(req, http.StatusOK, FakeCompletion(chat))
+
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/embeddings"):
+		var embedding openai.EmbeddingRequest
+		if err := json.NewDecoder(req.Body).Decode(&embedding); err != nil {
+			return nil, err
+		}
+		return fakeResponse(req, http.StatusOK, FakeEmbeddings(embedding, embeddingInputs(embedding.Input)))
+	}
+
+	return fakeResponse(req, http.StatusNotFound, openai.ErrorResponse{Error: &openai.APIError{
+		Type:    "invalid_request_error",
+		Message: fmt.Sprintf("the fake provider does not serve %s %s", req.Method, req.URL.Path),
+	}})
+}
+
+func fakeResponse(req *http.Request, status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := cassetteEntry{
+		Status: status,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   string(data),
+	}
+	return entry.response(req), nil
+}
+
+// FakeCompletion answers the last prompt of req with a canned reply picked
+// by the prompt and seed.
+func FakeCompletion(req openai.ChatCompletionRequest) openai.ChatCompletionResponse {
+	var prompt string
+	for _, msg := range req.Messages {
+		if msg.Role == openai.ChatMessageRoleUser {
+			prompt = msg.Content
+		}
+	}
+	topic, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	if runes := []rune(topic); len(runes) > maxTitleLength {
+		topic = string(runes[:maxTitleLength-1]) + "…"
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	if req.Seed != nil {
+		fmt.Fprint(h, *req.Seed)
+	}
+	reply := fmt.Sprintf(fakeReplies[h.Sum32()%uint32(len(fakeReplies))], topic)
+
+	var promptTokens int
+	for _, msg := range req.Messages {
+		promptTokens += EstimateTokens(msg.Content)
+	}
+	completionTokens := EstimateTokens(reply)
+
+	return openai.ChatCompletionResponse{
+		ID:                "chatcmpl-demo",
+		Object:            "chat.completion",
+		Model:             req.Model,
+		SystemFingerprint: fakeFingerprint,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply},
+			FinishReason: openai.FinishReasonStop,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}
+}
+
+// FakeEmbeddings embeds inputs by hashing their words, so texts sharing
+// words come out close without a model.
+func FakeEmbeddings(req openai.EmbeddingRequest, inputs []string) openai.EmbeddingResponse {
+	resp := openai.EmbeddingResponse{Object: "list", Model: req.Model}
+	for i, input := range inputs {
+		vector := make([]float32, fakeEmbeddingDimensions)
+		for _, word := range tokenize(input) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%fakeEmbeddingDimensions]++
+		}
+		normalize(vector)
+
+		resp.Data = append(resp.Data, openai.Embedding{Object: "embedding", Embedding: vector, Index: i})
+		resp.Usage.PromptTokens += EstimateTokens(input)
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp
+}
+
+// embeddingInputs reads the input of an embeddings request, a string or a
+// list of them.
+func embeddingInputs(input any) []string {
+	switch input := input.(type) {
+	case string:
+		return []string{input}
+	case []any:
+		var inputs []string
+		for _, item := range input {
+			if s, ok := item.(string); ok {
+				inputs = append(inputs, s)
+			}
+		}
+		return inputs
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+	"golang.org/x/net/html"
+)
+
+const (
+	fetchTimeout  = 20 * time.Second
+	maxFetchBytes = 2 * 1024 * 1024
+	maxFetchChars = 20000
+	fetchToolName = "fetch_url"
+	// Rounds of tool calls a reply may take before the request is given up
+	maxToolRounds    = 5
+	fetchContextHead = "Content fetched from %s (%s). Use it as context and cite the URL when relevant.\n\n%s"
+)
+
+var fetchTool = openai.Tool{
+	Type: openai.ToolTypeFunction,
+	Function: &openai.FunctionDefinition{
+		Name:        fetchToolName,
+		Description: "Download a web page and return its readable text content.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "description": "Absolute http(s) URL to fetch"}
+			},
+			"required": ["url"]
+		}`),
+	},
+}
+
+type fetchMsg struct {
+	page fetchedPage
+	err  error
+}
+
+type fetchedPage struct {
+	url   string
+	title string
+	text  string
+}
+
+func init() {
+	registerCommand(command{
+		name:        "fetch",
+		usage:       "/fetch <url>",
+		description: "Download a page and add its text as context",
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				AddNotice(m, "Usage: /fetch <url>")
+				return nil
+			}
+			AddNotice(m, "Fetching "+args+"…")
+			return FetchCmd(args)
+		},
+	})
+}
+
+func FetchCmd(rawURL string) tea.Cmd {
+	return func() tea.Msg {
+		page, err := FetchPage(rawURL)
+		return fetchMsg{page: page, err: err}
+	}
+}
+
+// FetchPage downloads a URL and reduces it to readable text. HTML is
+// converted to lightweight markdown, other text types are kept as is.
+func FetchPage(rawURL string) (fetchedPage, error) {
+	page := fetchedPage{url: rawURL}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return page, fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	resp, err := fetchClient.Get(rawURL)
+	if err != nil {
+		return page, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("%s returned %s", rawURL, resp.Status)
+	}
+
+	body := io.LimitReader(resp.Body, maxFetchBytes)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.title, page.text, err = htmlToText(body)
+		if err != nil {
+			return page, err
+		}
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json":
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return page, err
+		}
+		page.text = string(content)
+	default:
+		return page, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	if page.title == "" {
+		page.title = u.Host + u.Path
+	}
+	if runes := []rune(page.text); len(runes) > maxFetchChars {
+		page.text = string(runes[:maxFetchChars]) + "\n\n[truncated]"
+	}
+
+	return page, nil
+}
+
+// fetchClient only connects to public addresses, checked after the name is
+// resolved and again on every redirect, so neither a page nor a prompt
+// injected into one can reach the local network or cloud metadata.
+var fetchClient = &http.Client{
+	Timeout: fetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: fetchTimeout, Control: publicOnly}).DialContext,
+	},
+}
+
+// publicOnly refuses connections to loopback, private, link-local and other
+// non-public addresses.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return fmt.Errorf("refusing to fetch from non-public address %s", ip)
+	}
+	return nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "svg": true,
+	"nav": true, "footer": true, "header": true, "form": true,
+	"iframe": true, "template": true,
+}
+
+func htmlToText(r io.Reader) (string, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	var title string
+	var b strings.Builder
+
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		if n.Type == html.TextNode {
+			if pre {
+				b.WriteString(n.Data)
+			} else if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				b.WriteString(text + " ")
+			}
+			return
+		}
+
+		if n.Type == html.ElementNode {
+			if skippedElements[n.Data] {
+				return
+			}
+
+			switch n.Data {
+			case "title":
+				if n.FirstChild != nil && title == "" {
+					title = strings.TrimSpace(n.FirstChild.Data)
+				}
+				return
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				b.WriteString("\n\n" + strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+			case "p", "div", "section", "article", "table", "blockquote":
+				b.WriteString("\n\n")
+			case "br", "tr":
+				b.WriteString("\n")
+			case "li":
+				b.WriteString("\n- ")
+			case "pre":
+				b.WriteString("\n\n```\n")
+				pre = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+
+		if n.Type == html.ElementNode && n.Data == "pre" {
+			b.WriteString("\n```\n\n")
+		}
+	}
+	walk(doc, false)
+
+	text := blankLines.ReplaceAllString(b.String(), "\n\n")
+	return title, strings.TrimSpace(text), nil
+}
+
+// FetchContextMessage wraps a fetched page as a system message for the chat
+// history.
+func FetchContextMessage(page fetchedPage) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf(fetchContextHead, page.url, page.title, page.text),
+	}
+}
+
+func FetchCitation(page fetchedPage) string {
+	return fmt.Sprintf("Source: %s <%s>", page.title, page.url)
+}
+
+// runFetchTool executes a fetch_url tool call requested by the model.
+func runFetchTool(call openai.ToolCall) (string, *fetchedPage) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		return "Error: invalid arguments: " + err.Error(), nil
+	}
+
+	page, err := FetchPage(args.URL)
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	return page.text, &page
+}
@@ -0,0 +1,46 @@
+package main
+
+const (
+	focusColor   = "#cda9d6"
+	unfocusColor = ""
+)
+
+// focus is the pane receiving key input. Global bindings such as quit and
+// help work regardless of focus.
+type focus int
+
+const (
+	focusInput focus = iota
+	focusViewport
+	focusSidebar
+	focusCount
+)
+
+// NextFocus cycles through the visible panes.
+func (m *model) NextFocus() {
+	next := (m.focus + 1) % focusCount
+	if next == focusSidebar && !m.sidebar.visible {
+		next = (next + 1) % focusCount
+	}
+	m.SetFocus(next)
+}
+
+func (m *model) SetFocus(f focus) {
+	m.focus = f
+
+	viewportColor := unfocusColor
+	if f == focusInput {
+		m.textarea.Focus()
+	} else {
+		m.textarea.Blur()
+	}
+	if f == focusViewport {
+		viewportColor = focusColor
+	}
+
+	m.sidebar.SetFocused(f == focusSidebar)
+
+	// The header shares its border with the viewport
+	m.viewport.Style = m.viewport.Style.BorderForeground(Color(viewportColor))
+	m.header.style = m.header.style.BorderForeground(Color(viewportColor))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Folded messages show their first lines and a count of the rest. Whether a
+// message is folded is kept on the message, so it survives messages being
+// added around it and identical messages fold on their own.
+const foldPreviewLines = 8
+
+// foldState is whether a message was folded or unfolded by hand.
+type foldState int
+
+const (
+	// Folded by the fold_lines config
+	foldAuto foldState = iota
+	foldOpen
+	foldClosed
+)
+
+// IsFolded reports whether msg, rendered as block, is shown folded. Messages
+// without an explicit toggle fold when they are longer than the fold_lines
+// config.
+func (m model) IsFolded(msg Message, block string) bool {
+	lines := strings.Count(block, "\n") + 1
+	if lines <= foldPreviewLines {
+		return false
+	}
+
+	switch msg.Fold {
+	case foldOpen:
+		return false
+	case foldClosed:
+		return true
+	}
+	return cfg.FoldLines > 0 && lines > cfg.FoldLines
+}
+
+func (m model) FoldBlock(block string) string {
+	lines := strings.Split(block, "\n")
+	marker := m.noticeStyle.Render(fmt.Sprintf("… %d more lines", len(lines)-foldPreviewLines))
+	return strings.Join(lines[:foldPreviewLines], "\n") + "\n" + marker
+}
+
+// ToggleFold folds or unfolds message i.
+func (m *model) ToggleFold(i int) {
+	msg := &m.messages[i]
+	block := m.renderCache.Render(m, msg.Rendered)
+
+	folded := m.IsFolded(*msg, block)
+	msg.Fold = foldClosed
+	if folded {
+		msg.Fold = foldOpen
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// With verify_grounding set, a reply drawing on retrieved excerpts is checked
+// by a second request against those excerpts. The claims the verifier finds
+// unsupported are listed below the reply with a warning marker, and a reply
+// without any is labeled grounded.
+
+const verifierSystemPrompt = "You check answers against the sources they were given. " +
+	"The user sends numbered source excerpts and an answer. List every claim of the answer " +
+	"that the excerpts do not support, quoting or closely paraphrasing it. " +
+	`Reply with a JSON object: {"unsupported": ["<claim>", ...]}, with an empty list when the excerpts support every claim.`
+
+// grounding is the verdict on a reply, kept by its index in the session.
+type grounding struct {
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+type groundingMsg struct {
+	tab       int
+	index     int
+	reply     string
+	grounding grounding
+	err       error
+}
+
+// VerifyCmd checks the reply at index i against the chunks retrieved for it.
+func VerifyCmd(tab, i int, reply string, chunks []chunk) tea.Cmd {
+	model := modelName
+
+	var b strings.Builder
+	b.WriteString("Sources:")
+	for n, c := range chunks {
+		fmt.Fprintf(&b, "\n\n--- [%d] %s ---\n%s", n+1, c.source, c.text)
+	}
+	b.WriteString("\n\nAnswer:\n\n" + reply)
+	content := b.String()
+
+	return func() tea.Msg {
+		req := openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: verifierSystemPrompt},
+				UserMessage(content),
+			},
+		}
+		if SupportsJSONMode(model) {
+			req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		}
+
+		resp, err := callChat(ctx, req)
+		if err == nil && len(resp.Choices) == 0 {
+			err = errNoChoices
+		}
+		msg := groundingMsg{tab: tab, index: i, reply: reply, err: err}
+		if err == nil {
+			msg.grounding, msg.err = parseGrounding(resp.Choices[0].Message.Content)
+		}
+		return msg
+	}
+}
+
+// parseGrounding reads the verifier's JSON reply.
+func parseGrounding(content string) (grounding, error) {
+	var g grounding
+	trimmed := strings.TrimSpace(content)
+	trimmed = strings.TrimPrefix(strings.TrimSuffix(trimmed, "```"), "```json")
+	if err := json.Unmarshal([]byte(trimmed), &g); err != nil {
+		return g, errors.New("the verifier did not reply with the expected JSON")
+	}
+	return g, nil
+}
+
+// HandleGrounding keeps the verdict on a reply and shows it, unless the
+// conversation moved on from the reply meanwhile.
+func HandleGrounding(m *model, msg groundingMsg) {
+	if msg.tab != m.ActiveTabID() || msg.index >= len(chatMessages) || chatMessages[msg.index].Content != msg.reply {
+		return
+	}
+	if msg.err != nil {
+		AddNotice(m, "Could not check the reply against its sources: "+msg.err.Error())
+		return
+	}
+
+	currentSession.RecordGrounding(msg.index, msg.grounding)
+	SaveCurrentSession()
+	LabelReplies(m)
+}
+
+// RecordGrounding keeps the verdict on the reply at index i.
+func (s *session) RecordGrounding(i int, g grounding) {
+	if s.Groundings == nil {
+		s.Groundings = map[int]grounding{}
+	}
+	s.Groundings[i] = g
+}
+
+// RenderUnsupported lists the unsupported claims of a reply below it.
+func (m model) RenderUnsupported(claims []string) string {
+	indent := strings.Repeat(" ", lipgloss.Width(ResponsePrefix()))
+	style := lipgloss.NewStyle().Foreground(Color(warningColor))
+
+	lines := []string{indent + icons.Warning + " Not supported by the sources:"}
+	for _, claim := range claims {
+		claim = wordwrap.String(claim, messageWidth(ResponsePrefix())-2)
+		lines = append(lines, indent+"- "+strings.ReplaceAll(claim, "\n", "\n"+indent+"  "))
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// headerTransport adds the configured headers to every API request, for
+// gateways that need an organization id or their own auth. Values may refer
+// to environment variables as $NAME or ${NAME}, to keep secrets out of the
+// config.
+type headerTransport struct {
+	headers   http.Header
+	transport http.RoundTripper
+}
+
+func NewHeaderTransport(headers map[string]string, transport http.RoundTripper) http.RoundTripper {
+	t := headerTransport{headers: http.Header{}, transport: transport}
+	for name, value := range headers {
+		t.headers.Set(name, os.ExpandEnv(value))
+	}
+	return t
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.transport.RoundTrip(req)
+}
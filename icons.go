@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+
+	"github.com/charmbracelet/bubbles/spinner"
+)
+
+const (
+	iconsAuto    = "auto"
+	iconsNerd    = "nerd"
+	iconsDefault = "default"
+	iconsASCII   = "ascii"
+)
+
+// An iconSet holds the glyphs used for roles, tool calls and request state.
+type iconSet struct {
+	User      string
+	Assistant string
+	Tool      string
+	Success   string
+	Error     string
+	Warning   string
+	Spinner   spinner.Spinner
+	// Sparkline levels, lowest first, none to skip the sparkline
+	Spark []rune
+}
+
+var (
+	nerdIcons = iconSet{
+		User:      "\uf007",     // nf-fa-user
+		Assistant: "\U000f06a9", // nf-md-robot
+		Tool:      "\uf0ad",     // nf-fa-wrench
+		Success:   "\uf00c",     // nf-fa-check
+		Error:     "\uf00d",     // nf-fa-xmark
+		Warning:   "\uf071",     // nf-fa-triangle_exclamation
+		Spinner: spinner.Spinner{
+			// Progress indicator frames, added in Nerd Fonts 3.0
+			Frames: []string{"\uee06", "\uee07", "\uee08", "\uee09", "\uee0a", "\uee0b"},
+			FPS:    spinner.MiniDot.FPS,
+		},
+		Spark: sparkBars,
+	}
+
+	defaultIcons = iconSet{
+		User:      ">",
+		Assistant: ">",
+		Tool:      "⚙",
+		Success:   "✔",
+		Error:     "✘",
+		Warning:   "⚠",
+		Spinner:   spinner.MiniDot,
+		Spark:     sparkBars,
+	}
+
+	asciiIcons = iconSet{
+		User:      ">",
+		Assistant: ">",
+		Tool:      "*",
+		Success:   "ok",
+		Error:     "x",
+		Warning:   "!",
+		Spinner:   spinner.Line,
+	}
+
+	icons = defaultIcons
+)
+
+// IconSet resolves the configured icons. There is no way to ask a terminal
+// for its fonts, so auto only picks Nerd Font glyphs in terminals that bundle
+// them or when NERD_FONT is set.
+func IconSet(name string) iconSet {
+	switch name {
+	case iconsNerd:
+		return nerdIcons
+	case iconsASCII:
+		return asciiIcons
+	case iconsDefault:
+		return defaultIcons
+	}
+
+	if os.Getenv("NERD_FONT") != "" || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("TERM_PROGRAM") == "ghostty" {
+		return nerdIcons
+	}
+	return defaultIcons
+}
+
+// ApplyIcons selects the configured icon set. Prefixes still at their default
+// show the set's role icons.
+func ApplyIcons() {
+	icons = IconSet(cfg.Icons)
+
+	if cfg.Theme.PromptPrefix == promptPrefix {
+		cfg.Theme.PromptPrefix = icons.User + " "
+	}
+	if cfg.Theme.ResponsePrefix == responsePrefix {
+		cfg.Theme.ResponsePrefix = icons.Assistant + " "
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	imagesAuto   = "auto"
+	imagesKitty  = "kitty"
+	imagesITerm2 = "iterm2"
+	imagesSixel  = "sixel"
+	imagesNone   = "none"
+
+	maxImageBytes  = 10 * 1024 * 1024
+	maxImageCols   = 40
+	maxImageRows   = 20
+	kittyChunkSize = 4096
+
+	// Kitty draws the image over cells holding this character
+	kittyPlaceholder = "\U0010EEEE"
+)
+
+// Row and column numbers of a kitty placeholder are given by combining
+// diacritics, in this order, see kitty's rowcolumn-diacritics.txt.
+var kittyDiacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F, 0x0346, 0x034A,
+	0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357, 0x035B, 0x0363, 0x0364, 0x0365,
+}
+
+var (
+	markdownImage = regexp.MustCompile(`!\[([^\]]*)\]\((https?://[^)\s]+)\)`)
+	bareImageURL  = regexp.MustCompile(`https?://\S+?\.(?:png|jpe?g|gif)\b`)
+
+	nextImageID atomic.Uint32
+)
+
+type inlineImage struct {
+	alt string
+	url string
+}
+
+type imageMsg struct {
+	image inlineImage
+	// Escape sequence uploading or drawing the image, and with kitty the
+	// placeholders it is drawn over
+	transmit string
+	block    string
+	err      error
+}
+
+// terminalOutput is the program's output. Frames are written to it whole, so
+// sequences written between them, like image uploads, never land inside one.
+var terminalOutput = &syncedOutput{File: os.Stdout}
+
+type syncedOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (o *syncedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.Write(p)
+}
+
+func init() {
+	registerCommand(command{
+		name:        "image",
+		usage:       "/image <prompt>",
+		description: "Generate an image and show it inline",
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				AddNotice(m, "Usage: /image <prompt>")
+				return nil
+			}
+			AddNotice(m, "Generating image…")
+			return GenerateImageCmd(args)
+		},
+	})
+	registerCommand(command{
+		name:        "view-image",
+		usage:       "/view-image [n]",
+		description: "Draw an iTerm2 or sixel image, the last by default",
+		run: func(m *model, args string) tea.Cmd {
+			if len(m.images) == 0 {
+				AddNotice(m, "No images to view")
+				return nil
+			}
+			n := len(m.images)
+			if args != "" {
+				var err error
+				if n, err = strconv.Atoi(args); err != nil || n < 1 || n > len(m.images) {
+					AddNotice(m, fmt.Sprintf("Usage: /view-image [n], n from 1 to %d", len(m.images)))
+					return nil
+				}
+			}
+			return ViewImage(m.images[n-1])
+		},
+	})
+}
+
+// ImageProtocol resolves the configured protocol. Only kitty's unicode
+// placeholders survive the viewport's line handling, so iTerm2 and sixel
+// images are drawn away from it with /view-image, and other terminals get a
+// link instead.
+func ImageProtocol() string {
+	switch cfg.Images {
+	case imagesKitty, imagesITerm2, imagesSixel, imagesNone:
+		return cfg.Images
+	}
+
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty":
+		return imagesKitty
+	case program == "iTerm.app" || program == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return imagesITerm2
+	case strings.Contains(term, "sixel") || term == "foot" || term == "foot-extra" || term == "mlterm":
+		return imagesSixel
+	}
+	return imagesNone
+}
+
+// ExtractImages finds markdown images and bare image links in text.
+func ExtractImages(text string) []inlineImage {
+	var images []inlineImage
+	seen := map[string]bool{}
+
+	for _, match := range markdownImage.FindAllStringSubmatch(text, -1) {
+		seen[match[2]] = true
+		images = append(images, inlineImage{alt: match[1], url: match[2]})
+	}
+	for _, url := range bareImageURL.FindAllString(text, -1) {
+		if !seen[url] {
+			seen[url] = true
+			images = append(images, inlineImage{url: url})
+		}
+	}
+
+	return images
+}
+
+// ShowImages displays images found in a response, inline when the terminal
+// supports it and as links otherwise.
+func ShowImages(m *model, text string) tea.Cmd {
+	images := ExtractImages(text)
+	if len(images) == 0 {
+		return nil
+	}
+
+	if ImageProtocol() == imagesNone {
+		for _, img := range images {
+			AddNotice(m, ImagePlaceholder(img))
+		}
+		return nil
+	}
+
+	cmds := make([]tea.Cmd, len(images))
+	for i, img := range images {
+		cmds[i] = LoadImageCmd(img)
+	}
+	return tea.Batch(cmds...)
+}
+
+func ImagePlaceholder(img inlineImage) string {
+	alt := img.alt
+	if alt == "" {
+		alt = "image"
+	}
+	return fmt.Sprintf("[%s] %s", alt, img.url)
+}
+
+func GenerateImageCmd(prompt string) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := client.CreateImage(ctx, openai.ImageRequest{
+			Prompt:         prompt,
+			N:              1,
+			Size:           openai.CreateImageSize512x512,
+			ResponseFormat: openai.CreateImageResponseFormatURL,
+		})
+		if err != nil {
+			return imageMsg{err: err}
+		}
+		if len(resp.Data) == 0 {
+			return imageMsg{err: fmt.Errorf("no image returned")}
+		}
+
+		img := inlineImage{alt: prompt, url: resp.Data[0].URL}
+		if ImageProtocol() == imagesNone {
+			return imageMsg{image: img}
+		}
+		return LoadImageCmd(img)()
+	}
+}
+
+func LoadImageCmd(img inlineImage) tea.Cmd {
+	return func() tea.Msg {
+		transmit, block, err := loadImage(img.url, ImageProtocol())
+		return imageMsg{image: img, transmit: transmit, block: block, err: err}
+	}
+}
+
+// loadImage downloads the image at url and returns the sequence that uploads
+// or draws it with protocol, and for kitty the placeholder block it is drawn
+// over.
+func loadImage(url, protocol string) (transmit, block string, err error) {
+	// Image links come from replies, so they are held to the same public
+	// addresses as fetched pages
+	resp, err := fetchClient.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	decoded, _, err := image.Decode(io.LimitReader(resp.Body, maxImageBytes))
+	if err != nil {
+		return "", "", err
+	}
+	bounds := decoded.Bounds()
+	if bounds.Empty() {
+		return "", "", fmt.Errorf("%s is an empty image", url)
+	}
+
+	// Kitty only takes PNG for direct transmission, iTerm2 any format
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, decoded); err != nil {
+		return "", "", err
+	}
+
+	// Terminal cells are roughly twice as tall as wide
+	cols := maxImageCols
+	rows := max(1, cols*bounds.Dy()/bounds.Dx()/2)
+	if rows > maxImageRows {
+		rows = maxImageRows
+		cols = max(1, rows*2*bounds.Dx()/bounds.Dy())
+	}
+
+	switch protocol {
+	case imagesITerm2:
+		return iTerm2Image(encoded.Bytes(), cols, rows), "", nil
+	case imagesSixel:
+		return SixelImage(decoded, cols*sixelCellWidth), "", nil
+	}
+	id := nextImageID.Add(1)
+	return kittyTransmit(id, encoded.Bytes(), cols, rows), kittyPlaceholders(id, cols, rows), nil
+}
+
+// ShowImage uploads the image of msg between frames and adds its
+// placeholders to the transcript. Images without placeholders are kept for
+// /view-image.
+func ShowImage(m *model, msg imageMsg) {
+	if msg.block == "" {
+		m.images = append(m.images, msg)
+		AddNotice(m, fmt.Sprintf("%s · /view-image %d draws it", ImagePlaceholder(msg.image), len(m.images)))
+		return
+	}
+
+	if _, err := terminalOutput.WriteString(msg.transmit); err != nil {
+		AddNotice(m, "Could not show image: "+err.Error())
+		return
+	}
+	AddMessage(m, ImageMessage(msg.block))
+}
+
+// iTerm2Image draws an image file over cols by rows cells at the cursor.
+func iTerm2Image(data []byte, cols, rows int) string {
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+}
+
+// imageView draws an image on the terminal's main screen, where the
+// interface does not draw over it, until enter is pressed.
+type imageView struct {
+	image  imageMsg
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// ViewImage leaves the interface to show the image of msg.
+func ViewImage(msg imageMsg) tea.Cmd {
+	return tea.Exec(&imageView{image: msg}, func(err error) tea.Msg {
+		if err != nil {
+			return fmt.Errorf("showing image: %w", err)
+		}
+		return nil
+	})
+}
+
+func (v *imageView) SetStdin(r io.Reader)  { v.stdin = r }
+func (v *imageView) SetStdout(w io.Writer) { v.stdout = w }
+func (v *imageView) SetStderr(io.Writer)   {}
+
+func (v *imageView) Run() error {
+	if _, err := fmt.Fprintf(v.stdout, "\n%s\n%s\n\nPress enter to return", ImagePlaceholder(v.image.image), v.image.transmit); err != nil {
+		return err
+	}
+	_, err := bufio.NewReader(v.stdin).ReadString('\n')
+	return err
+}
+
+// kittyTransmit uploads a PNG and creates a virtual placement for it that is
+// shown wherever its placeholder cells are drawn.
+func kittyTransmit(id uint32, data []byte, cols, rows int) string {
+	payload := base64.StdEncoding.EncodeToString(data)
+
+	var b strings.Builder
+	for i := 0; i < len(payload); i += kittyChunkSize {
+		end := min(i+kittyChunkSize, len(payload))
+		more := 1
+		if end == len(payload) {
+			more = 0
+		}
+
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,U=1,f=100,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, payload[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, payload[i:end])
+		}
+	}
+	return b.String()
+}
+
+// kittyPlaceholders builds the text block the image is drawn over. The image
+// id is carried in the foreground color, and cells after the first in a row
+// inherit its row and continue its column.
+func kittyPlaceholders(id uint32, cols, rows int) string {
+	color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", id>>16&0xff, id>>8&0xff, id&0xff)
+	rows = min(rows, len(kittyDiacritics))
+
+	lines := make([]string, rows)
+	for r := range lines {
+		lines[r] = color + kittyPlaceholder + string(kittyDiacritics[r]) + string(kittyDiacritics[0]) +
+			strings.Repeat(kittyPlaceholder, cols-1) + "\x1b[39m"
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// chatGPTConversation is one conversation of ChatGPT's conversations.json
+// export. Messages form a tree, edits and regenerations branch off, and the
+// shown conversation is the path from current_node up to the root.
+type chatGPTConversation struct {
+	Title       string                 `json:"title"`
+	CreateTime  float64                `json:"create_time"`
+	UpdateTime  float64                `json:"update_time"`
+	CurrentNode string                 `json:"current_node"`
+	Mapping     map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		Content struct {
+			Parts []json.RawMessage `json:"parts"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// RunImport converts the conversations in a file to saved sessions, for
+// `bubblechat import <file>`.
+func RunImport(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: bubblechat import <file>")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	sessions, err := ImportSessions(data, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	for _, s := range sessions {
+		if err := SaveSession(s); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Imported %d conversations to %s\n", len(sessions), sessionsDir())
+	return nil
+}
+
+// ImportSessions reads a ChatGPT export, a single conversation from one, or
+// OpenAI messages, either as a list or as {"messages": [...]}.
+func ImportSessions(data []byte, now time.Time) ([]session, error) {
+	data = bytes.TrimSpace(data)
+
+	var conversations []chatGPTConversation
+	var messages []openai.ChatCompletionMessage
+
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		if len(items) > 0 && items[0]["mapping"] != nil {
+			if err := json.Unmarshal(data, &conversations); err != nil {
+				return nil, err
+			}
+		} else if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, []byte("{")):
+		var object struct {
+			Mapping  json.RawMessage                `json:"mapping"`
+			Messages []openai.ChatCompletionMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+		if object.Mapping != nil {
+			var c chatGPTConversation
+			if err := json.Unmarshal(data, &c); err != nil {
+				return nil, err
+			}
+			conversations = append(conversations, c)
+		}
+		messages = object.Messages
+	default:
+		return nil, errors.New("not a JSON export")
+	}
+
+	var sessions []session
+	used := map[string]bool{}
+	addSession := func(s session) {
+		if len(s.Messages) == 0 {
+			return
+		}
+		// Exports can hold conversations started in the same millisecond
+		for used[s.ID] || sessionExists(s.ID) {
+			s.Created = s.Created.Add(time.Millisecond)
+			s.ID = s.Created.Format(sessionIDLayout)
+		}
+		used[s.ID] = true
+		sessions = append(sessions, s)
+	}
+
+	for _, c := range conversations {
+		addSession(c.Session())
+	}
+	if len(messages) > 0 {
+		addSession(session{
+			ID:       now.Format(sessionIDLayout),
+			Title:    sessionTitle(messages),
+			Model:    modelName,
+			Created:  now,
+			Updated:  now,
+			Messages: messages,
+		})
+	}
+
+	if len(sessions) == 0 {
+		return nil, errors.New("no conversations found")
+	}
+	return sessions, nil
+}
+
+func sessionExists(id string) bool {
+	_, err := os.Stat(sessionPath(id))
+	return err == nil
+}
+
+// Session converts the shown branch of the conversation. Only text from user
+// and assistant messages is kept, system prompts, tools and images are not.
+func (c chatGPTConversation) Session() session {
+	var messages []openai.ChatCompletionMessage
+	for id := c.CurrentNode; id != ""; id = c.Mapping[id].Parent {
+		node := c.Mapping[id]
+		if node.Message == nil {
+			continue
+		}
+
+		role := node.Message.Author.Role
+		if role != openai.ChatMessageRoleUser && role != openai.ChatMessageRoleAssistant {
+			continue
+		}
+
+		var parts []string
+		for _, raw := range node.Message.Content.Parts {
+			var text string
+			if json.Unmarshal(raw, &text) == nil && text != "" {
+				parts = append(parts, text)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		messages = append(messages, openai.ChatCompletionMessage{Role: role, Content: strings.Join(parts, "\n")})
+	}
+
+	// The walk went from the newest message up
+	slices.Reverse(messages)
+
+	created := unixTime(c.CreateTime)
+	title := c.Title
+	if title == "" {
+		title = sessionTitle(messages)
+	}
+
+	return session{
+		ID:       created.Format(sessionIDLayout),
+		Title:    title,
+		Model:    modelName,
+		Created:  created,
+		Updated:  unixTime(c.UpdateTime),
+		Messages: messages,
+	}
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.UnixMilli(int64(seconds * 1000))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// /inject adds a message with any role to the conversation without a request,
+// to set up few-shot examples or try how a model continues a given context.
+
+var injectRoles = []string{
+	openai.ChatMessageRoleSystem,
+	openai.ChatMessageRoleUser,
+	openai.ChatMessageRoleAssistant,
+}
+
+func init() {
+	registerCommand(command{
+		name:        "inject",
+		usage:       "/inject <system|user|assistant> <text>",
+		description: "Add a message to the conversation without sending it",
+		complete: func(prefix string) []string {
+			var roles []string
+			for _, role := range injectRoles {
+				if strings.HasPrefix(role, prefix) {
+					roles = append(roles, role)
+				}
+			}
+			return roles
+		},
+		run: func(m *model, args string) tea.Cmd {
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the reply before injecting messages")
+				return nil
+			}
+
+			role, content := args, ""
+			if i := strings.IndexFunc(args, unicode.IsSpace); i >= 0 {
+				role, content = args[:i], strings.TrimSpace(args[i:])
+			}
+			if err := InjectMessage(m, role, content); err != nil {
+				AddNotice(m, err.Error())
+			}
+			return nil
+		},
+	})
+}
+
+// InjectMessage appends a message with role to chatMessages and the
+// transcript, and saves the session.
+func InjectMessage(m *model, role, content string) error {
+	switch {
+	case !slices.Contains(injectRoles, role):
+		return fmt.Errorf("usage: /inject <%s> <text>", strings.Join(injectRoles, "|"))
+	case content == "":
+		return fmt.Errorf("nothing to inject as %s", role)
+	}
+
+	chatMessages = append(chatMessages, openai.ChatCompletionMessage{Role: role, Content: content})
+	index := len(chatMessages) - 1
+	currentSession.MarkInjected(index)
+
+	switch role {
+	case openai.ChatMessageRoleSystem:
+		AddMessage(m, m.SystemMessage(content))
+	case openai.ChatMessageRoleUser:
+		AddMessage(m, m.PromptMessage(currentSession, content, index))
+	case openai.ChatMessageRoleAssistant:
+		AddMessage(m, m.ReplyMessage(currentSession, content, index))
+	}
+	m.viewport.GotoBottom()
+
+	SaveCurrentSession()
+	m.sidebar.Refresh()
+	return nil
+}
+
+// SystemMessage shows an injected system message like a notice, as it is
+// context rather than part of the exchange.
+func (m model) SystemMessage(content string) Message {
+	return m.NoticeMessage("System: " + content)
+}
+
+// MarkInjected notes that the message at index i was added with /inject.
+func (s *session) MarkInjected(i int) {
+	if s.InjectedMessages == nil {
+		s.InjectedMessages = map[int]bool{}
+	}
+	s.InjectedMessages[i] = true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	inputModeDefault = "default"
+	inputModeEmacs   = "emacs"
+)
+
+// inputEditor layers an editing mode over the textarea. In emacs mode text
+// removed by the kill commands is kept and can be yanked back with ctrl+y.
+type inputEditor struct {
+	mode       string
+	killBuffer string
+	yank       key.Binding
+}
+
+func NewInputEditor(mode string) inputEditor {
+	switch mode {
+	case "", inputModeDefault:
+		mode = inputModeDefault
+	case inputModeEmacs:
+	default:
+		log.Printf("Unknown input mode %q, using %s", mode, inputModeDefault)
+		mode = inputModeDefault
+	}
+
+	return inputEditor{
+		mode: mode,
+		yank: key.NewBinding(key.WithKeys("ctrl+y")),
+	}
+}
+
+// TextareaKeyMap returns the textarea bindings for the mode. Both keep the
+// textarea's own bindings, readline keys included; the default mode adds
+// ctrl+arrows for moving by word, emacs keeps what the kill keys remove for
+// ctrl+y.
+func (e inputEditor) TextareaKeyMap() textarea.KeyMap {
+	km := textarea.DefaultKeyMap
+	if e.mode == inputModeEmacs {
+		return km
+	}
+
+	km.WordForward.SetKeys(append(km.WordForward.Keys(), "ctrl+right")...)
+	km.WordBackward.SetKeys(append(km.WordBackward.Keys(), "ctrl+left")...)
+	return km
+}
+
+// Track is called after the textarea handled msg, with the value it had
+// before, to fill the kill buffer or yank from it.
+func (e *inputEditor) Track(msg tea.Msg, before string, ta *textarea.Model) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || e.mode != inputModeEmacs {
+		return
+	}
+
+	km := ta.KeyMap
+	switch {
+	case key.Matches(keyMsg, km.DeleteWordBackward, km.DeleteWordForward, km.DeleteAfterCursor, km.DeleteBeforeCursor):
+		if killed := removedText(before, ta.Value()); killed != "" {
+			e.killBuffer = killed
+		}
+	case key.Matches(keyMsg, e.yank):
+		ta.InsertString(e.killBuffer)
+	}
+}
+
+// removedText returns the contiguous run of characters deleted from before to
+// produce after.
+func removedText(before, after string) string {
+	b, a := []rune(before), []rune(after)
+	if len(a) >= len(b) {
+		return ""
+	}
+
+	prefix := 0
+	for prefix < len(a) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	return string(b[prefix : len(b)-suffix])
+}
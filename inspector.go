@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	inspectorTitleColor   = "#cda9d6"
+	requestInspectorTitle = "Last request"
+)
+
+// inspectorModel collects details about requests, shown in place of the
+// viewport while toggled on. Features add their own sections.
+type inspectorModel struct {
+	visible  bool
+	sections []inspectorSection
+}
+
+type inspectorSection struct {
+	title string
+	lines []string
+}
+
+// Set replaces the section with the given title, adding it if new.
+func (i *inspectorModel) Set(title string, lines ...string) {
+	for s := range i.sections {
+		if i.sections[s].title == title {
+			i.sections[s].lines = lines
+			return
+		}
+	}
+	i.sections = append(i.sections, inspectorSection{title, lines})
+}
+
+func (i inspectorModel) View() string {
+	titleStyle := lipgloss.NewStyle().Foreground(Color(inspectorTitleColor)).Bold(true)
+
+	var b strings.Builder
+	if len(i.sections) == 0 {
+		b.WriteString("Nothing to inspect yet")
+	}
+	for s, section := range i.sections {
+		if s > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(titleStyle.Render(section.title))
+		for _, line := range section.lines {
+			b.WriteString("\n" + line)
+		}
+	}
+
+	width, height, maxHeight := OverlaySize()
+	return lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(focusColor)).
+		Padding(0, 1).
+		Width(width).
+		Height(height).
+		MaxHeight(maxHeight).
+		Render(b.String())
+}
+
+func RequestInspectorLines(msg responseMsg) []string {
+	lines := []string{
+		"model: " + modelName,
+		fmt.Sprintf("tokens: %d prompt, %d completion", msg.usage.PromptTokens, msg.usage.CompletionTokens),
+	}
+	if msg.metrics.Duration > 0 {
+		lines = append(lines, "timing: "+msg.metrics.String())
+	}
+	lines = append(lines, GenerationInspectorLines(msg.generation)...)
+	if len(apiKeys.keys) > 1 {
+		lines = append(lines, KeyInspectorLine())
+	}
+	if msg.err != nil {
+		lines = append(lines, "error: "+msg.err.Error())
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Jump mode numbers every exchange in the transcript. Typing a number and
+// enter scrolls to it, [ and ] step between prompts.
+type jumpKeyMap struct {
+	Prev key.Binding
+	Next key.Binding
+	Go   key.Binding
+	Exit key.Binding
+}
+
+var jumpKeys = jumpKeyMap{
+	Prev: key.NewBinding(key.WithKeys("[")),
+	Next: key.NewBinding(key.WithKeys("]")),
+	Go:   key.NewBinding(key.WithKeys("enter")),
+	Exit: key.NewBinding(key.WithKeys("esc")),
+}
+
+// prompts returns the indexes of the prompts in m.messages.
+func (m model) prompts() []int {
+	var indexes []int
+	for i, message := range m.messages {
+		if message.Kind == kindPrompt {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (m *model) StartJump() {
+	if len(m.prompts()) == 0 {
+		AddNotice(m, "Nothing to jump to yet")
+		return
+	}
+
+	m.jumping = true
+	m.jumpInput = ""
+	UpdateViewport(m)
+}
+
+func (m *model) StopJump() {
+	m.jumping = false
+	UpdateViewport(m)
+}
+
+func (m *model) scrollToTop(i int) {
+	m.viewport.SetYOffset(m.contentTop + m.messageOffsets[i])
+}
+
+// HandleJumpKey runs jump mode bindings. The jump binding leaves jump mode,
+// like esc.
+func HandleJumpKey(m *model, msg tea.KeyMsg) (tea.Cmd, bool) {
+	prompts := m.prompts()
+
+	switch {
+	case key.Matches(msg, jumpKeys.Exit, m.keys.Jump):
+		m.StopJump()
+	case key.Matches(msg, jumpKeys.Next):
+		for _, i := range prompts {
+			if m.contentTop+m.messageOffsets[i] > m.viewport.YOffset {
+				m.scrollToTop(i)
+				break
+			}
+		}
+	case key.Matches(msg, jumpKeys.Prev):
+		for j := len(prompts) - 1; j >= 0; j-- {
+			if m.contentTop+m.messageOffsets[prompts[j]] < m.viewport.YOffset {
+				m.scrollToTop(prompts[j])
+				break
+			}
+		}
+	case key.Matches(msg, jumpKeys.Go):
+		n, err := strconv.Atoi(m.jumpInput)
+		m.StopJump()
+		if err != nil || n < 1 || n > len(prompts) {
+			return nil, true
+		}
+		m.scrollToTop(prompts[n-1])
+	case msg.Type == tea.KeyBackspace:
+		if m.jumpInput != "" {
+			m.jumpInput = m.jumpInput[:len(m.jumpInput)-1]
+		}
+	case msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && msg.Runes[0] >= '0' && msg.Runes[0] <= '9':
+		m.jumpInput += string(msg.Runes)
+	default:
+		return nil, false
+	}
+
+	return nil, true
+}
+
+func JumpAnchor(m *model, n int) string {
+	return m.noticeStyle.Render(fmt.Sprintf("#%d", n))
+}
+
+func (m model) JumpPromptView() string {
+	return m.noticeStyle.Render(fmt.Sprintf("Jump to: %s▏  enter go · [ ] previous/next prompt · esc done", m.jumpInput))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+)
+
+// `bubblechat kb ./docs` opens the chat over a documentation folder. The
+// folder is indexed like with /add-dir, every question is answered from the
+// excerpts retrieved for it, and each answer is followed by the files it was
+// drawn from.
+
+const kbPromptHead = "Answer from these excerpts of the documentation only. " +
+	"When they do not cover the question, say so instead of guessing."
+
+// Documentation folder of `bubblechat kb`, empty otherwise
+var kbRoot string
+
+// StartKnowledgeBase holds questions back until the folder is indexed, which
+// starts with the program.
+func (m *model) StartKnowledgeBase() {
+	m.kbIndexing = true
+	AddNotice(m, "Knowledge base: indexing "+kbRoot+"…")
+}
+
+// KnowledgeBaseNotice lists the files of the excerpts an answer was given
+// from.
+func KnowledgeBaseNotice(retrieved []chunk) string {
+	var files []string
+	seen := map[string]bool{}
+	for _, c := range retrieved {
+		if !seen[c.source] {
+			seen[c.source] = true
+			files = append(files, c.source)
+		}
+	}
+
+	if len(files) == 0 {
+		return "No part of " + kbRoot + " matched the question"
+	}
+	return "From " + strings.Join(files, ", ")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap holds every application level binding. Bindings can be overridden
+// per action from the "keys" section of the config, e.g.
+//
+//	"keys": {"quit": ["ctrl+q"], "copy": ["ctrl+y"]}
+type keyMap struct {
+	Send       key.Binding
+	Quit       key.Binding
+	ScrollUp   key.Binding
+	ScrollDown key.Binding
+	PageUp     key.Binding
+	PageDown   key.Binding
+	Cancel     key.Binding
+	Copy       key.Binding
+	Focus      key.Binding
+	Sidebar    key.Binding
+	NewTab     key.Binding
+	NextTab    key.Binding
+	PrevTab    key.Binding
+	Inspector  key.Binding
+	RawText    key.Binding
+	Select     key.Binding
+	Jump       key.Binding
+	Undo       key.Binding
+	Palette    key.Binding
+	Help       key.Binding
+}
+
+func DefaultKeyMap() keyMap {
+	return keyMap{
+		Send: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "send"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c", "esc"),
+			key.WithHelp("esc", "quit"),
+		),
+		ScrollUp: key.NewBinding(
+			key.WithKeys("up"),
+			key.WithHelp("↑", "up"),
+		),
+		ScrollDown: key.NewBinding(
+			key.WithKeys("down"),
+			key.WithHelp("↓", "down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdn", "page down"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "cancel"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "copy reply"),
+		),
+		Focus: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch pane"),
+		),
+		Sidebar: key.NewBinding(
+			key.WithKeys("ctrl+b"),
+			key.WithHelp("ctrl+b", "sidebar"),
+		),
+		NewTab: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "new tab"),
+		),
+		// Most terminals cannot send ctrl+tab, so tabs switch like pages
+		NextTab: key.NewBinding(
+			key.WithKeys("ctrl+pgdown"),
+			key.WithHelp("ctrl+pgdn", "next tab"),
+		),
+		PrevTab: key.NewBinding(
+			key.WithKeys("ctrl+pgup"),
+			key.WithHelp("ctrl+pgup", "previous tab"),
+		),
+		Inspector: key.NewBinding(
+			key.WithKeys("f2"),
+			key.WithHelp("f2", "inspector"),
+		),
+		RawText: key.NewBinding(
+			key.WithKeys("f3"),
+			key.WithHelp("f3", "raw text"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "select message"),
+		),
+		Jump: key.NewBinding(
+			key.WithKeys("ctrl+g"),
+			key.WithHelp("ctrl+g", "jump to message"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("ctrl+z"),
+			key.WithHelp("ctrl+z", "undo send"),
+		),
+		Palette: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "actions"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("f1"),
+			key.WithHelp("f1", "help"),
+		),
+	}
+}
+
+// bindings maps config action names to the bindings they configure.
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"send":        &k.Send,
+		"quit":        &k.Quit,
+		"scroll_up":   &k.ScrollUp,
+		"scroll_down": &k.ScrollDown,
+		"page_up":     &k.PageUp,
+		"page_down":   &k.PageDown,
+		"cancel":      &k.Cancel,
+		"copy":        &k.Copy,
+		"focus":       &k.Focus,
+		"sidebar":     &k.Sidebar,
+		"new_tab":     &k.NewTab,
+		"next_tab":    &k.NextTab,
+		"prev_tab":    &k.PrevTab,
+		"inspector":   &k.Inspector,
+		"raw_text":    &k.RawText,
+		"select":      &k.Select,
+		"jump":        &k.Jump,
+		"undo":        &k.Undo,
+		"palette":     &k.Palette,
+		"help":        &k.Help,
+	}
+}
+
+func NewKeyMap(overrides map[string][]string) keyMap {
+	keys := DefaultKeyMap()
+	bindings := keys.bindings()
+
+	for action, keyNames := range overrides {
+		binding, ok := bindings[action]
+		if !ok {
+			log.Printf("Unknown key action in config: %q", action)
+			continue
+		}
+		if len(keyNames) == 0 {
+			binding.SetEnabled(false)
+			continue
+		}
+
+		binding.SetKeys(keyNames...)
+		binding.SetHelp(strings.Join(keyNames, "/"), binding.Help().Desc)
+	}
+
+	return keys
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Send, k.Focus, k.Cancel, k.Copy, k.Palette, k.Quit, k.Help}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Send, k.Undo, k.Cancel, k.Copy, k.Select},
+		{k.ScrollUp, k.ScrollDown, k.PageUp, k.PageDown, k.Jump},
+		{k.NewTab, k.NextTab, k.PrevTab},
+		{k.Focus, k.Sidebar, k.Inspector, k.RawText, k.Palette, k.Help, k.Quit},
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// keyRing holds the API keys requests rotate through. When a key is rate
+// limited or rejected, the next one takes over for every later request.
+type keyRing struct {
+	mu     sync.Mutex
+	keys   []string
+	active int
+}
+
+var apiKeys keyRing
+
+// LoadAPIKeys reads the api_keys list, expanding $ENV references so the keys
+// themselves can stay in .env.
+func LoadAPIKeys() {
+	apiKeys.keys = nil
+	for _, key := range cfg.APIKeys {
+		if key = os.ExpandEnv(key); key != "" {
+			apiKeys.keys = append(apiKeys.keys, key)
+		}
+	}
+}
+
+func (r *keyRing) Active() (int, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active, r.keys[r.active]
+}
+
+// Rotate moves on from key i. Requests failing at the same time rotate once,
+// not once each.
+func (r *keyRing) Rotate(i int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == i {
+		r.active = (i + 1) % len(r.keys)
+	}
+}
+
+// KeyInspectorLine shows which of several keys is in use.
+func KeyInspectorLine() string {
+	i, key := apiKeys.Active()
+	return fmt.Sprintf("api key: %d of %d (…%s)", i+1, len(apiKeys.keys), key[max(len(key)-4, 0):])
+}
+
+// keyTransport sends each request with the active key, retrying with the
+// next keys on 429 and 401 until every key was tried.
+type keyTransport struct {
+	transport http.RoundTripper
+}
+
+func (t keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		i, key := apiKeys.Active()
+
+		try := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try.Body = body
+		}
+		try.Header.Set("Authorization", "Bearer "+key)
+
+		resp, err := t.transport.RoundTrip(try)
+		if err != nil {
+			return nil, err
+		}
+
+		rejected := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusUnauthorized
+		if !rejected {
+			return resp, nil
+		}
+
+		apiKeys.Rotate(i)
+		if attempt == len(apiKeys.keys) || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The reply language is enforced with an instruction sent after the
+// conversation with every request, but not kept in it, so replies stay in
+// that language whatever the language of the prompt or attached files.
+
+const (
+	replyLanguageOff = "off"
+
+	replyLanguageInstruction = "Always reply in %s, whatever the language of the messages above. " +
+		"Keep code, identifiers, commands and quoted text as they are."
+)
+
+// Language names by ISO 639-1 code, for the instruction. Other values are
+// used as written, e.g. "Brazilian Portuguese".
+var languageNames = map[string]string{
+	"da": "Danish",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fi": "Finnish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"no": "Norwegian",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"sv": "Swedish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+func init() {
+	registerCommand(command{
+		name:        "lang",
+		usage:       "/lang [<language> | off]",
+		description: "Show or set the language replies are written in",
+		complete: func(prefix string) []string {
+			codes := []string{replyLanguageOff}
+			for code := range languageNames {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+
+			var matches []string
+			for _, code := range codes {
+				if strings.HasPrefix(code, prefix) {
+					matches = append(matches, code)
+				}
+			}
+			return matches
+		},
+		run: func(m *model, args string) tea.Cmd {
+			switch args {
+			case "":
+			case replyLanguageOff:
+				cfg.ReplyLanguage = ""
+			default:
+				cfg.ReplyLanguage = args
+			}
+
+			if cfg.ReplyLanguage == "" {
+				AddNotice(m, "Replies are in the language of the prompt")
+			} else {
+				AddNotice(m, "Replies are in "+languageName(cfg.ReplyLanguage))
+			}
+			return nil
+		},
+	})
+}
+
+// languageName spells out a language code.
+func languageName(language string) string {
+	if name, ok := languageNames[strings.ToLower(language)]; ok {
+		return name
+	}
+	return language
+}
+
+// WithReplyLanguage returns messages followed by the instruction to reply in
+// language, or messages as they are without a language.
+func WithReplyLanguage(messages []openai.ChatCompletionMessage, language string) []openai.ChatCompletionMessage {
+	if language == "" {
+		return messages
+	}
+
+	instruction := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf(replyLanguageInstruction, languageName(language)),
+	}
+	return append(messages[:len(messages):len(messages)], instruction)
+}
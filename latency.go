@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxLatencies = 10
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// RecordLatency keeps the duration of the most recent requests.
+func (h *headerModel) RecordLatency(d time.Duration) {
+	h.latencies = append(h.latencies, d)
+	if len(h.latencies) > maxLatencies {
+		h.latencies = h.latencies[len(h.latencies)-maxLatencies:]
+	}
+}
+
+// LatencyView shows recent request latencies as a sparkline scaled to the
+// slowest one, followed by their average.
+func (h headerModel) LatencyView() string {
+	if len(h.latencies) == 0 {
+		return ""
+	}
+
+	var total, slowest time.Duration
+	for _, d := range h.latencies {
+		total += d
+		slowest = max(slowest, d)
+	}
+	average := fmt.Sprintf("%.1fs", (total / time.Duration(len(h.latencies))).Seconds())
+
+	// The ASCII icon set, also used in accessibility mode, has no bars
+	bars := icons.Spark
+	if len(bars) == 0 {
+		return "avg " + average
+	}
+
+	var spark strings.Builder
+	for _, d := range h.latencies {
+		level := int(d * time.Duration(len(bars)-1) / max(slowest, 1))
+		spark.WriteRune(bars[level])
+	}
+	return spark.String() + " " + average
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FitWindow returns view, or a notice asking for a larger terminal when the
+// fixed layout does not fit and would wrap into a mess.
+func (m model) FitWindow(view string) string {
+	if m.windowWidth == 0 {
+		return view
+	}
+
+	width, height := lipgloss.Size(view)
+	if width <= m.windowWidth && height <= m.windowHeight {
+		return view
+	}
+
+	notice := fmt.Sprintf(
+		"Terminal too small\n\nneed %dx%d, have %dx%d",
+		width, height, m.windowWidth, m.windowHeight,
+	)
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, notice)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Before a message is sent, the files it mentions and the message itself are
+// checked against the context window. A single one taking more than
+// attachment_share of it crowds out the conversation, so sending waits for a
+// choice: summarize the files first, read them in chunks, send as is, or edit
+// the message.
+
+const (
+	defaultAttachmentShare = 0.25
+
+	summaryContextHead = "Summary of %s, mentioned by the user:\n\n%s"
+
+	summarizePrompt = "Summarize this file so it can stand in for it as context in a conversation. " +
+		"Keep key facts, names, numbers, decisions and code identifiers.\n\n" +
+		"File %s:\n\n```\n%s\n```"
+)
+
+// oversized is an attachment, or the message itself when path is empty,
+// taking more than the configured share of the context window.
+type oversized struct {
+	path   string
+	tokens int
+}
+
+// attachmentLint is a message waiting for a choice about its oversized
+// attachments.
+type attachmentLint struct {
+	message string
+	found   []oversized
+}
+
+type summariesMsg struct {
+	tab       int
+	message   string
+	summaries map[string]string
+	err       error
+}
+
+// LintAttachments lists the files mentioned in message, and the message
+// itself, that take too much of the context window of the model.
+func LintAttachments(message string) []oversized {
+	window := contextWindow(modelName)
+	if window == 0 || cfg.AttachmentShare <= 0 {
+		return nil
+	}
+	limit := int(cfg.AttachmentShare * float64(window))
+
+	var found []oversized
+	for _, match := range mention.FindAllStringSubmatch(message, -1) {
+		info, err := os.Stat(match[1])
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			continue
+		}
+		if tokens := SizeTokens(info.Size()); tokens > limit {
+			found = append(found, oversized{path: match[1], tokens: tokens})
+		}
+	}
+	if tokens := EstimateTokens(message); tokens > limit {
+		found = append(found, oversized{tokens: tokens})
+	}
+	return found
+}
+
+// LintWarning explains the oversized attachments and the choices.
+func LintWarning(found []oversized) string {
+	window := contextWindow(modelName)
+
+	lines := []string{"Large attachments", ""}
+	files := false
+	for _, o := range found {
+		name := "The message"
+		if o.path != "" {
+			name = o.path
+			files = true
+		}
+		lines = append(lines, fmt.Sprintf("%s is ~%s tokens, %.0f%% of the %s context window of %s",
+			name, formatCount(o.tokens), 100*float64(o.tokens)/float64(window), shortCount(window), modelName))
+	}
+
+	lines = append(lines, "")
+	if files {
+		lines = append(lines, "Press s to summarize the files first, c to read them in chunks for the question, "+
+			"y to send as is, any other key to edit.")
+	} else {
+		lines = append(lines, "Press y to send as is, any other key to edit.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HandleLintKey acts on the choice for a message with oversized attachments.
+func HandleLintKey(m *model, msg tea.KeyMsg) tea.Cmd {
+	lint := m.lint
+	m.lint = nil
+	m.modal = ""
+
+	var paths []string
+	for _, o := range lint.found {
+		if o.path != "" {
+			paths = append(paths, o.path)
+		}
+	}
+
+	switch {
+	case msg.String() == "y":
+		return SendChecked(m, lint.message, nil)
+	case msg.String() == "s" && len(paths) > 0:
+		return SummarizeAttachments(m, lint.message, paths)
+	case msg.String() == "c" && len(paths) > 0:
+		return ReadInChunks(m, lint.message, paths)
+	}
+	m.textarea.SetValue(lint.message)
+	return nil
+}
+
+// SummarizeAttachments shows a placeholder while the files are summarized,
+// after which message is sent with the summaries attached instead.
+func SummarizeAttachments(m *model, message string, paths []string) tea.Cmd {
+	AddNotice(m, "Summarizing "+strings.Join(paths, ", "))
+	m.messages = append(m.messages, m.PlaceholderMessage())
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+
+	m.SetRequest(requestSending)
+	requestCtx, cancel := context.WithCancel(ctx)
+	m.cancelRequest = cancel
+
+	return tea.Batch(m.StartSpinner(), SummarizeCmd(requestCtx, m.ActiveTabID(), message, paths))
+}
+
+func SummarizeCmd(ctx context.Context, tab int, message string, paths []string) tea.Cmd {
+	model := modelName
+
+	return func() tea.Msg {
+		msg := summariesMsg{tab: tab, message: message, summaries: map[string]string{}}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+
+			req := openai.ChatCompletionRequest{
+				Model:    model,
+				Messages: []openai.ChatCompletionMessage{UserMessage(fmt.Sprintf(summarizePrompt, path, content))},
+			}
+			resp, err := callChat(ctx, req)
+			if err != nil {
+				msg.err = fmt.Errorf("summarizing %s: %w", path, err)
+				return msg
+			}
+			if len(resp.Choices) == 0 {
+				msg.err = fmt.Errorf("summarizing %s: %w", path, errNoChoices)
+				return msg
+			}
+			msg.summaries[path] = resp.Choices[0].Message.Content
+		}
+		return msg
+	}
+}
+
+// HandleSummaries sends the message that waited for its summaries, or puts it
+// back into the input when summarizing failed.
+func HandleSummaries(m *model, msg summariesMsg) tea.Cmd {
+	if errors.Is(msg.err, context.Canceled) {
+		return nil
+	}
+
+	// The message is only sent from the tab it was written in
+	if msg.tab != m.ActiveTabID() {
+		notice := "Summarized, but not sent after switching tabs: " + msg.message
+		if msg.err != nil {
+			notice = "Could not summarize: " + msg.err.Error()
+		}
+		m.ShowResult(msg.tab, m.NoticeMessage(notice), nil)
+		return nil
+	}
+
+	m.SetRequest(resultState(msg.err))
+	m.cancelRequest = nil
+	m.messages = m.messages[:len(m.messages)-1]
+	if msg.err != nil {
+		AddNotice(m, "Could not summarize: "+msg.err.Error())
+		m.textarea.SetValue(msg.message)
+		return nil
+	}
+	return SendChecked(m, msg.message, msg.summaries)
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -15,34 +22,193 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fsnotify/fsnotify"
 	"github.com/joho/godotenv"
 	"github.com/muesli/reflow/wordwrap"
 	openai "github.com/sashabaranov/go-openai"
 )
 
 func main() {
+	flag.StringVar(&recordPath, "record", "", "record API responses to this file")
+	flag.StringVar(&replayPath, "replay", "", "serve API responses from this file instead of the network")
+	flag.BoolVar(&noColor, "no-color", false, "disable colors, same as setting NO_COLOR")
+	flag.BoolVar(&continueLast, "continue", false, "reopen the most recently used session")
+	flag.StringVar(&hostAddr, "host", "", "share the session on this socket path or host:port")
+	flag.StringVar(&joinAddr, "join", "", "join a session shared on this socket path or host:port")
+	flag.StringVar(&shareToken, "token", "", "token of the shared session, random for --host by default ($"+shareTokenEnv+")")
+	flag.StringVar(&prefillMessage, "m", "", "start with this prompt in the input")
+	flag.Func("f", "mention this file in the prompt, attaching it when sent (repeatable)", AddPrefillFile)
+	flag.BoolVar(&prefillSend, "send", false, "send the -m and -f prompt right away")
+	flag.StringVar(&examplesName, "examples", "", "start the session with this few-shot example set from the config")
+	flag.BoolVar(&quietMode, "quiet", false, "no spinners or animations, same as the quiet config")
+	flag.BoolVar(&demoMode, "demo", false, "run with canned replies, a fixed seed and the default config, for reproducible recordings")
+	flag.StringVar(&pprofAddr, "pprof", "", "serve net/http/pprof profiles on this address while the UI runs, e.g. :6060")
+	flag.Parse()
+
+	if demoMode {
+		if err := StartDemo(); err != nil {
+			log.Fatalf("Error starting demo: %v", err)
+		}
+		defer os.RemoveAll(demoConfigDir)
+	}
+
+	cfg = LoadConfig()
+	if demoMode {
+		ApplyDemoConfig()
+	}
+	if quietMode {
+		cfg.Quiet = true
+	}
+	if err := LoadSessionKey(); err != nil {
+		log.Fatalf("Error loading session key: %v", err)
+	}
+	if err := LoadProvider(); err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if err := OpenVectorStore(); err != nil {
+		log.Fatalf("Error opening vector store: %v", err)
+	}
+
+	// Subcommands run without the UI
+	switch flag.Arg(0) {
+	case "import":
+		if err := RunImport(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "popup":
+		if err := RunPopup(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "batch":
+		if err := RunBatch(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "run":
+		if err := RunTemplate(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "experiment":
+		if err := RunExperiment(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "eval":
+		if err := RunEval(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "bench":
+		// Hidden, for performance work
+		if err := RunBench(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "embed":
+		if err := RunEmbed(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "kb":
+		// Opens the UI, over the documentation folder
+		if flag.NArg() != 2 {
+			log.Fatal("usage: bubblechat kb <docs folder>")
+		}
+		kbRoot = flag.Arg(1)
+	}
+
+	LoadAliases()
+	ApplyIcons()
+	ApplyAccessibility()
+	ApplyColorProfile()
+
 	model := initialModel()
-	program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	if continueLast {
+		if s, ok := LastSession(); ok {
+			OpenSession(&model, s)
+		}
+	}
+	if examplesName != "" {
+		if err := model.AttachExamples(examplesName); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if snap, ok := TakeSnapshot(); ok {
+		model.modal = RestorePrompt(snap)
+		model.confirmAction = RestoreCmd(snap)
+	}
+	if kbRoot != "" {
+		model.StartKnowledgeBase()
+	}
+	model.Prefill()
+	if err := LoadShareToken(); err != nil {
+		log.Fatalf("Error creating share token: %v", err)
+	}
+	if hostAddr != "" {
+		AddNotice(&model, ShareInstructions())
+	}
+	program := tea.NewProgram(recoveryModel{model}, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithOutput(terminalOutput))
+	notify = program.Send
 
 	initializeClient()
 
-	model.resetSpinner()
+	switch {
+	case hostAddr != "":
+		if err := StartHosting(program, hostAddr); err != nil {
+			log.Fatalf("Error hosting shared session: %v", err)
+		}
+	case joinAddr != "":
+		if err := JoinSession(program, joinAddr); err != nil {
+			log.Fatalf("Error joining shared session: %v", err)
+		}
+	}
+
+	if pprofAddr != "" {
+		if err := StartPprof(pprofAddr); err != nil {
+			log.Fatalf("Error starting pprof: %v", err)
+		}
+	}
+
+	WatchHangup()
 
-	if _, err := program.Run(); err != nil {
+	// Anything but quitting from the keyboard keeps a snapshot, a panic
+	// returns no model at all
+	final, err := program.Run()
+	if r, ok := final.(recoveryModel); ok && r.quitting {
+		os.Remove(recoveryPath())
+	} else {
+		WriteSnapshot()
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
 
 func getApiKey() string {
+	// godotenv never overrides the environment
+	credentialSource = sourceDotEnv
+	if os.Getenv(apiKeyEnv) != "" {
+		credentialSource = sourceEnv
+	}
+
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
+	if LoadAPIKeys(); len(apiKeys.keys) > 0 {
+		credentialSource, credentialKey = sourceAPIKeys, apiKeys.keys[0]
+		return apiKeys.keys[0]
+	}
+
+	apiKey := os.Getenv(apiKeyEnv)
 	if apiKey == "" {
-		log.Fatal("OPENAI_API_KEY is not set")
+		log.Fatal(apiKeyEnv + " is not set")
 	}
+	credentialKey = apiKey
 	return apiKey
 }
 
@@ -66,47 +232,153 @@ const (
 	textareaWidth  = 80
 	textareaHeight = 1
 
-	modelName = openai.GPT3Dot5Turbo
+	defaultModel = openai.GPT3Dot5Turbo
 )
 
 var (
-	spinnerType       = spinner.MiniDot
-	statusSpinnerType = spinner.Line
+	// Model for new requests, see /model
+	modelName = defaultModel
 
 	client       *openai.Client
 	ctx          context.Context
 	chatMessages []openai.ChatCompletionMessage
 	baseURL      string
+
+	recordPath string
+	replayPath string
 )
 
 func initializeClient() {
-	config := openai.DefaultConfig(getApiKey())
+	// Replays and joined sessions never reach the API, so no key is needed,
+	// local servers take any and with OAuth the token replaces it
+	apiKey := "replay"
+	switch {
+	case replayPath != "" || joinAddr != "":
+	case cfg.OAuth.ClientID != "":
+		credentialSource = sourceOAuth
+	case provider.noAuth:
+		credentialSource = sourceNoAuth
+	default:
+		apiKey = getApiKey()
+	}
+	config := openai.DefaultConfig(apiKey)
 
-	// Change base URL for custom OpenAI-like endpoint
-	// config.BaseURL = "https://my.api.com/v1"
+	config.BaseURL = provider.baseURL
 	baseURL = config.BaseURL
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if cfg.Provider == providerFake {
+		transport = fakeTransport{}
+	}
+	if len(apiKeys.keys) > 1 {
+		transport = keyTransport{transport}
+	}
+	if len(cfg.Headers) > 0 {
+		transport = NewHeaderTransport(cfg.Headers, transport)
+	}
+	if cfg.OAuth.ClientID != "" {
+		transport = tokenTransport{transport}
+	}
+	if transport != http.DefaultTransport {
+		config.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	if recordPath != "" || replayPath != "" {
+		path := recordPath
+		if replayPath != "" {
+			path = replayPath
+		}
+
+		c, err := NewCassette(path, replayPath != "")
+		if err != nil {
+			log.Fatalf("Error opening cassette: %v", err)
+		}
+		c.transport = transport
+		config.HTTPClient = &http.Client{Transport: c}
+	}
 	client = openai.NewClientWithConfig(config)
+	callChat = Chain(client.CreateChatCompletion, Middlewares()...)
 	ctx = context.Background()
 }
 
 type model struct {
 	header            headerModel
 	viewport          viewport.Model
-	messages          []string
+	messages          []Message
 	textarea          textarea.Model
+	input             inputEditor
+	focus             focus
+	sidebar           sidebarModel
+	statusBar         statusBarModel
+	modal             string
+	confirmSend       *moderationMsg
+	lint              *attachmentLint
+	chunked           *chunkedRead
+	citation          *citationOverlay
+	kbIndexing        bool
+	confirmAction     tea.Cmd
+	quitting          bool
+	inspector         inspectorModel
+	palette           paletteModel
+	completion        completion
+	pull              *ollamaPull
+	configWatcher     *fsnotify.Watcher
+	drag              mouseDrag
+	raw               bool
+	rawView           viewport.Model
+	viewportLines     []string
+	keys              keyMap
+	help              help.Model
+	cancelRequest     context.CancelFunc
+	queue             []string
+	tabs              []conversation
+	activeTab         int
+	nextTabID         int
+	spinnerActive     bool
 	promptStyle       lipgloss.Style
 	promptTextStyle   lipgloss.Style
 	responseStyle     lipgloss.Style
 	responseTextStyle lipgloss.Style
+	noticeStyle       lipgloss.Style
 	spinner           spinner.Model
-	waiting           bool
+	request           requestState
 	renderer          *glamour.TermRenderer
+	renderCache       renderCache
+	messageOffsets    []int
+	contentTop        int
+	images            []imageMsg
+	offline           bool
+	undoUntil         time.Time
+	savedDraft        string
+	selecting         bool
+	selected          int
+	jumping           bool
+	jumpInput         string
+	replay            *sessionReplay
+	typing            *typewriter
+	translateTo       string
+	proofread         string
+	renderWidth       int
+	windowWidth       int
+	windowHeight      int
 	err               error
 }
 
 type responseMsg struct {
-	message string
-	err     error
+	tab       int
+	model     string
+	replies   []openai.ChatCompletionMessage
+	message   string
+	sources   []fetchedPage
+	retrieved []chunk
+	cached    bool
+	usage     openai.Usage
+	// Usage per model for requests to several models, instead of usage
+	usageByModel map[string]openai.Usage
+	generation   generation
+	latency      time.Duration
+	metrics      replyMetrics
+	err          error
 }
 
 type statusMsg struct {
@@ -119,85 +391,121 @@ type headerModel struct {
 	style          lipgloss.Style
 	requestDone    bool
 	requestSuccess bool
+	request        requestState
+	offline        bool
+	warming        bool
+	latencies      []time.Duration
 }
 
 func (h headerModel) View() string {
 	var rightIcon string
-	var padAmount int
 	if h.requestDone {
-		padAmount = 2
 		if h.requestSuccess {
-			rightIcon = "✔"
+			rightIcon = icons.Success
 		} else {
-			rightIcon = "✘"
+			rightIcon = icons.Error
 		}
+	} else if !AnimateStatusSpinner() {
+		rightIcon = "..."
 	} else {
-		padAmount = 4
 		rightIcon = h.statusSpinner.View()
 	}
 
-	middlePadding := strings.Repeat(" ", viewportWidth-len(h.modelName)-len(rightIcon)-padAmount)
-	content := modelName + middlePadding + rightIcon
+	if latency := h.LatencyView(); latency != "" {
+		rightIcon = latency + " " + rightIcon
+	}
+	if label := h.request.HeaderLabel(); label != "" {
+		rightIcon = label + " " + rightIcon
+	}
+	if h.warming {
+		rightIcon = "warming up… " + rightIcon
+	}
+	if h.offline {
+		rightIcon = "offline, will retry " + rightIcon
+	}
+
+	width := h.style.GetWidth() - h.style.GetHorizontalPadding()
+	middlePadding := strings.Repeat(" ", max(width-len(h.modelName)-lipgloss.Width(rightIcon), 1))
+	content := h.modelName + middlePadding + rightIcon
 	return h.style.Render(content)
 }
 
 func initialModel() model {
+	keys := NewKeyMap(cfg.Keys)
+	input := NewInputEditor(cfg.InputMode)
+
+	m := model{
+		header:      NewHeader(),
+		viewport:    NewViewport(keys),
+		messages:    []Message{},
+		textarea:    NewTextarea(input),
+		input:       input,
+		statusBar:   NewStatusBar(),
+		sidebar:     NewSidebar(),
+		tabs:        []conversation{{id: 0}},
+		nextTabID:   1,
+		keys:        keys,
+		help:        NewHelp(),
+		noticeStyle: StyleFromColor(noticeColor),
+		err:         nil,
+	}
+	m.ApplyThemeStyles()
+	m.configWatcher = WatchConfig()
+
 	// Renderer
-	renderer, _ := glamour.NewTermRenderer(
-		glamour.WithWordWrap(0),
-	)
+	m.SetRenderWidth(viewportContentWidth(m.viewport))
 
-	return model{
-		header:            NewHeader(),
-		viewport:          NewViewport(),
-		messages:          []string{},
-		textarea:          NewTextarea(),
-		promptStyle:       StyleFromColor(promptColor),
-		promptTextStyle:   StyleFromColor(promptTextColor),
-		responseStyle:     StyleFromColor(responseColor),
-		responseTextStyle: StyleFromColor(responseTextColor),
-		spinner:           spinner.New(spinner.WithSpinner(spinnerType)),
-		waiting:           false,
-		renderer:          renderer,
-		err:               nil,
-	}
+	m.LoadDraft()
 
+	return m
 }
 
 func StyleFromColor(color string) lipgloss.Style {
-	return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	return lipgloss.NewStyle().Foreground(Color(color))
 }
 
 func NewHeader() headerModel {
 	headerModel := headerModel{
 		modelName:     modelName,
-		statusSpinner: spinner.New(spinner.WithSpinner(statusSpinnerType)),
+		statusSpinner: NewStatusSpinner(),
 		requestDone:   false,
+		warming:       WarmUpEnabled(),
+		style:         HeaderStyle(),
 	}
 
-	border := lipgloss.RoundedBorder()
+	return headerModel
+}
+
+// HeaderStyle draws the header as the top of the viewport's box, open at the
+// bottom.
+func HeaderStyle() lipgloss.Style {
+	border := Border()
 	border.Bottom = ""
 	border.BottomLeft = ""
 	border.BottomRight = ""
 
-	headerStyle := lipgloss.
+	style := lipgloss.
 		NewStyle().
-		Width(viewportTextWidth).
 		Height(1).
 		Padding(0, 1).
 		Border(border, true, true, false, true).
-		Foreground(lipgloss.Color("#636363"))
-
-	headerModel.style = headerStyle
+		Foreground(Color("#636363"))
 
-	return headerModel
+	return style.Width(bodyWidth() - style.GetHorizontalBorderSize())
 }
 
-func NewTextarea() textarea.Model {
+func NewTextarea(input inputEditor) textarea.Model {
 	ta := textarea.New()
+	ta.KeyMap = input.TextareaKeyMap()
 	ta.Focus()
 
 	ta.Prompt = "┃ "
+	if cfg.Accessible {
+		ta.Prompt = accessibleTextareaPrompt
+	}
+	if !Animated() {
+		ta.Cursor.SetMode(cursor.CursorStatic)
+	}
 	ta.CharLimit = 280
 
 	ta.SetWidth(textareaWidth)
@@ -210,105 +518,245 @@ func NewTextarea() textarea.Model {
 
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
-	// Add border
-	borderStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder())
-
-	ta.FocusedStyle.Base = borderStyle
-	ta.BlurredStyle.Base = borderStyle
+	ta.FocusedStyle.Base, ta.BlurredStyle.Base = TextareaStyles()
 
 	return ta
 }
 
-func NewViewport() viewport.Model {
-	vp := viewport.New(viewportWidth, viewportHeight+2)
-	vpBorder := lipgloss.RoundedBorder()
-	vpBorder.TopLeft = "├"
-	vpBorder.TopRight = "┤"
+// TextareaStyles are the input's focused and blurred styles: a border,
+// highlighted while focused.
+func TextareaStyles() (focused, blurred lipgloss.Style) {
+	borderStyle := lipgloss.NewStyle().Border(Border())
+	return borderStyle.Copy().BorderForeground(Color(focusColor)), borderStyle
+}
 
-	vp.Style = lipgloss.NewStyle().Border(vpBorder).PaddingLeft(1)
-	vp.Style.Background(lipgloss.Color(backgroundColor))
+func NewHelp() help.Model {
+	h := help.New()
+	h.Width = viewportWidth
+	return h
+}
 
+func NewViewport(keys keyMap) viewport.Model {
+	vp := viewport.New(viewportWidth, viewportHeight+2)
+	vp.Style = ViewportStyle()
 	vp.MouseWheelEnabled = true
 
-	// just use scrolling or arrows for scrolling
-	vp.KeyMap = viewport.KeyMap{
-		Up: key.NewBinding(
-			key.WithKeys("up"),
-			key.WithHelp("↑", "up"),
-		),
-		Down: key.NewBinding(
-			key.WithKeys("down"),
-			key.WithHelp("↓", "down"),
-		),
-	}
+	vp.KeyMap = ViewportKeyMap(keys)
 	return vp
 }
 
+// ViewportStyle joins the viewport's box to the header above it.
+func ViewportStyle() lipgloss.Style {
+	vpBorder := Border()
+	if !cfg.Accessible && !Compact() {
+		vpBorder.TopLeft = "├"
+		vpBorder.TopRight = "┤"
+	}
+
+	style := lipgloss.NewStyle().Border(vpBorder).PaddingLeft(viewportPadding)
+	style.Background(Color(backgroundColor))
+	return style
+}
+
+// ViewportKeyMap leaves the viewport only scrolling, with the configured keys.
+func ViewportKeyMap(keys keyMap) viewport.KeyMap {
+	return viewport.KeyMap{
+		Up:       keys.ScrollUp,
+		Down:     keys.ScrollDown,
+		PageUp:   keys.PageUp,
+		PageDown: keys.PageDown,
+	}
+}
+
 func (m model) Init() tea.Cmd {
-	return tea.Batch(textarea.Blink, GetStatusCmd(), m.header.statusSpinner.Tick)
+	cmds := []tea.Cmd{GetStatusCmd(), DraftTickCmd(), WaitConfigChange(m.configWatcher), PruneCheckCmd(), SyncCmd(), RefreshOllamaModelsCmd(), WarmUpCmd(modelName), PrefillSendCmd()}
+	if Animated() {
+		cmds = append(cmds, textarea.Blink)
+	}
+	if AnimateStatusSpinner() {
+		cmds = append(cmds, m.header.statusSpinner.Tick)
+	}
+	if m.kbIndexing {
+		cmds = append(cmds, AddDirCmd(kbRoot))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.update(msg)
+
+	// The token gauge follows the draft as it is typed
+	m.statusBar.tokens = ContextTokens(chatMessages, m.textarea.Value())
+
+	return m, cmd
+}
+
+func (m model) update(msg tea.Msg) (model, tea.Cmd) {
 	var (
 		textInputCmd tea.Cmd
 		viewportCmd  tea.Cmd
 		spinnerCmd   tea.Cmd
 	)
 
-	m.textarea, textInputCmd = m.textarea.Update(msg)
-	m.viewport, viewportCmd = m.viewport.Update(msg)
+	keyMsg, isKey := msg.(tea.KeyMsg)
+
+	// A flagged message waits for confirmation before it is sent
+	if isKey && m.confirmSend != nil {
+		pending := m.confirmSend
+		m.confirmSend = nil
+		m.modal = ""
+
+		if keyMsg.String() == "y" {
+			return m, SendMessage(&m, pending.message, pending.attachments)
+		}
+		m.textarea.SetValue(pending.message)
+		return m, nil
+	}
+
+	if isKey && m.lint != nil {
+		return m, HandleLintKey(&m, keyMsg)
+	}
+
+	// Other confirmations run their action on y
+	if isKey && m.confirmAction != nil {
+		action := m.confirmAction
+		m.confirmAction = nil
+		m.modal = ""
+
+		if keyMsg.String() == "y" {
+			return m, action
+		}
+		AddNotice(&m, "Cancelled")
+		return m, nil
+	}
+
+	// Any key dismisses an open modal
+	if isKey && m.modal != "" {
+		m.modal = ""
+		return m, nil
+	}
+
+	// and an open citation
+	if isKey && m.citation != nil {
+		m.citation = nil
+		return m, nil
+	}
+
+	// The palette takes every key while open
+	if isKey && m.palette.visible {
+		return m, HandlePaletteKey(&m, keyMsg)
+	}
+
+	if isKey && m.raw {
+		return m, HandleRawTextKey(&m, keyMsg)
+	}
+
+	if isKey && m.replay != nil {
+		return m, HandleReplayKey(&m, keyMsg)
+	}
+
+	// Selection mode takes all keys but global bindings
+	if isKey && m.selecting {
+		if cmd, handled := HandleSelectionKey(&m, keyMsg); handled {
+			return m, cmd
+		}
+		cmd, _ := HandleGlobalKey(&m, keyMsg)
+		return m, cmd
+	}
+
+	if isKey && m.jumping {
+		if cmd, handled := HandleJumpKey(&m, keyMsg); handled {
+			return m, cmd
+		}
+		cmd, _ := HandleGlobalKey(&m, keyMsg)
+		return m, cmd
+	}
+
+	// Suggestions for the word being typed take arrows, tab and esc
+	if isKey && HandleCompletionKey(&m, keyMsg) {
+		return m, nil
+	}
+
+	// Global bindings take precedence over the panes
+	if isKey {
+		if cmd, handled := HandleGlobalKey(&m, keyMsg); handled {
+			return m, cmd
+		}
+	}
+
+	// Clicks focus panes and drags select transcript text
+	if mouseMsg, ok := msg.(tea.MouseMsg); ok && HandleMouse(&m, mouseMsg) {
+		return m, nil
+	}
+
+	// Keys only go to the focused pane, everything else to all of them
+	if !isKey || m.focus == focusInput {
+		before := m.textarea.Value()
+		m.textarea, textInputCmd = m.textarea.Update(msg)
+		m.input.Track(msg, before, &m.textarea)
+		ExpandAlias(&m.textarea, msg)
+		if isKey {
+			m.completion.Refresh(m.textarea.Value())
+		}
+	}
+	if !isKey || m.focus == focusViewport {
+		m.viewport, viewportCmd = m.viewport.Update(msg)
+	}
+	if isKey && m.focus == focusSidebar {
+		var sidebarCmd tea.Cmd
+		m.sidebar, sidebarCmd = m.sidebar.Update(msg)
+		return m, sidebarCmd
+	}
 
-	if m.waiting {
+	if m.request.InFlight() {
 		m.spinner, spinnerCmd = m.spinner.Update(msg)
 	}
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q", "esc":
-			fmt.Println(m.textarea.Value())
-			return m, tea.Quit
-		case "enter":
+		switch {
+		case key.Matches(msg, m.keys.Send) && m.focus == focusInput:
 			log.Printf("Msg: %v", msg.Type)
 			log.Printf("Message: %v", m.textarea.Value())
 			log.Printf("Message line count: %v", m.viewport.TotalLineCount())
 
 			message := strings.TrimSpace(m.textarea.Value())
-			message = wordwrap.String(message, viewportTextWidth-3)
-
-			m.messages = append(m.messages, m.promptStyle.Render(promptPrefix)+m.promptTextStyle.Render(message))
-			m.messages = append(m.messages, m.responseStyle.Render(responsePrefix)+m.spinner.View())
-
-			UpdateViewport(&m)
-
-			log.Printf("Viewport line count: %v\n", m.viewport.TotalLineCount())
-
 			m.textarea.Reset()
-			m.viewport.GotoBottom()
+			m.completion = completion{}
 
-			m.waiting = true
+			if isCommand(message) {
+				return m, tea.Batch(RunCommand(&m, message), textInputCmd, viewportCmd)
+			}
+
+			// Hold messages written during a request, or while offline,
+			// until they can be sent
+			if m.request.InFlight() || m.offline {
+				m.queue = append(m.queue, message)
+				AddNotice(&m, "Queued: "+message)
+				return m, tea.Batch(textInputCmd, viewportCmd)
+			}
 
-			return m, tea.Batch(m.spinner.Tick, GetResponseCmd(message), textInputCmd, viewportCmd)
+			if tokens := ContextTokens(chatMessages, message); Overflows(modelName, tokens) {
+				AddNotice(&m, OverflowWarning(modelName, tokens))
+			}
 
+			return m, tea.Batch(RequestSend(&m, message), textInputCmd, viewportCmd)
 		}
 
 	case spinner.TickMsg:
 
 		if msg.ID == m.spinner.ID() {
-			if !m.waiting {
+			if !m.request.InFlight() {
+				m.spinnerActive = false
 				return m, nil
 			}
 
 			m.spinner, _ = m.spinner.Update(msg)
 
-			updatedMessage := m.responseStyle.Render(responsePrefix) + m.spinner.View()
-			m.messages = append(m.messages[:len(m.messages)-1], updatedMessage)
+			m.messages = append(m.messages[:len(m.messages)-1], m.PlaceholderMessage())
 
 			UpdateViewport(&m)
 
-			m.textarea.Reset()
-			m.viewport.GotoBottom()
-
 			// Control spinner animation
 			time.Sleep(100 * time.Millisecond)
 
@@ -328,30 +776,315 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	case draftTickMsg:
+		m.SaveDraft()
+		return m, DraftTickCmd()
+
+	case configChangedMsg:
+		return m, m.HandleConfigChange()
+
+	case configReloadMsg:
+		m.ApplyConfig(msg)
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.windowWidth = msg.Width
+		m.windowHeight = msg.Height
+		m.ResizeLayout(msg.Width, msg.Height)
+		if m.raw {
+			m.rawView.Width, m.rawView.Height = msg.Width, msg.Height-1
+			m.rawView.SetContent(RawTranscript(chatMessages, msg.Width))
+		}
+
 	case responseMsg:
 		log.Printf("Msg: %T", msg)
 
-		m.waiting = false
+		// The placeholder was already removed when the request was cancelled
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+
+		m.inspector.Set(requestInspectorTitle, RequestInspectorLines(msg)...)
+		m.header.RecordLatency(msg.latency)
+
+		if msg.usageByModel == nil {
+			msg.usageByModel = map[string]openai.Usage{msg.model: msg.usage}
+		}
+		for model, usage := range msg.usageByModel {
+			if s := m.sessionForTab(msg.tab); s != nil && msg.err == nil {
+				s.Stats.Record(model, usage, msg.latency)
+			}
+		}
+		if s := m.sessionForTab(msg.tab); s != nil && msg.err == nil {
+			s.Stats.RecordMetrics(msg.model, msg.metrics)
+		}
+
+		if msg.tab != m.ActiveTabID() {
+			m.BackgroundResponse(msg)
+			return m, nil
+		}
+
+		m.cancelRequest = nil
+		m.FinishTyping()
 
 		if msg.err != nil {
+			m.SetRequest(requestFailed)
+			BroadcastShare(shareEvent{Type: shareError, Content: msg.err.Error()})
+			if IsOffline(msg.err) {
+				return m, m.GoOffline()
+			}
 			m.err = msg.err
+			ShowError(&m, msg.err)
+			m.DropQueued()
+			if IsAuthError(msg.err) && m.modal == "" {
+				m.modal = AuthDiagnostics(msg.err)
+			}
 			return m, nil
 		}
 
+		m.SetRequest(requestTyping)
+		BroadcastShare(shareEvent{Type: shareResponse, Content: msg.message})
+
 		log.Printf("Original line count: %v", strings.Count(msg.message, "\n")+1)
 		log.Printf("Original message: \n%v", msg.message)
 
-		message := wordwrap.String(msg.message, viewportTextWidth-3)
-		response := m.responseStyle.Render(responsePrefix) + m.responseTextStyle.Render(message)
-		m.messages = append(m.messages[:len(m.messages)-1], response)
-
-		log.Printf("Wrapped line count: %v", strings.Count(message, "\n")+1)
+		chatMessages = append(chatMessages, msg.replies...)
+		reply := len(chatMessages) - 1
+		mixed := currentSession.RecordReplyModel(reply, msg.model)
+		currentSession.RecordGeneration(reply, msg.generation)
+		currentSession.RecordCitations(reply, msg.retrieved)
+		currentSession.RecordMetrics(reply, msg.metrics)
+		if msg.cached {
+			currentSession.MarkCached(reply)
+		}
+		m.messages = append(m.messages[:len(m.messages)-1], m.ReplyMessage(currentSession, msg.message, reply))
+		block := len(m.messages) - 1
 
+		// Earlier replies get their labels once a second model answers
+		if mixed {
+			LabelReplies(&m)
+		}
 		UpdateViewport(&m)
 
 		log.Printf("Viewport line count: %v\n", m.viewport.TotalLineCount())
 
-		m.viewport.GotoBottom()
+		for _, page := range msg.sources {
+			AddNotice(&m, icons.Tool+" "+FetchCitation(page))
+		}
+		if kbRoot != "" {
+			AddNotice(&m, KnowledgeBaseNotice(msg.retrieved))
+		}
+
+		typing := m.TypeOut(block, msg.message)
+		if typing == nil {
+			m.SetRequest(requestDone)
+		}
+
+		var verify tea.Cmd
+		if cfg.VerifyGrounding && len(msg.retrieved) > 0 {
+			verify = VerifyCmd(msg.tab, reply, msg.message, msg.retrieved)
+		}
+
+		SaveCurrentSession()
+		m.sidebar.Refresh()
+
+		return m, tea.Batch(typing, verify, ShowImages(&m, msg.message), m.SendQueued())
+
+	case usageMsg:
+		if m.statusBar.Record(msg.model, msg.usage, msg.rateLimit) {
+			m.modal = BudgetWarning(m.statusBar.daily.Cost)
+		}
+		return m, nil
+
+	case redactedMsg:
+		AddNotice(&m, RedactionNotice(msg.counts))
+		return m, nil
+
+	case moderationMsg:
+		m.inspector.Set(moderationInspectorTitle, ModerationInspectorLines(msg)...)
+
+		if msg.err != nil {
+			AddNotice(&m, "Moderation check failed, sending anyway: "+msg.err.Error())
+			return m, SendMessage(&m, msg.message, msg.attachments)
+		}
+		if len(msg.flagged) > 0 {
+			m.confirmSend = &msg
+			m.modal = ModerationWarning(msg)
+			return m, nil
+		}
+
+		return m, SendMessage(&m, msg.message, msg.attachments)
+
+	case imageMsg:
+		switch {
+		case msg.err != nil:
+			AddNotice(&m, "Could not load image: "+msg.err.Error())
+		case msg.transmit == "":
+			AddNotice(&m, ImagePlaceholder(msg.image))
+		default:
+			ShowImage(&m, msg)
+		}
+
+		return m, nil
+
+	case sidebarSelectMsg:
+		switch msg.item.kind {
+		case sidebarSession:
+			if m.request.InFlight() {
+				AddNotice(&m, "Wait for the current reply before switching sessions")
+				return m, nil
+			}
+
+			s, err := LoadSession(msg.item.sessionID)
+			if err != nil {
+				AddNotice(&m, "Could not open session: "+err.Error())
+				return m, nil
+			}
+			OpenSession(&m, s)
+		case sidebarPrompt:
+			m.textarea.SetValue(msg.item.prompt)
+			m.SetFocus(focusInput)
+		}
+
+		return m, nil
+
+	case fetchMsg:
+		if msg.err != nil {
+			AddNotice(&m, "Could not fetch: "+msg.err.Error())
+			return m, nil
+		}
+
+		chatMessages = append(chatMessages, FetchContextMessage(msg.page))
+		AddNotice(&m, FetchCitation(msg.page))
+
+		return m, nil
+
+	case syncMsg:
+		if msg.err != nil {
+			AddNotice(&m, "Could not sync sessions: "+msg.err.Error())
+		} else if msg.pulled > 0 || msg.pushed > 0 {
+			AddNotice(&m, fmt.Sprintf("Synced sessions: %d updated here, %d sent", msg.pulled, msg.pushed))
+		}
+		m.sidebar.Refresh()
+		return m, nil
+
+	case deviceCodeMsg:
+		if msg.err != nil {
+			AddNotice(&m, "Could not sign in: "+msg.err.Error())
+			return m, nil
+		}
+		AddNotice(&m, DeviceCodePrompt(msg.code))
+		return m, PollTokenCmd(msg.code)
+
+	case loginMsg:
+		if msg.err != nil {
+			AddNotice(&m, "Could not sign in: "+msg.err.Error())
+		} else {
+			AddNotice(&m, "Signed in")
+		}
+		return m, nil
+
+	case ollamaModelsMsg:
+		return m, HandleOllamaModels(&m, msg)
+
+	case warmedUpMsg:
+		HandleWarmedUp(&m, msg)
+		return m, nil
+
+	case prefillSendMsg:
+		return m, m.HandlePrefillSend(msg)
+
+	case summariesMsg:
+		return m, HandleSummaries(&m, msg)
+
+	case groundingMsg:
+		HandleGrounding(&m, msg)
+		return m, nil
+
+	case chunkReadConfirmedMsg:
+		return m, StartChunkedRead(&m, msg.read)
+
+	case chunkNotesMsg:
+		return m, HandleChunkNotes(&m, msg)
+
+	case proofreadMsg:
+		return m, HandleProofread(&m, msg)
+
+	case translationMsg:
+		return m, HandleTranslation(&m, msg)
+
+	case typewriterTickMsg:
+		return m, HandleTypewriterTick(&m, msg)
+
+	case replayTickMsg:
+		return m, HandleReplayTick(&m, msg)
+
+	case replyEditedMsg:
+		HandleReplyEdited(&m, msg)
+		return m, nil
+
+	case pullStartMsg:
+		return m, m.StartPull(msg.model)
+
+	case pullProgressMsg:
+		return m, HandlePullProgress(&m, msg)
+
+	case pruneMsg:
+		// Never stack the report on another confirmation, like a restore
+		if len(msg.sessions) > 0 && m.modal == "" && m.confirmAction == nil {
+			m.modal = PruneReport(msg.sessions)
+			m.confirmAction = PruneCmd(msg.sessions)
+		}
+		return m, nil
+
+	case prunedMsg:
+		if msg.err != nil {
+			AddNotice(&m, fmt.Sprintf("Pruned %d sessions, some could not be deleted: %v", msg.count, msg.err))
+		} else {
+			AddNotice(&m, fmt.Sprintf("Pruned %d sessions", msg.count))
+		}
+		m.sidebar.Refresh()
+		return m, nil
+
+	case restoreMsg:
+		m.RestoreSnapshot(msg.snapshot)
+		return m, nil
+
+	case uploadMsg:
+		switch {
+		case msg.url == "":
+			AddNotice(&m, "Could not share: "+msg.err.Error())
+		case msg.err != nil:
+			AddNotice(&m, msg.url+"\n"+msg.err.Error())
+		default:
+			AddNotice(&m, "Shared at "+msg.url+" (link copied)")
+		}
+
+		return m, nil
+
+	case addDirMsg:
+		HandleAddDir(&m, msg)
+		return m, nil
+
+	case shareEventMsg:
+		return m, HandleShareEvent(&m, msg)
+
+	case shareJoinedMsg:
+		sendShareEvent(msg.peer, shareEvent{Type: shareHistory, Messages: chatMessages})
+		AddNotice(&m, "Another terminal joined the session")
+
+		return m, nil
+
+	case shareClosedMsg:
+		if joinAddr != "" {
+			AddNotice(&m, "Disconnected from the host")
+			if m.request.InFlight() {
+				m.SetRequest(requestFailed)
+				m.messages = m.messages[:len(m.messages)-1]
+			}
+		} else {
+			AddNotice(&m, "A joined terminal left the session")
+		}
 
 		return m, nil
 
@@ -360,16 +1093,35 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if msg.err != nil {
 			m.err = msg.err
+			if errors.Is(msg.err, errModelLoading) {
+				return m, ProbeCmd()
+			}
+			if IsOffline(msg.err) {
+				m.SetOffline(true)
+				return m, ProbeCmd()
+			}
+			if IsAuthError(msg.err) {
+				AddNotice(&m, "The API rejected the key: "+ErrorSuggestion(msg.err))
+			}
 			return m, nil
 		}
 
 		m.header.requestSuccess = true
 
+		if m.offline {
+			m.SetOffline(false)
+			AddNotice(&m, "Back online")
+			if !m.request.InFlight() {
+				return m, m.SendQueued()
+			}
+		}
+
 		return m, nil
 
 	case error:
 		log.Printf("Msg: %v", msg)
 		m.err = msg
+		AddMessage(&m, ErrorMessage(msg))
 		return m, nil
 
 	}
@@ -377,38 +1129,236 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(textInputCmd, viewportCmd, spinnerCmd)
 }
 
+func (m model) RenderPrompt(message string) string {
+	prefix := PromptPrefix()
+	message = wordwrap.String(message, messageWidth(prefix))
+	return m.promptStyle.Render(prefix) + HighlightRedactions(message, m.promptTextStyle)
+}
+
+func (m model) RenderPlaceholder() string {
+	switch {
+	case cfg.Accessible:
+		return m.responseStyle.Render(ResponsePrefix()) + accessiblePlaceholder
+	case cfg.Quiet:
+		return m.responseStyle.Render(ResponsePrefix()) + quietPlaceholder
+	case !AnimateSpinner():
+		return m.responseStyle.Render(ResponsePrefix()) + staticPlaceholder
+	}
+	return m.responseStyle.Render(ResponsePrefix()) + m.spinner.View()
+}
+
+func (m model) RenderResponse(message string) string {
+	message = ConvertMath(message)
+	prefix := ResponsePrefix()
+	// Screen readers do better with the markdown table than box drawing
+	if !cfg.Accessible {
+		message = FormatTables(message, messageWidth(prefix))
+	}
+	message = wordwrap.String(message, messageWidth(prefix))
+	return m.responseStyle.Render(prefix) + m.responseTextStyle.Render(message)
+}
+
 func UpdateViewport(m *model) {
-	// TODO: Make chat start from bottom
+	anchor, offset, following := m.ScrollAnchor()
+
+	content := RenderMessages(m) + "\n"
 
-	toDisplay := strings.Join(m.messages, "\n") + "\n\u200e"
-	toDisplay, _ = m.renderer.Render(toDisplay + "\n ")
+	// The viewport scrolls by its full height but the border takes up rows,
+	// so pad the bottom by the frame size to keep the last line visible
+	frame := m.viewport.Style.GetVerticalFrameSize()
+	visible := m.viewport.Height - frame
+
+	// Anchor short conversations to the bottom, right above the input
+	m.contentTop = 0
+	if lines := strings.Count(content, "\n") + 1; lines < visible {
+		m.contentTop = visible - lines
+		content = strings.Repeat("\n", m.contentTop) + content
+	}
 
-	m.viewport.SetContent(toDisplay)
+	content += strings.Repeat("\n", frame)
+	m.viewportLines = strings.Split(content, "\n")
+	m.viewport.SetContent(content)
+	m.RestoreScroll(anchor, offset, following)
+
+	if m.raw {
+		m.rawView.SetContent(RawTranscript(chatMessages, m.rawView.Width))
+	}
 }
 
-func GetResponseCmd(message string) tea.Cmd {
-	return func() tea.Msg {
-		chatMessages = append(chatMessages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleUser,
-			Content: message,
-		})
+// SendMessage adds message, after its attachments, to the active conversation
+// and requests a reply.
+func SendMessage(m *model, message string, attachments []openai.ChatCompletionMessage) tea.Cmd {
+	// Joined sessions leave the request to the host
+	if joinAddr != "" {
+		SendToHost(m, message)
+		return nil
+	}
+
+	// Overrides were checked before, in RequestSend
+	overrides, message, _ := ParseOverrides(message)
+
+	BroadcastShare(shareEvent{Type: sharePrompt, Content: message})
+
+	requestCtx := BeginExchange(m, message, attachments, overrides)
+	cmd := GetResponseCmd(requestCtx, m.ActiveTabID(), chatMessages, message, overrides)
+
+	if cfg.UndoSendSeconds > 0 {
+		cmd = m.DelaySend(requestCtx, cmd)
+	}
+
+	return tea.Batch(m.StartSpinner(), cmd)
+}
+
+// BeginExchange adds message, its attachments and a reply placeholder to the
+// active conversation and returns the context for its request.
+func BeginExchange(m *model, message string, attachments []openai.ChatCompletionMessage, overrides requestOverrides) context.Context {
+	chatMessages = append(chatMessages, attachments...)
+	currentSession.pendingAttachments = len(attachments)
+	chatMessages = append(chatMessages, UserMessage(message))
+	currentSession.RecordOverrides(len(chatMessages)-1, overrides)
+
+	m.messages = append(m.messages, m.PromptMessage(currentSession, message, len(chatMessages)-1))
+	m.messages = append(m.messages, m.PlaceholderMessage())
+
+	UpdateViewport(m)
+
+	log.Printf("Viewport line count: %v\n", m.viewport.TotalLineCount())
+
+	m.viewport.GotoBottom()
+
+	m.SetRequest(requestSending)
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	m.cancelRequest = cancel
+
+	return requestCtx
+}
+
+// DropExchange removes the pending exchange of the active conversation,
+// the placeholder, its prompt and the files attached to it, and returns the
+// message that was sent.
+func DropExchange(m *model) (string, bool) {
+	m.messages = m.messages[:len(m.messages)-1]
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Kind == kindPrompt {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			break
+		}
+	}
+
+	last := len(chatMessages) - 1
+	if last < 0 || chatMessages[last].Role != openai.ChatMessageRoleUser {
+		return "", false
+	}
+	message := chatMessages[last].Content
+	currentSession.RecordOverrides(last, requestOverrides{})
+	chatMessages = chatMessages[:max(last-currentSession.pendingAttachments, 0)]
+	currentSession.pendingAttachments = 0
+
+	return message, true
+}
 
-		log.Print("Chat messages: ", chatMessages)
+func UserMessage(content string) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: content,
+	}
+}
+
+// GetResponseCmd requests a completion for history, which ends with the new
+// user message. The reply and any tool messages are returned in responseMsg
+// for the tab to append, so requests can finish in the background.
+func GetResponseCmd(ctx context.Context, tab int, history []openai.ChatCompletionMessage, message string, overrides requestOverrides) tea.Cmd {
+	history = slices.Clip(history)
+	index := slices.Clip(docIndex)
+	model := cmp.Or(overrides.Model, modelName)
+	parameters := RequestParameters()
+	language := cfg.ReplyLanguage
+
+	return func() tea.Msg {
+		ctx, cached := WithCacheReport(ctx)
+		ctx, firstByte := WithFirstByte(ctx)
 
+		messages, retrieved := WithRetrievedContext(ctx, index, history, message)
 		req := openai.ChatCompletionRequest{
-			Model:    modelName,
-			Messages: chatMessages,
+			Model:    model,
+			Messages: WithReplyLanguage(messages, language),
 		}
+		parameters(&req)
+		overrides.Apply(&req)
 
-		resp, err := client.CreateChatCompletion(ctx, req)
+		if cfg.FetchTool && SupportsTools(model) {
+			req.Tools = []openai.Tool{fetchTool}
+		}
+
+		var sources []fetchedPage
+		var usage openai.Usage
+		var replies []openai.ChatCompletionMessage
+		start := time.Now()
+
+		for round := 0; ; round++ {
+			if round > maxToolRounds {
+				err := fmt.Errorf("gave up after %d rounds of tool calls", maxToolRounds)
+				return responseMsg{tab: tab, model: model, err: err, usage: usage, latency: time.Since(start)}
+			}
 
-		chatMessages = append(chatMessages, resp.Choices[0].Message)
+			resp, err := callChat(ctx, req)
+			if err != nil {
+				return responseMsg{tab: tab, model: model, err: err, usage: usage, latency: time.Since(start)}
+			}
 
-		message := resp.Choices[0].Message.Content
+			usage.PromptTokens += resp.Usage.PromptTokens
+			usage.CompletionTokens += resp.Usage.CompletionTokens
+			usage.TotalTokens += resp.Usage.TotalTokens
+			if len(resp.Choices) == 0 {
+				return responseMsg{tab: tab, model: model, err: errNoChoices, usage: usage, latency: time.Since(start)}
+			}
 
-		return responseMsg{
-			message: message,
-			err:     err,
+			reply := resp.Choices[0].Message
+			replies = append(replies, reply)
+
+			if len(reply.ToolCalls) == 0 {
+				msg := responseMsg{
+					tab:       tab,
+					model:     model,
+					replies:   replies,
+					message:   reply.Content,
+					sources:   sources,
+					retrieved: retrieved,
+					cached:    *cached,
+					usage:     usage,
+					generation: generation{
+						Seed:        req.Seed,
+						Fingerprint: resp.SystemFingerprint,
+					},
+					latency: time.Since(start),
+				}
+				if !msg.cached {
+					msg.metrics = NewReplyMetrics(start, *firstByte, usage.CompletionTokens)
+				}
+				return msg
+			}
+
+			// Run the requested tools and send their output back
+			req.Messages = append(req.Messages, reply)
+			for _, call := range reply.ToolCalls {
+				result := "Error: unknown tool " + call.Function.Name
+				if call.Function.Name == fetchToolName {
+					var page *fetchedPage
+					result, page = runFetchTool(call)
+					if page != nil {
+						sources = append(sources, *page)
+					}
+				}
+
+				toolMessage := openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Content:    result,
+					ToolCallID: call.ID,
+				}
+				req.Messages = append(req.Messages, toolMessage)
+				replies = append(replies, toolMessage)
+			}
 		}
 	}
 
@@ -416,26 +1366,88 @@ func GetResponseCmd(message string) tea.Cmd {
 
 func GetStatusCmd() tea.Cmd {
 	return func() tea.Msg {
+		if joinAddr != "" {
+			return statusMsg{}
+		}
+
+		if provider.health != "" {
+			return statusMsg{err: ClassifyError(ProbeHealth(), modelName)}
+		}
+
 		// make get request to the clients base url
 		_, err := client.ListModels(ctx)
 
 		return statusMsg{
-			err: err,
+			err: ClassifyError(err, modelName),
 		}
 	}
 }
 
-func (m *model) resetSpinner() {
-	m.spinner = spinner.New()
-	m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
-	m.spinner.Spinner = spinnerType
+// StartSpinner starts the placeholder animation unless it is already running.
+func (m *model) StartSpinner() tea.Cmd {
+	if !m.request.InFlight() || m.spinnerActive || !AnimateSpinner() {
+		return nil
+	}
+	m.spinnerActive = true
+	return m.spinner.Tick
 }
 
 func (m model) View() string {
-	return lipgloss.JoinVertical(
+	if m.raw {
+		return m.RawTextView()
+	}
+
+	body := m.viewport.View()
+	if m.drag.active {
+		body = m.HighlightDrag(body)
+	}
+	if m.inspector.visible {
+		body = m.inspector.View()
+	}
+	if m.citation != nil {
+		body = m.citation.View()
+	}
+	if m.palette.visible {
+		body = m.palette.View()
+	}
+	if m.modal != "" {
+		body = RenderModal(m.modal)
+	}
+
+	footer := m.help.View(requestKeyMap{m.keys, m.request})
+	if m.jumping {
+		footer = m.JumpPromptView()
+	}
+
+	status := m.statusBar.View()
+	if m.pull != nil {
+		status = m.pull.View()
+	}
+
+	if Compact() {
+		return m.CompactView(body, m.completion.View(), status, footer)
+	}
+
+	column := lipgloss.JoinVertical(
 		lipgloss.Left,
+		m.TabBarView(),
 		m.header.View(),
-		m.viewport.View(),
-		m.textarea.View(),
+		body,
+		m.completion.View(),
+		UnderlineMisspelled(m.textarea.View(), m.textarea.Value()),
 	)
+	if m.sidebar.visible {
+		column = lipgloss.JoinHorizontal(lipgloss.Top, m.sidebar.View(), column)
+	}
+	if m.ShowDetails() {
+		width, height := lipgloss.Size(column)
+		column = lipgloss.JoinHorizontal(lipgloss.Top, column, m.DetailsView(m.windowWidth-width, height))
+	}
+
+	return m.FitWindow(lipgloss.JoinVertical(
+		lipgloss.Left,
+		column,
+		status,
+		footer,
+	))
 }
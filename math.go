@@ -0,0 +1,155 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Best effort conversion of TeX math in responses to readable unicode. This
+// is not a TeX renderer, it only makes common notation legible in a terminal.
+
+var (
+	displayMath = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]`)
+	// No space right inside the dollars and no digit after the closing one,
+	// so prices like "$5 and $10" are left alone
+	inlineMath = regexp.MustCompile(`\\\((.+?)\\\)|\$([^\s$](?:[^$\n]*[^\s$])?)\$([^0-9]|$)`)
+
+	mathFrac    = regexp.MustCompile(`\\[dt]?frac\s*\{([^{}]*)\}\s*\{([^{}]*)\}`)
+	mathSqrt    = regexp.MustCompile(`\\sqrt\s*\{([^{}]*)\}`)
+	mathText    = regexp.MustCompile(`\\(?:text|mathrm|mathbf|mathit|mathbb|mathcal|operatorname)\s*\{([^{}]*)\}`)
+	mathSup     = regexp.MustCompile(`\^\{([^{}]*)\}|\^(\S)`)
+	mathSub     = regexp.MustCompile(`_\{([^{}]*)\}|_(\S)`)
+	mathCommand = regexp.MustCompile(`\\([a-zA-Z]+)`)
+)
+
+var mathSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "iota": "ι",
+	"kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π",
+	"rho": "ρ", "sigma": "σ", "tau": "τ", "upsilon": "υ", "phi": "φ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "propto": "∝", "infty": "∞",
+	"sum": "∑", "prod": "∏", "int": "∫", "oint": "∮", "partial": "∂",
+	"nabla": "∇", "in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆",
+	"supset": "⊃", "cup": "∪", "cap": "∩", "emptyset": "∅", "forall": "∀",
+	"exists": "∃", "neg": "¬", "land": "∧", "lor": "∨", "to": "→",
+	"rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"leftrightarrow": "↔", "iff": "⇔", "implies": "⇒", "mapsto": "↦",
+	"cdots": "⋯", "ldots": "…", "dots": "…", "circ": "∘", "degree": "°",
+	"angle": "∠", "perp": "⊥", "parallel": "∥", "hbar": "ℏ", "ell": "ℓ",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "quad": "  ", "qquad": "    ",
+	"left": "", "right": "", "displaystyle": "", ",": " ",
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶',
+	'7': '⁷', '8': '⁸', '9': '⁹', '+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽',
+	')': '⁾', 'n': 'ⁿ', 'i': 'ⁱ', 'T': 'ᵀ',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆',
+	'7': '₇', '8': '₈', '9': '₉', '+': '₊', '-': '₋', '=': '₌', '(': '₍',
+	')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'n': 'ₙ',
+	'o': 'ₒ', 'x': 'ₓ',
+}
+
+// ConvertMath rewrites math segments outside code blocks and inline code.
+func ConvertMath(text string) string {
+	parts := strings.Split(text, "```")
+	for i := range parts {
+		// Odd parts are inside fenced code
+		if i%2 == 1 {
+			continue
+		}
+		parts[i] = convertOutsideInlineCode(parts[i])
+	}
+	return strings.Join(parts, "```")
+}
+
+func convertOutsideInlineCode(text string) string {
+	parts := strings.Split(text, "`")
+	for i := range parts {
+		if i%2 == 1 {
+			continue
+		}
+
+		parts[i] = displayMath.ReplaceAllStringFunc(parts[i], func(match string) string {
+			groups := displayMath.FindStringSubmatch(match)
+			return "\n    " + texToUnicode(groups[1]+groups[2]) + "\n"
+		})
+		parts[i] = inlineMath.ReplaceAllStringFunc(parts[i], func(match string) string {
+			groups := inlineMath.FindStringSubmatch(match)
+			return texToUnicode(groups[1]+groups[2]) + groups[3]
+		})
+	}
+	return strings.Join(parts, "`")
+}
+
+func texToUnicode(tex string) string {
+	tex = strings.TrimSpace(tex)
+	tex = strings.Join(strings.Fields(tex), " ")
+
+	tex = mathText.ReplaceAllString(tex, "$1")
+
+	// Innermost first so nested fractions and roots resolve
+	for i := 0; i < 4; i++ {
+		tex = mathFrac.ReplaceAllStringFunc(tex, func(match string) string {
+			groups := mathFrac.FindStringSubmatch(match)
+			return group(groups[1]) + "/" + group(groups[2])
+		})
+		tex = mathSqrt.ReplaceAllString(tex, "√($1)")
+	}
+
+	tex = mathCommand.ReplaceAllStringFunc(tex, func(match string) string {
+		if symbol, ok := mathSymbols[match[1:]]; ok {
+			return symbol
+		}
+		return match[1:]
+	})
+	tex = strings.ReplaceAll(tex, `\,`, " ")
+	tex = strings.ReplaceAll(tex, `\\`, "\n")
+
+	tex = mathSup.ReplaceAllStringFunc(tex, func(match string) string {
+		groups := mathSup.FindStringSubmatch(match)
+		return script(groups[1]+groups[2], superscripts, "^")
+	})
+	tex = mathSub.ReplaceAllStringFunc(tex, func(match string) string {
+		groups := mathSub.FindStringSubmatch(match)
+		return script(groups[1]+groups[2], subscripts, "_")
+	})
+
+	tex = strings.NewReplacer("{", "", "}", "").Replace(tex)
+	return tex
+}
+
+// group wraps compound fraction parts in parentheses.
+func group(s string) string {
+	s = strings.TrimSpace(s)
+	if len([]rune(s)) > 1 && strings.ContainsAny(s, "+-*/ ") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// script maps text to unicode super or subscripts, falling back to the
+// marker form when a character has no scripted equivalent.
+func script(s string, table map[rune]rune, marker string) string {
+	var b strings.Builder
+	for _, r := range s {
+		mapped, ok := table[r]
+		if !ok {
+			if len([]rune(s)) == 1 {
+				return marker + s
+			}
+			return marker + "(" + s + ")"
+		}
+		b.WriteRune(mapped)
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// The transcript is a list of messages rather than of styled strings. Prompts,
+// replies, notices, errors and the results of commands keep what they were
+// made from, so they can be rendered again at a new width or in a new theme,
+// quoted, edited and found; the styled block is only a cache of their
+// rendering. Other blocks, like images, are kept as rendered.
+
+type messageKind int
+
+const (
+	// A styled block kept as is
+	kindBlock messageKind = iota
+	kindNotice
+	kindPrompt
+	kindReply
+	// The reply being waited for
+	kindPlaceholder
+	// Kitty placeholders an image is drawn over, shown as is
+	kindImage
+	kindError
+	kindTranslation
+	kindProofread
+	// Progress of reading files in chunks, one line per file
+	kindProgress
+)
+
+// Message is a block of the transcript.
+type Message struct {
+	Kind messageKind
+	// Markdown of prompts and replies, text of notices, translations and
+	// progress, corrected text of proofreads
+	Content string
+	// Text before proofreading of proofreads, language label of translations
+	Note string
+	// Error of error blocks
+	Err error
+	// Index in the conversation of prompts and replies, -1 for blocks that
+	// are not part of it
+	Index  int
+	Time   time.Time
+	Model  string
+	Tokens int
+	// Whether the message was folded or unfolded by hand
+	Fold foldState
+
+	// The styled block shown, rendered from the above
+	Rendered string
+}
+
+// BlockMessage is a block shown as rendered.
+func BlockMessage(block string) Message {
+	return Message{Kind: kindBlock, Index: -1, Time: time.Now(), Rendered: block}
+}
+
+// NoticeMessage is a dim informational line.
+func (m model) NoticeMessage(text string) Message {
+	return Message{Kind: kindNotice, Content: text, Index: -1, Time: time.Now(), Rendered: m.RenderNotice(text)}
+}
+
+// PromptMessage is the prompt at index i of the conversation of s, or a
+// prompt outside of it with i -1.
+func (m model) PromptMessage(s session, content string, i int) Message {
+	msg := Message{Kind: kindPrompt, Content: content, Index: i, Time: sentAt(s, i)}
+	msg.Rendered = m.RenderMessage(s, msg)
+	return msg
+}
+
+// ReplyMessage is the reply at index i of the conversation of s.
+func (m model) ReplyMessage(s session, content string, i int) Message {
+	msg := Message{
+		Kind:    kindReply,
+		Content: content,
+		Index:   i,
+		Time:    sentAt(s, i),
+		Model:   s.ReplyModels[i],
+		Tokens:  s.Metrics[i].CompletionTokens,
+	}
+	msg.Rendered = m.RenderMessage(s, msg)
+	return msg
+}
+
+// ImageMessage is the placeholder block of an image uploaded to the
+// terminal.
+func ImageMessage(block string) Message {
+	return Message{Kind: kindImage, Content: block, Index: -1, Time: time.Now(), Rendered: block}
+}
+
+// ErrorMessage is the error block of err.
+func ErrorMessage(err error) Message {
+	return Message{Kind: kindError, Err: err, Index: -1, Time: time.Now(), Rendered: RenderError(err)}
+}
+
+// TranslationMessage is a translation with its source and target language.
+func (m model) TranslationMessage(translation, label string) Message {
+	msg := Message{Kind: kindTranslation, Content: translation, Note: label, Index: -1, Time: time.Now()}
+	msg.Rendered = m.RenderTranslation(translation, label)
+	return msg
+}
+
+// ProofreadMessage is the corrections from original to corrected.
+func (m model) ProofreadMessage(original, corrected string) Message {
+	msg := Message{Kind: kindProofread, Content: corrected, Note: original, Index: -1, Time: time.Now()}
+	msg.Rendered = m.RenderProofread(original, corrected)
+	return msg
+}
+
+// ProgressMessage is a progress block of lines.
+func (m model) ProgressMessage(progress string) Message {
+	return Message{Kind: kindProgress, Content: progress, Index: -1, Time: time.Now(), Rendered: m.RenderProgress(progress)}
+}
+
+// PlaceholderMessage stands in for the reply being waited for.
+func (m model) PlaceholderMessage() Message {
+	return Message{Kind: kindPlaceholder, Index: -1, Time: time.Now(), Rendered: m.RenderPlaceholder()}
+}
+
+// RenderMessage styles msg for the current width and theme, with the labels
+// s has for it.
+func (m model) RenderMessage(s session, msg Message) string {
+	switch msg.Kind {
+	case kindNotice:
+		return m.RenderNotice(msg.Content)
+	case kindPrompt:
+		if msg.Index < 0 {
+			return m.RenderPrompt(msg.Content)
+		}
+		return m.RenderSentPrompt(s, msg.Content, msg.Index)
+	case kindReply:
+		if msg.Index < 0 {
+			return m.RenderResponse(msg.Content)
+		}
+		return m.RenderReply(s, msg.Content, msg.Index)
+	case kindPlaceholder:
+		return m.RenderPlaceholder()
+	case kindImage:
+		return msg.Content
+	case kindError:
+		return RenderError(msg.Err)
+	case kindTranslation:
+		return m.RenderTranslation(msg.Content, msg.Note)
+	case kindProofread:
+		return m.RenderProofread(msg.Note, msg.Content)
+	case kindProgress:
+		return m.RenderProgress(msg.Content)
+	}
+	return msg.Rendered
+}
+
+// RenderNotice styles a notice, wrapped to the transcript.
+func (m model) RenderNotice(text string) string {
+	return m.noticeStyle.Render(noticePrefix + wordwrap.String(text, textWidth()-3))
+}
+
+// RenderProgress styles progress lines like a notice.
+func (m model) RenderProgress(progress string) string {
+	indent := strings.Repeat(" ", lipgloss.Width(noticePrefix))
+	return m.noticeStyle.Render(noticePrefix + strings.ReplaceAll(progress, "\n", "\n"+indent))
+}
+
+// RerenderMessages renders messages again with the labels s has for them,
+// after the width or theme changed.
+func (m model) RerenderMessages(s session, messages []Message) {
+	for i := range messages {
+		messages[i].Rendered = m.RenderMessage(s, messages[i])
+	}
+}
+
+// sentAt is when the message at index i of s was first saved, now for
+// messages not saved yet.
+func sentAt(s session, i int) time.Time {
+	if i >= 0 && i < len(s.MessageTimes) && !s.MessageTimes[i].IsZero() {
+		return s.MessageTimes[i]
+	}
+	return time.Now()
+}
+
+// RerenderTabs renders the transcript of every tab again, keeping notices.
+func (m *model) RerenderTabs() {
+	for i := range m.tabs {
+		if i != m.activeTab {
+			m.RerenderMessages(m.tabs[i].session, m.tabs[i].messages)
+		}
+	}
+	m.RerenderMessages(currentSession, m.messages)
+	UpdateViewport(m)
+}
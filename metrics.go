@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptrace"
+	"time"
+)
+
+// Each reply is timed: how long until the first byte of its response arrived
+// (TTFB), how long the request took in all and how fast tokens came. Replies
+// are not streamed, so TTFB is when the whole reply is ready rather than its
+// first token. The timings are shown in the inspector, below replies with
+// reply_metrics set, and per model in /stats, to compare providers.
+
+// replyMetrics are the timings of a reply, kept by its index in the session.
+type replyMetrics struct {
+	FirstByte        time.Duration `json:"first_byte"`
+	Duration         time.Duration `json:"duration"`
+	CompletionTokens int           `json:"completion_tokens"`
+}
+
+// WithFirstByte returns a context for requests, and the time the first of
+// them began to receive its response once they are sent.
+func WithFirstByte(ctx context.Context) (context.Context, *time.Time) {
+	first := new(time.Time)
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			if first.IsZero() {
+				*first = time.Now()
+			}
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), first
+}
+
+// NewReplyMetrics times a reply requested at start. Responses that did not
+// come over HTTP, like those of the fake provider, count as arriving at once.
+func NewReplyMetrics(start, firstByte time.Time, completionTokens int) replyMetrics {
+	metrics := replyMetrics{Duration: time.Since(start), CompletionTokens: completionTokens}
+	metrics.FirstByte = metrics.Duration
+	if !firstByte.IsZero() {
+		metrics.FirstByte = firstByte.Sub(start)
+	}
+	return metrics
+}
+
+// TokensPerSecond is the rate of completion tokens over the whole request.
+func (r replyMetrics) TokensPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.CompletionTokens) / r.Duration.Seconds()
+}
+
+func (r replyMetrics) String() string {
+	return fmt.Sprintf("TTFB %.1fs · %.1fs · %.0f tok/s", r.FirstByte.Seconds(), r.Duration.Seconds(), r.TokensPerSecond())
+}
+
+// RecordMetrics keeps the timings of the reply at index i.
+func (s *session) RecordMetrics(i int, metrics replyMetrics) {
+	if metrics.Duration == 0 {
+		return
+	}
+	if s.Metrics == nil {
+		s.Metrics = map[int]replyMetrics{}
+	}
+	s.Metrics[i] = metrics
+}
+
+// RecordMetrics adds the timings of a reply to the stats of its model.
+func (s *sessionStats) RecordMetrics(model string, metrics replyMetrics) {
+	if metrics.Duration == 0 {
+		return
+	}
+	if *s == nil {
+		*s = sessionStats{}
+	}
+
+	stats := (*s)[model]
+	stats.Timed++
+	stats.FirstByte += metrics.FirstByte
+	stats.Generation += metrics.Duration
+	stats.GeneratedTokens += metrics.CompletionTokens
+	stats.Provider = provider.name
+	(*s)[model] = stats
+}
+
+// averageFirstByte is the mean time to the first byte of the timed requests.
+func averageFirstByte(stats modelStats) string {
+	if stats.Timed == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", (stats.FirstByte / time.Duration(stats.Timed)).Seconds())
+}
+
+// averageTokenRate is the mean rate of completion tokens of the timed
+// requests.
+func averageTokenRate(stats modelStats) string {
+	if stats.Generation <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f tok/s", float64(stats.GeneratedTokens)/stats.Generation.Seconds())
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Chat completion requests go through a chain of middleware on their way to
+// the provider, so features that act on every request, like the audit log or
+// retries, wrap the call once instead of being repeated at each call site.
+
+// Caller sends a chat completion request.
+type Caller func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+
+// Middleware wraps a Caller, acting on the request, the response or both.
+type Middleware func(next Caller) Caller
+
+// Initial wait between retries, doubled after each
+const retryBackoff = time.Second
+
+// callChat sends chat completion requests through the middleware, set up by
+// initializeClient.
+var callChat Caller
+
+// notify hands what the middleware learns about a request to the interface,
+// set to the Send of the program in main. Without the interface, as in the
+// batch subcommand, it is dropped.
+var notify = func(tea.Msg) {}
+
+// usageMsg reports the usage of a finished request for the status bar.
+type usageMsg struct {
+	model     string
+	usage     openai.Usage
+	rateLimit openai.RateLimitHeaders
+}
+
+// redactedMsg reports what RedactMiddleware masked in a request.
+type redactedMsg struct {
+	counts map[string]int
+}
+
+// Chain wraps call in middlewares, the first outermost.
+func Chain(call Caller, middlewares ...Middleware) Caller {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		call = middlewares[i](call)
+	}
+	return call
+}
+
+// Middlewares is the chain every chat completion request goes through.
+func Middlewares() []Middleware {
+	return []Middleware{ErrorMiddleware, RedactMiddleware, LogMiddleware, CacheMiddleware, UsageMiddleware, AuditMiddleware, RetryMiddleware}
+}
+
+// reportedRedactions holds the hashes of the message contents whose
+// redactions were reported, so a history is only reported on once.
+var reportedRedactions sync.Map
+
+// RedactMiddleware masks secrets and personal data in the messages of each
+// request when redaction is on, see Redact. What it masks is reported for
+// messages sent for the first time.
+func RedactMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		if !cfg.Redact {
+			return next(ctx, req)
+		}
+
+		counts := map[string]int{}
+		messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+		for i, message := range req.Messages {
+			var found map[string]int
+			content := message.Content
+			message.Content, found = Redact(content)
+			if _, reported := reportedRedactions.LoadOrStore(hashMessage(content), true); !reported {
+				for label, n := range found {
+					counts[label] += n
+				}
+			}
+			messages[i] = message
+		}
+		req.Messages = messages
+
+		if RedactionNotice(counts) != "" {
+			notify(redactedMsg{counts})
+		}
+		return next(ctx, req)
+	}
+}
+
+// UsageMiddleware adds the cost of each request that reached the provider to
+// the status bar. Cached answers pass around it, as they cost nothing.
+func UsageMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		resp, err := next(ctx, req)
+		if err == nil {
+			notify(usageMsg{model: req.Model, usage: resp.Usage, rateLimit: resp.GetRateLimitHeaders()})
+		}
+		return resp, err
+	}
+}
+
+// LogMiddleware logs the model, token counts and latency of each request.
+func LogMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		if err != nil {
+			log.Printf("Request to %s failed after %s: %v", req.Model, time.Since(start), err)
+		} else {
+			log.Printf("Request to %s: %d prompt, %d completion tokens in %s",
+				req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, time.Since(start))
+		}
+		return resp, err
+	}
+}
+
+// AuditMiddleware appends each request and its outcome to the audit log.
+func AuditMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		resp, err := next(ctx, req)
+		AuditRequest(req, resp, err)
+		return resp, err
+	}
+}
+
+// RetryMiddleware sends a request again up to the configured number of
+// retries when the provider fails with a server error or rate limit, waiting
+// longer each time.
+func RetryMiddleware(next Caller) Caller {
+	return func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+		wait := retryBackoff
+		for attempt := 0; ; attempt++ {
+			resp, err := next(ctx, req)
+			if err == nil || attempt >= cfg.Retries || !retryable(err) {
+				return resp, err
+			}
+
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return resp, err
+			}
+			wait *= 2
+		}
+	}
+}
+
+// retryable reports whether a request that failed with err may succeed when
+// sent again.
+func retryable(err error) bool {
+	status, _ := errorDetails(err)
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
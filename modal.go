@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+const modalColor = "#e88388"
+
+// RenderModal draws text in a bordered box centered over the viewport area.
+func RenderModal(text string) string {
+	box := lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(modalColor)).
+		Padding(1, 2).
+		Width(min(viewportTextWidth/2, bodyWidth())).
+		Render(text)
+
+	width, height := viewportWidth+2, viewportHeight+4
+	if Compact() {
+		width, height = bodyWidth(), bodyHeight()
+	}
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// modelInfo describes what a model can do and what it costs. Prices are USD
+// per million tokens.
+type modelInfo struct {
+	ContextWindow int     `json:"context_window"`
+	InputPrice    float64 `json:"input_price"`
+	OutputPrice   float64 `json:"output_price"`
+	Vision        bool    `json:"vision"`
+	Tools         bool    `json:"tools"`
+	JSONMode      bool    `json:"json_mode"`
+}
+
+var builtinModels = map[string]modelInfo{
+	openai.GPT3Dot5Turbo: {ContextWindow: 16_385, InputPrice: 0.5, OutputPrice: 1.5, Tools: true, JSONMode: true},
+	openai.GPT4o:         {ContextWindow: 128_000, InputPrice: 5, OutputPrice: 15, Vision: true, Tools: true, JSONMode: true},
+	openai.GPT4Turbo:     {ContextWindow: 128_000, InputPrice: 10, OutputPrice: 30, Vision: true, Tools: true, JSONMode: true},
+	openai.GPT4:          {ContextWindow: 8_192, InputPrice: 30, OutputPrice: 60, Tools: true},
+
+	// Embedding models, for their input price
+	string(openai.SmallEmbedding3): {ContextWindow: 8_191, InputPrice: 0.02},
+	string(openai.LargeEmbedding3): {ContextWindow: 8_191, InputPrice: 0.13},
+	string(openai.AdaEmbeddingV2):  {ContextWindow: 8_191, InputPrice: 0.1},
+}
+
+// LookupModel returns what is known about a model. Entries in the models
+// config replace the built-in ones.
+func LookupModel(name string) (modelInfo, bool) {
+	if info, ok := cfg.Models[name]; ok {
+		return info, true
+	}
+	info, ok := builtinModels[name]
+	return info, ok
+}
+
+// SupportsTools reports whether tool calls can be sent to a model. Unknown
+// models are assumed to support them, like before the registry.
+func SupportsTools(name string) bool {
+	info, ok := LookupModel(name)
+	return !ok || info.Tools
+}
+
+// SupportsJSONMode reports whether a model can be asked for a JSON object
+// reply. Unknown models are not, as the request fails where it is unsupported.
+func SupportsJSONMode(name string) bool {
+	info, ok := LookupModel(name)
+	return ok && info.JSONMode
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const moderationInspectorTitle = "Moderation"
+
+type moderationMsg struct {
+	message     string
+	attachments []openai.ChatCompletionMessage
+	flagged     []string
+	scores      map[string]float64
+	err         error
+}
+
+// ModerateCmd checks message against the moderations endpoint before it is
+// sent with attachments, see cfg.Moderation.
+func ModerateCmd(message string, attachments []openai.ChatCompletionMessage) tea.Cmd {
+	return func() tea.Msg {
+		// Moderation requests do not go through the chat middleware
+		input := message
+		if cfg.Redact {
+			input, _ = Redact(message)
+		}
+		resp, err := client.Moderations(ctx, openai.ModerationRequest{Input: input})
+		if err != nil {
+			return moderationMsg{message: message, attachments: attachments, err: err}
+		}
+		if len(resp.Results) == 0 {
+			return moderationMsg{message: message, attachments: attachments, err: fmt.Errorf("empty moderation result")}
+		}
+
+		result := resp.Results[0]
+		msg := moderationMsg{message: message, attachments: attachments}
+
+		// Categories are only named in the JSON tags
+		var categories map[string]bool
+		data, _ := json.Marshal(result.Categories)
+		json.Unmarshal(data, &categories)
+		for category, flagged := range categories {
+			if flagged {
+				msg.flagged = append(msg.flagged, category)
+			}
+		}
+		sort.Strings(msg.flagged)
+
+		data, _ = json.Marshal(result.CategoryScores)
+		json.Unmarshal(data, &msg.scores)
+
+		return msg
+	}
+}
+
+// RequestSend sends message with the files it mentions attached, redacted and
+// after a moderation check when enabled.
+func RequestSend(m *model, message string) tea.Cmd {
+	m.proofread = ""
+	if m.kbIndexing {
+		AddNotice(m, "Wait for "+kbRoot+" to be indexed")
+		m.textarea.SetValue(message)
+		return nil
+	}
+	if m.translateTo != "" {
+		return Translate(m, message)
+	}
+
+	if _, _, err := ParseOverrides(message); err != nil {
+		AddNotice(m, err.Error())
+		m.textarea.SetValue(message)
+		return nil
+	}
+
+	if paths := OversizedDocuments(message); len(paths) > 0 {
+		return ReadInChunks(m, message, paths)
+	}
+	if found := LintAttachments(message); len(found) > 0 {
+		m.lint = &attachmentLint{message: message, found: found}
+		m.modal = LintWarning(found)
+		return nil
+	}
+	return SendChecked(m, message, nil)
+}
+
+// SendChecked sends a message that passed the attachment check, with the
+// summaries of files mentioned in it attached in their place.
+func SendChecked(m *model, message string, summaries map[string]string) tea.Cmd {
+	attachments := MentionAttachments(m, message, summaries)
+	SlowStartNotice(m)
+
+	if cfg.Moderation {
+		return ModerateCmd(message, attachments)
+	}
+	return SendMessage(m, message, attachments)
+}
+
+func ModerationInspectorLines(msg moderationMsg) []string {
+	if msg.err != nil {
+		return []string{"error: " + msg.err.Error()}
+	}
+
+	lines := []string{"flagged: none"}
+	if len(msg.flagged) > 0 {
+		lines[0] = "flagged: " + strings.Join(msg.flagged, ", ")
+	}
+
+	categories := make([]string, 0, len(msg.scores))
+	for category := range msg.scores {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return msg.scores[categories[i]] > msg.scores[categories[j]]
+	})
+	for _, category := range categories[:min(5, len(categories))] {
+		lines = append(lines, fmt.Sprintf("  %-24s %.4f", category, msg.scores[category]))
+	}
+
+	return lines
+}
+
+func ModerationWarning(msg moderationMsg) string {
+	return fmt.Sprintf(
+		"Message flagged by moderation\n\nCategories: %s\n\nPress y to send anyway, any other key to edit it.",
+		strings.Join(msg.flagged, ", "),
+	)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mouseDrag is a selection being dragged over the transcript, in content
+// lines and columns of the viewport.
+type mouseDrag struct {
+	active    bool
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+// ordered returns the drag's start and end in reading order.
+func (d mouseDrag) ordered() (int, int, int, int) {
+	if d.endLine < d.startLine || (d.endLine == d.startLine && d.endCol < d.startCol) {
+		return d.endLine, d.endCol, d.startLine, d.startCol
+	}
+	return d.startLine, d.startCol, d.endLine, d.endCol
+}
+
+// columnLeft is where the conversation column starts, right of the sidebar.
+// In compact mode an open sidebar covers the whole window.
+func (m model) columnLeft() int {
+	if !m.sidebar.visible {
+		return 0
+	}
+	if Compact() {
+		return m.windowWidth
+	}
+	return lipgloss.Width(m.sidebar.View())
+}
+
+// bodyTop is the row of the viewport's top border.
+func (m model) bodyTop() int {
+	return lipgloss.Height(m.TabBarView()) + lipgloss.Height(m.header.View())
+}
+
+// viewportTextLeft is the columns between the viewport's left edge and its
+// text: border and padding.
+func (m model) viewportTextLeft() int {
+	return m.viewport.Style.GetBorderLeftSize() + m.viewport.Style.GetPaddingLeft()
+}
+
+func (m model) inputTop() int {
+	return m.bodyTop() + m.viewport.Height + lipgloss.Height(m.completion.View())
+}
+
+// contentPosition maps a cell to a line and column of the viewport content,
+// clamped to the visible text.
+func (m model) contentPosition(x, y int) (int, int) {
+	row := min(max(y-m.bodyTop()-m.viewport.Style.GetBorderTopSize(), 0), m.viewport.Height-m.viewport.Style.GetVerticalFrameSize()-1)
+	col := max(x-m.columnLeft()-m.viewportTextLeft(), 0)
+	return m.viewport.YOffset + row, col
+}
+
+// HandleMouse focuses the pane clicked on and selects transcript text by
+// dragging, copying it when the button is released. The wheel is left to the
+// viewport.
+func HandleMouse(m *model, msg tea.MouseMsg) bool {
+	// Some terminals report no button for motion and release
+	if msg.Button != tea.MouseButtonLeft && !(m.drag.active && msg.Action != tea.MouseActionPress) {
+		return false
+	}
+	if m.modal != "" || m.palette.visible || m.inspector.visible {
+		return false
+	}
+
+	switch msg.Action {
+	case tea.MouseActionPress:
+		switch {
+		case msg.X < m.columnLeft():
+			m.SetFocus(focusSidebar)
+		case msg.Y >= m.inputTop():
+			m.SetFocus(focusInput)
+		case msg.Y >= m.bodyTop() && msg.Y < m.bodyTop()+m.viewport.Height:
+			m.SetFocus(focusViewport)
+			line, col := m.contentPosition(msg.X, msg.Y)
+			m.drag = mouseDrag{active: true, startLine: line, startCol: col, endLine: line, endCol: col}
+		}
+
+	case tea.MouseActionMotion:
+		if m.drag.active {
+			m.drag.endLine, m.drag.endCol = m.contentPosition(msg.X, msg.Y)
+		}
+
+	case tea.MouseActionRelease:
+		if !m.drag.active {
+			return true
+		}
+		m.drag.endLine, m.drag.endCol = m.contentPosition(msg.X, msg.Y)
+		text := m.DraggedText()
+		m.drag = mouseDrag{}
+
+		if text == "" {
+			return true
+		}
+		if err := clipboard.WriteAll(text); err != nil {
+			AddNotice(m, "Could not copy: "+err.Error())
+		} else {
+			AddNotice(m, "Copied selection to clipboard")
+		}
+	}
+
+	return true
+}
+
+// DraggedText is the plain text under the drag, without colors and trailing
+// padding.
+func (m model) DraggedText() string {
+	startLine, startCol, endLine, endCol := m.drag.ordered()
+	if startLine == endLine && startCol == endCol {
+		return ""
+	}
+
+	var lines []string
+	for i := startLine; i <= endLine && i < len(m.viewportLines); i++ {
+		runes := []rune(StripANSI(m.viewportLines[i]))
+		from, to := 0, len(runes)
+		if i == startLine {
+			from = min(startCol, len(runes))
+		}
+		if i == endLine {
+			to = min(endCol+1, len(runes))
+		}
+		lines = append(lines, strings.TrimRight(string(runes[from:max(from, to)]), " "))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// HighlightDrag shows the dragged text of the viewport view in reverse video.
+// Highlighted lines lose their colors until the button is released.
+func (m model) HighlightDrag(view string) string {
+	startLine, startCol, endLine, endCol := m.drag.ordered()
+	highlight := lipgloss.NewStyle().Reverse(true)
+	left := m.viewportTextLeft()
+	top, bottom := m.viewport.Style.GetBorderTopSize(), m.viewport.Style.GetBorderBottomSize()
+	right := m.viewport.Style.GetBorderRightSize()
+
+	rows := strings.Split(view, "\n")
+	for r := top; r < len(rows)-bottom; r++ {
+		line := m.viewport.YOffset + r - top
+		if line < startLine || line > endLine {
+			continue
+		}
+
+		runes := []rune(StripANSI(rows[r]))
+		from, to := left, len(runes)-right
+		if line == startLine {
+			from = min(left+startCol, len(runes))
+		}
+		if line == endLine {
+			to = min(left+endCol+1, len(runes)-right)
+		}
+		if from >= to {
+			continue
+		}
+		rows[r] = string(runes[:from]) + highlight.Render(string(runes[from:to])) + string(runes[to:])
+	}
+	return strings.Join(rows, "\n")
+}
+
+// StripANSI removes escape sequences, leaving the text as shown.
+func StripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i = escapeEnd(s, i)
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	tokenFileName = "token.json"
+	oauthTimeout  = 30 * time.Second
+
+	deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// Refresh a little before the token runs out, not on the failed request
+	tokenExpiryMargin = time.Minute
+)
+
+// OAuthConfig sets up the device code flow of RFC 8628 for providers that
+// hand out tokens instead of API keys, e.g. GitHub:
+//
+//	"oauth": {"client_id": "...", "device_url": "https://github.com/login/device/code",
+//	          "token_url": "https://github.com/login/oauth/access_token"}
+type OAuthConfig struct {
+	ClientID  string `json:"client_id"`
+	DeviceURL string `json:"device_url"`
+	TokenURL  string `json:"token_url"`
+	Scope     string `json:"scope"`
+}
+
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// deviceCodeMsg asks the user to enter the code at the verification page.
+type deviceCodeMsg struct {
+	code deviceCode
+	err  error
+}
+
+type loginMsg struct {
+	err error
+}
+
+var (
+	tokenMu sync.Mutex
+	token   *oauthToken
+)
+
+func init() {
+	registerCommand(command{
+		name:        "login",
+		usage:       "/login",
+		description: "Sign in with the configured OAuth provider",
+		run: func(m *model, args string) tea.Cmd {
+			if cfg.OAuth.ClientID == "" {
+				AddNotice(m, "No OAuth provider, set oauth in the config")
+				return nil
+			}
+			AddNotice(m, "Requesting a sign-in code…")
+			return RequestDeviceCodeCmd()
+		},
+	})
+}
+
+func tokenPath() string {
+	return filepath.Join(configDir(), tokenFileName)
+}
+
+func (t oauthToken) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().Add(tokenExpiryMargin).After(t.Expiry)
+}
+
+// oauthPost posts a form and decodes the JSON answer, OAuth errors included.
+func oauthPost(endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers form encoded otherwise
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := (&http.Client{Timeout: oauthTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func RequestDeviceCodeCmd() tea.Cmd {
+	return func() tea.Msg {
+		var code deviceCode
+		err := oauthPost(cfg.OAuth.DeviceURL, url.Values{
+			"client_id": {cfg.OAuth.ClientID},
+			"scope":     {cfg.OAuth.Scope},
+		}, &code)
+		if err == nil && code.DeviceCode == "" {
+			err = errors.New("no device code returned")
+		}
+		return deviceCodeMsg{code: code, err: err}
+	}
+}
+
+// DeviceCodePrompt tells the user where to enter the code, which is also put
+// on the clipboard.
+func DeviceCodePrompt(code deviceCode) string {
+	copied := ""
+	if clipboard.WriteAll(code.UserCode) == nil {
+		copied = " (copied)"
+	}
+	return fmt.Sprintf("Open %s and enter %s%s, waiting for sign-in…", code.VerificationURI, code.UserCode, copied)
+}
+
+// PollTokenCmd waits for the user to approve the device code and stores the
+// token it is exchanged for.
+func PollTokenCmd(code deviceCode) tea.Cmd {
+	return func() tea.Msg {
+		interval := time.Duration(max(code.Interval, 5)) * time.Second
+		deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+		for time.Now().Before(deadline) {
+			time.Sleep(interval)
+
+			t, pending, err := exchangeToken(url.Values{
+				"client_id":   {cfg.OAuth.ClientID},
+				"device_code": {code.DeviceCode},
+				"grant_type":  {deviceCodeGrant},
+			})
+			switch {
+			case err != nil:
+				return loginMsg{err: err}
+			case pending == "slow_down":
+				interval += 5 * time.Second
+			case pending == "":
+				return loginMsg{err: SetToken(t)}
+			}
+		}
+
+		return loginMsg{err: errors.New("the code expired, run /login again")}
+	}
+}
+
+// exchangeToken requests a token. While the user has not approved the code
+// yet, the pending OAuth error is returned instead of failing.
+func exchangeToken(form url.Values) (oauthToken, string, error) {
+	var resp struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := oauthPost(cfg.OAuth.TokenURL, form, &resp); err != nil {
+		return oauthToken{}, "", err
+	}
+
+	switch resp.Error {
+	case "":
+	case "authorization_pending", "slow_down":
+		return oauthToken{}, resp.Error, nil
+	default:
+		if resp.ErrorDescription != "" {
+			return oauthToken{}, "", errors.New(resp.ErrorDescription)
+		}
+		return oauthToken{}, "", errors.New(resp.Error)
+	}
+
+	t := oauthToken{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken}
+	if resp.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	// Some providers only send a new refresh token when it changed
+	if t.RefreshToken == "" {
+		t.RefreshToken = form.Get("refresh_token")
+	}
+	return t, "", nil
+}
+
+// SetToken makes t the token for requests and saves it for the next launch.
+func SetToken(t oauthToken) error {
+	tokenMu.Lock()
+	token = &t
+	tokenMu.Unlock()
+
+	data, err := json.Marshal(t)
+	if err == nil {
+		data, err = SealBytes(data)
+	}
+	if err == nil {
+		err = os.MkdirAll(configDir(), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(tokenPath(), data, 0o600)
+	}
+	return err
+}
+
+// CurrentToken returns the saved token, refreshed when it has expired.
+func CurrentToken() (oauthToken, error) {
+	tokenMu.Lock()
+	if token == nil {
+		data, err := os.ReadFile(tokenPath())
+		if err == nil {
+			data, err = OpenBytes(data)
+		}
+		if err == nil {
+			token = &oauthToken{}
+			err = json.Unmarshal(data, token)
+		}
+		if err != nil {
+			token = nil
+			tokenMu.Unlock()
+			return oauthToken{}, errors.New("not signed in, run /login")
+		}
+	}
+	t := *token
+	tokenMu.Unlock()
+
+	if !t.Expired() {
+		return t, nil
+	}
+	if t.RefreshToken == "" {
+		return t, errors.New("sign-in expired, run /login")
+	}
+
+	refreshed, _, err := exchangeToken(url.Values{
+		"client_id":     {cfg.OAuth.ClientID},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.RefreshToken},
+	})
+	if err != nil {
+		return t, fmt.Errorf("refreshing sign-in: %w, run /login", err)
+	}
+	return refreshed, SetToken(refreshed)
+}
+
+// tokenTransport authorizes API requests with the OAuth token.
+type tokenTransport struct {
+	transport http.RoundTripper
+}
+
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	current, err := CurrentToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+current.AccessToken)
+	return t.transport.RoundTrip(req)
+}
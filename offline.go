@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const offlineRetryInterval = 5 * time.Second
+
+// IsOffline reports whether err means the endpoint could not be reached at
+// all, as opposed to the API answering with an error. Errors of requests that
+// skip ClassifyError, like embeddings and the warmup, are still net.Errors.
+func IsOffline(err error) bool {
+	var classified *NetworkError
+	var netErr net.Error
+	return errors.As(err, &classified) || errors.As(err, &netErr)
+}
+
+// GoOffline takes back the message whose request could not reach the
+// endpoint and queues it to be sent again once the endpoint is reachable.
+func (m *model) GoOffline() tea.Cmd {
+	if message, ok := DropExchange(m); ok {
+		m.queue = append([]string{message}, m.queue...)
+	}
+
+	wasOffline := m.offline
+	m.SetOffline(true)
+	m.header.requestDone = true
+	m.header.requestSuccess = false
+
+	if !wasOffline {
+		AddNotice(m, "Offline, will retry and send queued messages once the endpoint is back")
+	}
+	UpdateViewport(m)
+
+	return ProbeCmd()
+}
+
+func (m *model) SetOffline(offline bool) {
+	m.offline = offline
+	m.header.offline = offline
+}
+
+// ProbeCmd checks the endpoint again after a pause.
+func ProbeCmd() tea.Cmd {
+	return tea.Tick(offlineRetryInterval, func(time.Time) tea.Msg {
+		return GetStatusCmd()()
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const pullBarWidth = 30
+
+// ollamaModel is a locally pulled model as listed by /api/tags.
+type ollamaModel struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Details struct {
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// ollamaModelsMsg lists the pulled models. With want set it answers /model
+// <want>, which switches to the model or offers to pull it. Quiet refreshes
+// only update the palette's list.
+type ollamaModelsMsg struct {
+	models []ollamaModel
+	want   string
+	quiet  bool
+	err    error
+}
+
+// pullStartMsg starts pulling a model once confirmed.
+type pullStartMsg struct {
+	model string
+}
+
+// ollamaPull is a running pull, shown in place of the status bar.
+type ollamaPull struct {
+	model     string
+	status    string
+	completed int64
+	total     int64
+	updates   chan pullProgressMsg
+}
+
+type pullProgressMsg struct {
+	Status    string `json:"status"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+
+	done bool
+	err  error
+}
+
+// Pulled models, refreshed at startup and with /model, for the palette.
+var ollamaModels []ollamaModel
+
+func usingOllama() bool {
+	return cfg.Provider == providerOllama
+}
+
+// RefreshOllamaModelsCmd updates the list of pulled models for the palette.
+func RefreshOllamaModelsCmd() tea.Cmd {
+	if !usingOllama() {
+		return nil
+	}
+
+	list := OllamaModelsCmd("")
+	return func() tea.Msg {
+		msg := list().(ollamaModelsMsg)
+		msg.quiet = true
+		return msg
+	}
+}
+
+// OllamaModelsCmd lists the pulled models. want is the model /model asked
+// for, if any.
+func OllamaModelsCmd(want string) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := http.Get(serverRoot() + "/api/tags")
+		if err != nil {
+			return ollamaModelsMsg{want: want, err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return ollamaModelsMsg{want: want, err: fmt.Errorf("/api/tags returned %s", resp.Status)}
+		}
+
+		var tags struct {
+			Models []ollamaModel `json:"models"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&tags)
+		return ollamaModelsMsg{models: tags.Models, want: want, err: err}
+	}
+}
+
+func HandleOllamaModels(m *model, msg ollamaModelsMsg) tea.Cmd {
+	if msg.err == nil {
+		ollamaModels = msg.models
+	}
+
+	switch {
+	case msg.quiet:
+	case msg.err != nil && msg.want != "":
+		AddNotice(m, "Switched to "+msg.want+", could not check that it is pulled: "+msg.err.Error())
+		return SwitchModel(m, msg.want)
+	case msg.err != nil:
+		AddNotice(m, "Could not list Ollama models: "+msg.err.Error())
+	case msg.want == "":
+		AddNotice(m, OllamaModelList(msg.models))
+	case HasOllamaModel(msg.models, msg.want):
+		AddNotice(m, "Switched to "+msg.want)
+		return SwitchModel(m, msg.want)
+	default:
+		m.modal = PullPrompt(msg.want)
+		m.confirmAction = func() tea.Msg { return pullStartMsg{msg.want} }
+	}
+	return nil
+}
+
+func (o ollamaModel) Summary() string {
+	parts := []string{formatSize(o.Size)}
+	if o.Details.ParameterSize != "" {
+		parts = append(parts, o.Details.ParameterSize)
+	}
+	if o.Details.QuantizationLevel != "" {
+		parts = append(parts, o.Details.QuantizationLevel)
+	}
+	return strings.Join(parts, " ")
+}
+
+// OllamaModelList is the /model answer for Ollama, with size and
+// quantization of each pulled model.
+func OllamaModelList(models []ollamaModel) string {
+	if len(models) == 0 {
+		return "Model: " + modelName + "\nNo models pulled yet, /model <name> pulls one"
+	}
+
+	var b strings.Builder
+	b.WriteString("Model: " + modelName + "\nPulled:")
+	for _, o := range models {
+		fmt.Fprintf(&b, "\n  %-32s %s", o.Name, o.Summary())
+	}
+	return b.String()
+}
+
+// HasOllamaModel matches names with or without the implied :latest tag.
+func HasOllamaModel(models []ollamaModel, name string) bool {
+	return slices.ContainsFunc(models, func(o ollamaModel) bool {
+		return o.Name == name || o.Name == name+":latest"
+	})
+}
+
+func PullPrompt(name string) string {
+	return fmt.Sprintf("%s is not pulled yet.\n\nPress y to pull it from the Ollama library, any other key to cancel.", name)
+}
+
+// StartPull streams the download of a model, one pullProgressMsg per update.
+func (m *model) StartPull(name string) tea.Cmd {
+	updates := make(chan pullProgressMsg)
+	m.pull = &ollamaPull{model: name, status: "starting", updates: updates}
+
+	go func() {
+		defer close(updates)
+
+		body, _ := json.Marshal(map[string]any{"model": name, "stream": true})
+		resp, err := http.Post(serverRoot()+"/api/pull", "application/json", bytes.NewReader(body))
+		if err != nil {
+			updates <- pullProgressMsg{done: true, err: err}
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			updates <- pullProgressMsg{done: true, err: fmt.Errorf("/api/pull returned %s", resp.Status)}
+			return
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var progress pullProgressMsg
+			if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+				continue
+			}
+			if progress.Error != "" {
+				updates <- pullProgressMsg{done: true, err: errors.New(progress.Error)}
+				return
+			}
+			updates <- progress
+		}
+		updates <- pullProgressMsg{done: true, err: scanner.Err()}
+	}()
+
+	return m.pull.Wait()
+}
+
+// Wait receives the next update of the pull.
+func (p *ollamaPull) Wait() tea.Cmd {
+	return func() tea.Msg {
+		return <-p.updates
+	}
+}
+
+// HandlePullProgress records an update and waits for the next until the pull
+// is done, then switches to the model.
+func HandlePullProgress(m *model, msg pullProgressMsg) tea.Cmd {
+	if m.pull == nil {
+		return nil
+	}
+
+	if !msg.done {
+		m.pull.status = msg.Status
+		if msg.Total > 0 {
+			m.pull.completed, m.pull.total = msg.Completed, msg.Total
+		}
+		return m.pull.Wait()
+	}
+
+	name := m.pull.model
+	m.pull = nil
+	if msg.err != nil {
+		AddNotice(m, "Could not pull "+name+": "+msg.err.Error())
+		return nil
+	}
+
+	AddNotice(m, "Pulled "+name+" and switched to it")
+	return tea.Batch(SwitchModel(m, name), RefreshOllamaModelsCmd())
+}
+
+func (p ollamaPull) View() string {
+	style := lipgloss.NewStyle().Foreground(Color(statusBarColor)).PaddingLeft(1)
+	if p.total == 0 {
+		return style.Render(fmt.Sprintf("Pulling %s: %s", p.model, p.status))
+	}
+
+	filled := int(float64(pullBarWidth) * float64(p.completed) / float64(p.total))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", pullBarWidth-filled)
+	return style.Render(fmt.Sprintf("Pulling %s %s %3.0f%% %s / %s",
+		p.model, bar, 100*float64(p.completed)/float64(p.total), formatSize(p.completed), formatSize(p.total)))
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.0f MB", float64(bytes)/(1<<20))
+	default:
+		return fmt.Sprintf("%d KB", bytes/1024)
+	}
+}
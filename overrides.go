@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// requestOverrides are parameters set for a single request with "!name=value"
+// words ahead of the message, e.g. "!temp=0.2 !model=gpt-4o explain X". They
+// are stripped from the message and kept with the session to label the
+// prompt.
+type requestOverrides struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+// Override names with their aliases.
+var overrideNames = map[string]string{
+	"model":       "model",
+	"temp":        "temperature",
+	"temperature": "temperature",
+	"top_p":       "top_p",
+	"max_tokens":  "max_tokens",
+	"seed":        "seed",
+}
+
+// ParseOverrides splits the leading overrides off message.
+func ParseOverrides(message string) (requestOverrides, string, error) {
+	var o requestOverrides
+	rest := strings.TrimLeft(message, " ")
+
+	for strings.HasPrefix(rest, "!") {
+		word, after, _ := strings.Cut(rest, " ")
+		name, value, ok := strings.Cut(word[1:], "=")
+		if !ok {
+			break
+		}
+		if err := o.Set(name, value); err != nil {
+			return o, message, err
+		}
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	return o, rest, nil
+}
+
+// Set parses one override.
+func (o *requestOverrides) Set(name, value string) error {
+	float := func(low, high float64) (*float32, error) {
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil || f < low || f > high {
+			return nil, fmt.Errorf("!%s must be a number from %g to %g", name, low, high)
+		}
+		v := float32(f)
+		return &v, nil
+	}
+
+	var err error
+	switch overrideNames[name] {
+	case "model":
+		if value == "" {
+			return fmt.Errorf("!model needs a model name")
+		}
+		o.Model = value
+	case "temperature":
+		o.Temperature, err = float(0, 2)
+	case "top_p":
+		o.TopP, err = float(0, 1)
+	case "max_tokens":
+		o.MaxTokens, err = strconv.Atoi(value)
+		if err != nil || o.MaxTokens < 1 {
+			err = fmt.Errorf("!max_tokens must be a positive number")
+		}
+	case "seed":
+		var seed int
+		if seed, err = strconv.Atoi(value); err != nil {
+			err = fmt.Errorf("!seed must be a number")
+		}
+		o.Seed = &seed
+	default:
+		err = fmt.Errorf("unknown override !%s, use !model, !temp, !top_p, !max_tokens or !seed", name)
+	}
+	return err
+}
+
+func (o requestOverrides) IsZero() bool {
+	return o == requestOverrides{}
+}
+
+// Apply sets the overridden parameters on req. The model is picked by the
+// caller.
+func (o requestOverrides) Apply(req *openai.ChatCompletionRequest) {
+	if o.Temperature != nil {
+		req.Temperature = nonZero(*o.Temperature)
+	}
+	if o.TopP != nil {
+		req.TopP = nonZero(*o.TopP)
+	}
+	if o.MaxTokens > 0 {
+		req.MaxTokens = o.MaxTokens
+	}
+	if o.Seed != nil {
+		req.Seed = o.Seed
+	}
+}
+
+// nonZero keeps 0 from being dropped as unset when the request is encoded.
+func nonZero(f float32) float32 {
+	if f == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return f
+}
+
+// Badge lists the overrides for the prompt's label.
+func (o requestOverrides) Badge() string {
+	var parts []string
+	if o.Model != "" {
+		parts = append(parts, "model "+o.Model)
+	}
+	if o.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temp %g", *o.Temperature))
+	}
+	if o.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p %g", *o.TopP))
+	}
+	if o.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max_tokens %d", o.MaxTokens))
+	}
+	if o.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed %d", *o.Seed))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// RecordOverrides keeps the overrides of the prompt at index i, or forgets
+// earlier ones at that index.
+func (s *session) RecordOverrides(i int, o requestOverrides) {
+	if o.IsZero() {
+		delete(s.PromptOverrides, i)
+		return
+	}
+	if s.PromptOverrides == nil {
+		s.PromptOverrides = map[int]requestOverrides{}
+	}
+	s.PromptOverrides[i] = o
+}
+
+// RenderSentPrompt renders the prompt at index i of s, labeled with the
+// overrides it was sent with.
+func (m model) RenderSentPrompt(s session, message string, i int) string {
+	rendered := m.RenderPrompt(message)
+
+	o, ok := s.PromptOverrides[i]
+	if !ok {
+		return rendered
+	}
+
+	indent := strings.Repeat(" ", lipgloss.Width(PromptPrefix()))
+	return rendered + "\n" + m.noticeStyle.Render(indent+o.Badge())
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const paletteRows = 12
+
+// The palette lists every action, key bindings, slash commands and saved
+// sessions, filtered by a fuzzy search as it is typed.
+type paletteModel struct {
+	visible  bool
+	query    string
+	selected int
+	actions  []paletteAction
+}
+
+type paletteAction struct {
+	title string
+	hint  string
+	run   func(m *model) tea.Cmd
+}
+
+type paletteKeyMap struct {
+	Up   key.Binding
+	Down key.Binding
+	Run  key.Binding
+	Exit key.Binding
+}
+
+var paletteKeys = paletteKeyMap{
+	Up:   key.NewBinding(key.WithKeys("up", "ctrl+k")),
+	Down: key.NewBinding(key.WithKeys("down", "ctrl+j")),
+	Run:  key.NewBinding(key.WithKeys("enter")),
+	Exit: key.NewBinding(key.WithKeys("esc")),
+}
+
+// PaletteActions collects the actions offered in the palette. Commands that
+// take arguments are put into the input to be completed there.
+func PaletteActions(m *model) []paletteAction {
+	bound := func(title string, binding key.Binding, run func(m *model) tea.Cmd) paletteAction {
+		return paletteAction{title: title, hint: binding.Help().Key, run: run}
+	}
+
+	actions := []paletteAction{
+		bound("New tab", m.keys.NewTab, func(m *model) tea.Cmd { return m.NewTab() }),
+		bound("Next tab", m.keys.NextTab, func(m *model) tea.Cmd { return m.SwitchTab(1) }),
+		bound("Previous tab", m.keys.PrevTab, func(m *model) tea.Cmd { return m.SwitchTab(-1) }),
+		bound("Copy last reply", m.keys.Copy, func(m *model) tea.Cmd { CopyLastReply(m); return nil }),
+		bound("Cancel request", m.keys.Cancel, func(m *model) tea.Cmd { CancelRequest(m); return nil }),
+		bound("Undo send", m.keys.Undo, func(m *model) tea.Cmd { UndoSend(m); return nil }),
+		bound("Select message", m.keys.Select, func(m *model) tea.Cmd { m.StartSelection(); return nil }),
+		bound("Jump to message", m.keys.Jump, func(m *model) tea.Cmd { m.StartJump(); return nil }),
+		bound("Toggle sidebar", m.keys.Sidebar, func(m *model) tea.Cmd { ToggleSidebar(m); return nil }),
+		bound("Toggle inspector", m.keys.Inspector, func(m *model) tea.Cmd {
+			m.inspector.visible = !m.inspector.visible
+			return nil
+		}),
+		bound("Raw text view", m.keys.RawText, func(m *model) tea.Cmd { return m.ToggleRawText() }),
+		bound("Toggle help", m.keys.Help, func(m *model) tea.Cmd {
+			m.help.ShowAll = !m.help.ShowAll
+			return nil
+		}),
+		bound("Quit", m.keys.Quit, Quit),
+	}
+
+	for _, name := range commandNames() {
+		c := commands[name]
+		actions = append(actions, paletteAction{
+			title: c.usage,
+			hint:  c.description,
+			run: func(m *model) tea.Cmd {
+				if c.usage != commandPrefix+c.name {
+					m.textarea.SetValue(commandPrefix + c.name + " ")
+					m.textarea.CursorEnd()
+					m.SetFocus(focusInput)
+					return nil
+				}
+				return RunCommand(m, commandPrefix+c.name)
+			},
+		})
+	}
+
+	for _, o := range ollamaModels {
+		actions = append(actions, paletteAction{
+			title: "Switch model: " + o.Name,
+			hint:  o.Summary(),
+			run: func(m *model) tea.Cmd {
+				AddNotice(m, "Switched to "+o.Name)
+				return SwitchModel(m, o.Name)
+			},
+		})
+	}
+
+	for _, s := range ListSessions() {
+		item := sidebarItem{kind: sidebarSession, sessionID: s.ID}
+		title := "Open session: " + s.Title
+		if len(s.Tags) > 0 {
+			title += " " + FormatTags(s.Tags)
+		}
+		actions = append(actions, paletteAction{
+			title: title,
+			hint:  s.Updated.Format("Jan 02"),
+			run: func(m *model) tea.Cmd {
+				return func() tea.Msg { return sidebarSelectMsg{item} }
+			},
+		})
+	}
+
+	return actions
+}
+
+func (m *model) OpenPalette() {
+	m.palette = paletteModel{visible: true, actions: PaletteActions(m)}
+}
+
+// Matches returns the actions matching the query, best first.
+func (p paletteModel) Matches() []paletteAction {
+	type match struct {
+		action paletteAction
+		score  int
+	}
+
+	var matches []match
+	for _, action := range p.actions {
+		if score, ok := FuzzyScore(p.query, action.title); ok {
+			matches = append(matches, match{action, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	actions := make([]paletteAction, len(matches))
+	for i, match := range matches {
+		actions[i] = match.action
+	}
+	return actions
+}
+
+// FuzzyScore reports whether the letters of query appear in order in text,
+// scoring runs of consecutive letters and letters starting a word higher.
+func FuzzyScore(query, text string) (int, bool) {
+	query = strings.ToLower(strings.ReplaceAll(query, " ", ""))
+	runes := []rune(strings.ToLower(text))
+
+	score, pos, run := 0, 0, 0
+	for _, q := range query {
+		found := false
+		for ; pos < len(runes); pos++ {
+			if runes[pos] != q {
+				run = 0
+				continue
+			}
+
+			run++
+			score += run
+			if pos == 0 || !unicode.IsLetter(runes[pos-1]) {
+				score += 3
+			}
+			pos++
+			found = true
+			break
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	return score, true
+}
+
+// HandlePaletteKey filters and runs palette actions. The palette binding
+// closes it again, like esc.
+func HandlePaletteKey(m *model, msg tea.KeyMsg) tea.Cmd {
+	matches := m.palette.Matches()
+
+	switch {
+	case key.Matches(msg, paletteKeys.Exit, m.keys.Palette):
+		m.palette.visible = false
+	case key.Matches(msg, paletteKeys.Up):
+		m.palette.selected = max(m.palette.selected-1, 0)
+	case key.Matches(msg, paletteKeys.Down):
+		m.palette.selected = min(m.palette.selected+1, max(len(matches)-1, 0))
+	case key.Matches(msg, paletteKeys.Run):
+		m.palette.visible = false
+		if len(matches) == 0 {
+			return nil
+		}
+		return matches[m.palette.selected].run(m)
+	case msg.Type == tea.KeyBackspace:
+		if runes := []rune(m.palette.query); len(runes) > 0 {
+			m.palette.query = string(runes[:len(runes)-1])
+			m.palette.selected = 0
+		}
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		m.palette.query += string(msg.Runes)
+		m.palette.selected = 0
+	}
+
+	return nil
+}
+
+func (p paletteModel) View() string {
+	selected := lipgloss.NewStyle().Foreground(Color(focusColor))
+	hint := lipgloss.NewStyle().Foreground(Color(noticeColor))
+
+	lines := []string{"> " + p.query + "▏", ""}
+
+	matches := p.Matches()
+	offset := max(p.selected-paletteRows+1, 0)
+	for i := offset; i < len(matches) && i < offset+paletteRows; i++ {
+		action := matches[i]
+		title := truncate(action.title, viewportTextWidth/2)
+		line := title + strings.Repeat(" ", viewportTextWidth/2+2-lipgloss.Width(title)) +
+			hint.Render(truncate(action.hint, viewportTextWidth/2-4))
+
+		if i == p.selected {
+			line = selected.Render("▸ ") + line
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	if len(matches) == 0 {
+		lines = append(lines, hint.Render("  No matching actions"))
+	}
+
+	width, height, maxHeight := OverlaySize()
+	return lipgloss.NewStyle().
+		Border(Border()).
+		BorderForeground(Color(focusColor)).
+		Padding(0, 1).
+		Width(width).
+		Height(height).
+		MaxHeight(maxHeight).
+		Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Request parameters from the config, changed for the running session with
+// /set. They apply to every request, unlike the overrides of one message.
+
+const (
+	// The API takes at most this many stop sequences
+	maxStopSequences = 4
+
+	maxLogitBias = 100
+)
+
+// Settable parameters, for /set and its completion.
+var parameterNames = []string{"stop", "logit_bias", "seed"}
+
+func init() {
+	registerCommand(command{
+		name:        "set",
+		usage:       "/set [stop <seq>... | logit_bias <token>=<bias>... | seed <n>]",
+		description: "Show or set request parameters for this session",
+		complete: func(prefix string) []string {
+			var names []string
+			for _, name := range parameterNames {
+				if strings.HasPrefix(name, prefix) {
+					names = append(names, name)
+				}
+			}
+			return names
+		},
+		run: func(m *model, args string) tea.Cmd {
+			name, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+			if name == "" {
+				AddNotice(m, ParameterSummary())
+				return nil
+			}
+			if err := SetParameter(name, value); err != nil {
+				AddNotice(m, err.Error())
+				return nil
+			}
+			AddNotice(m, ParameterSummary())
+			return nil
+		},
+	})
+}
+
+// SetParameter changes a parameter from the words of /set. No value clears
+// it.
+func SetParameter(name, value string) error {
+	words := strings.Fields(value)
+
+	switch name {
+	case "stop":
+		if len(words) > maxStopSequences {
+			return fmt.Errorf("at most %d stop sequences", maxStopSequences)
+		}
+		stop := make([]string, len(words))
+		for i, word := range words {
+			// Escapes like \n stand for the characters
+			seq, err := strconv.Unquote(`"` + strings.ReplaceAll(word, `"`, `\"`) + `"`)
+			if err != nil {
+				return fmt.Errorf("invalid stop sequence %s", word)
+			}
+			stop[i] = seq
+		}
+		cfg.Stop = stop
+
+	case "logit_bias":
+		bias := map[string]int{}
+		for _, word := range words {
+			token, value, ok := strings.Cut(word, "=")
+			if _, err := strconv.Atoi(token); err != nil || !ok {
+				return fmt.Errorf("expected <token id>=<bias>, got %s", word)
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < -maxLogitBias || n > maxLogitBias {
+				return fmt.Errorf("the bias of token %s must be from %d to %d", token, -maxLogitBias, maxLogitBias)
+			}
+			bias[token] = n
+		}
+		cfg.LogitBias = bias
+
+	case "seed":
+		if len(words) == 0 {
+			cfg.Seed = nil
+			return nil
+		}
+		seed, err := strconv.Atoi(words[0])
+		if err != nil || len(words) > 1 {
+			return fmt.Errorf("the seed must be a number")
+		}
+		cfg.Seed = &seed
+
+	default:
+		return fmt.Errorf("unknown parameter %s, use %s", name, strings.Join(parameterNames, " or "))
+	}
+	return nil
+}
+
+// ParameterSummary lists the parameters that are set.
+func ParameterSummary() string {
+	stop := "none"
+	if len(cfg.Stop) > 0 {
+		quoted := make([]string, len(cfg.Stop))
+		for i, seq := range cfg.Stop {
+			quoted[i] = strconv.Quote(seq)
+		}
+		stop = strings.Join(quoted, " ")
+	}
+
+	bias := "none"
+	if len(cfg.LogitBias) > 0 {
+		tokens := make([]string, 0, len(cfg.LogitBias))
+		for token := range cfg.LogitBias {
+			tokens = append(tokens, token)
+		}
+		sort.Strings(tokens)
+		for i, token := range tokens {
+			tokens[i] = fmt.Sprintf("%s=%d", token, cfg.LogitBias[token])
+		}
+		bias = strings.Join(tokens, " ")
+	}
+
+	seed := "none"
+	if cfg.Seed != nil {
+		seed = strconv.Itoa(*cfg.Seed)
+	}
+
+	return fmt.Sprintf("stop: %s\nlogit_bias: %s\nseed: %s", stop, bias, seed)
+}
+
+// RequestParameters returns a function setting the configured parameters on
+// a request. They are copied when it is made, so /set and config reloads do
+// not change requests already on their way.
+func RequestParameters() func(req *openai.ChatCompletionRequest) {
+	stop := slices.Clone(cfg.Stop)
+	bias := maps.Clone(cfg.LogitBias)
+	var seed *int
+	if cfg.Seed != nil {
+		n := *cfg.Seed
+		seed = &n
+	}
+
+	return func(req *openai.ChatCompletionRequest) {
+		if len(stop) > 0 {
+			req.Stop = stop
+		}
+		if len(bias) > 0 {
+			req.LogitBias = bias
+		}
+		if seed != nil {
+			req.Seed = seed
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Popup size, as a percentage of the tmux window or in cells. Narrow popups
+// get the compact layout.
+const (
+	popupWidth  = "80%"
+	popupHeight = "80%"
+)
+
+// RunPopup opens bubblechat in a tmux popup over the current pane, resuming
+// the last session, for `bubblechat popup`. It returns when the popup is
+// closed, so it can be bound to a tmux key:
+//
+//	bind-key C-g run-shell -b "bubblechat popup"
+func RunPopup(args []string) error {
+	flags := flag.NewFlagSet("popup", flag.ContinueOnError)
+	width := flags.String("w", popupWidth, "popup width, in cells or a percentage")
+	height := flags.String("h", popupHeight, "popup height, in cells or a percentage")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if os.Getenv("TMUX") == "" {
+		return errors.New("bubblechat popup runs inside tmux, start bubblechat directly instead")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// tmux runs the popup's command through the shell. The remaining
+	// arguments are passed on, e.g. --no-color
+	words := []string{shellQuote(self), "--continue"}
+	for _, arg := range flags.Args() {
+		words = append(words, shellQuote(arg))
+	}
+
+	cmd := exec.Command("tmux", "display-popup", "-E",
+		"-w", *width, "-h", *height,
+		"-d", "#{pane_current_path}",
+		strings.Join(words, " "))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
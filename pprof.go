@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// With --pprof the profiles of net/http/pprof are served while the UI runs,
+// e.g. `go tool pprof http://localhost:6060/debug/pprof/profile?seconds=30`
+// while scrolling a long transcript.
+
+var pprofAddr string
+
+// StartPprof serves the profiling endpoints on addr, :6060 for all
+// interfaces or localhost:6060 for this machine only.
+func StartPprof(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	log.Printf("Serving pprof on http://%s/debug/pprof/", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("Error serving pprof: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A prompt given on the command line, `bubblechat -m "explain this error"
+// -f err.log`, starts in the input for follow-ups or is sent right away with
+// --send. Files are mentioned with @path, so they are attached when the
+// prompt is sent like mentions typed in the input.
+var (
+	prefillMessage string
+	prefillFiles   []string
+	prefillSend    bool
+)
+
+type prefillSendMsg struct {
+	message string
+}
+
+// AddPrefillFile is the -f flag, which can be repeated.
+func AddPrefillFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a file", path)
+	}
+	if strings.ContainsAny(path, " \t") {
+		return fmt.Errorf("%s: paths with spaces can not be mentioned", path)
+	}
+	prefillFiles = append(prefillFiles, path)
+	return nil
+}
+
+// PrefillText is the prompt from -m and -f, empty without them.
+func PrefillText() string {
+	words := []string{}
+	if message := strings.TrimSpace(prefillMessage); message != "" {
+		words = append(words, message)
+	}
+	for _, path := range prefillFiles {
+		words = append(words, "@"+path)
+	}
+	return strings.Join(words, " ")
+}
+
+// Prefill puts the command line prompt in the input, replacing the saved
+// draft. A prompt sent right away skips the input and its length limit.
+func (m *model) Prefill() {
+	text := PrefillText()
+	if text == "" || prefillSend {
+		return
+	}
+
+	m.textarea.SetValue(text)
+	if m.textarea.Value() != text {
+		AddNotice(m, fmt.Sprintf("The prompt was cut to the input's %d characters, send it with --send to keep all of it", m.textarea.CharLimit))
+	}
+}
+
+// PrefillSendCmd sends the command line prompt once the UI runs, with --send.
+func PrefillSendCmd() tea.Cmd {
+	text := PrefillText()
+	if text == "" || !prefillSend {
+		return nil
+	}
+	return func() tea.Msg {
+		return prefillSendMsg{text}
+	}
+}
+
+// HandlePrefillSend sends the command line prompt, or leaves it in the input
+// while a dialog, like the restore prompt, asks for an answer first.
+func (m *model) HandlePrefillSend(msg prefillSendMsg) tea.Cmd {
+	if m.modal != "" {
+		m.textarea.SetValue(msg.message)
+		return nil
+	}
+	return RequestSend(m, msg.message)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/termenv"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// /proofread has the model correct a text and shows the corrections as a
+// word diff against it. Like translations, proofreading happens outside the
+// conversation. The copy key copies the corrected text until the next
+// message is sent.
+
+const (
+	proofreadSystemPrompt = "You are a proofreader. Fix the spelling, grammar and punctuation of the text the user sends, " +
+		"changing as little as possible and keeping its language, wording, tone and formatting. " +
+		"Reply with the corrected text only."
+
+	// Longer texts are shown corrected without a diff, which takes time and
+	// memory by the product of their lengths
+	maxDiffWords = 2000
+
+	diffDeleteColor = errorColor
+	diffInsertColor = responseColor
+)
+
+// Words and the spaces between them, both kept by the diff.
+var diffToken = regexp.MustCompile(`\s+|\S+`)
+
+type proofreadMsg struct {
+	tab       int
+	original  string
+	corrected string
+	latency   time.Duration
+	err       error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "proofread",
+		usage:       "/proofread <text>",
+		description: "Correct the grammar of a text and show the changes",
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				AddNotice(m, "Usage: /proofread <text>")
+				return nil
+			}
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the current reply before proofreading")
+				return nil
+			}
+			return Proofread(m, args)
+		},
+	})
+}
+
+// Proofread shows a placeholder for the corrections of text and requests
+// them.
+func Proofread(m *model, text string) tea.Cmd {
+	m.messages = append(m.messages, m.PlaceholderMessage())
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+
+	m.SetRequest(requestSending)
+	requestCtx, cancel := context.WithCancel(ctx)
+	m.cancelRequest = cancel
+
+	return tea.Batch(m.StartSpinner(), ProofreadCmd(requestCtx, m.ActiveTabID(), text))
+}
+
+func ProofreadCmd(ctx context.Context, tab int, text string) tea.Cmd {
+	model := modelName
+
+	return func() tea.Msg {
+		req := openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: proofreadSystemPrompt},
+				UserMessage(text),
+			},
+		}
+
+		start := time.Now()
+		resp, err := callChat(ctx, req)
+		if err == nil && len(resp.Choices) == 0 {
+			err = errNoChoices
+		}
+		msg := proofreadMsg{tab: tab, original: text, latency: time.Since(start), err: err}
+		if err == nil {
+			msg.corrected = strings.TrimSpace(resp.Choices[0].Message.Content)
+		}
+		return msg
+	}
+}
+
+// HandleProofread shows the corrections and keeps the corrected text for the
+// copy key.
+func HandleProofread(m *model, msg proofreadMsg) tea.Cmd {
+	if errors.Is(msg.err, context.Canceled) {
+		return nil
+	}
+
+	var result Message
+	if msg.err == nil {
+		result = m.ProofreadMessage(msg.original, msg.corrected)
+	}
+	if !m.ShowResult(msg.tab, result, msg.err) {
+		return nil
+	}
+	m.header.RecordLatency(msg.latency)
+	if msg.err == nil {
+		m.proofread = msg.corrected
+	}
+	return m.SendQueued()
+}
+
+// RenderProofread renders the word diff from original to corrected, with a
+// note on copying the corrected text.
+func (m model) RenderProofread(original, corrected string) string {
+	prefix := ResponsePrefix()
+	indent := strings.Repeat(" ", lipgloss.Width(prefix))
+
+	note := "No corrections"
+	body := m.responseTextStyle.Render(corrected)
+	if corrected != strings.TrimSpace(original) {
+		note = "Corrected, " + m.keys.Copy.Help().Key + " copies the corrected text"
+		if diff, ok := WordDiff(original, corrected); ok {
+			body = RenderWordDiff(diff, m.responseTextStyle)
+		}
+	}
+
+	body = wordwrap.String(body, messageWidth(prefix))
+	return m.responseStyle.Render(prefix) + body + "\n" + m.noticeStyle.Render(indent+note)
+}
+
+// diffOp is a run of text the two sides share, or that only one has.
+type diffOp struct {
+	kind byte // '=', '-' or '+'
+	text string
+}
+
+// WordDiff compares two texts word by word, from the longest common
+// subsequence of their words and spaces. It fails for texts longer than
+// maxDiffWords.
+func WordDiff(a, b string) ([]diffOp, bool) {
+	x := diffToken.FindAllString(a, -1)
+	y := diffToken.FindAllString(b, -1)
+	if len(x) > maxDiffWords || len(y) > maxDiffWords {
+		return nil, false
+	}
+
+	// lcs[i][j] is the common length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	add := func(kind byte, text string) {
+		if n := len(ops); n > 0 && ops[n-1].kind == kind {
+			ops[n-1].text += text
+			return
+		}
+		ops = append(ops, diffOp{kind, text})
+	}
+
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			add('=', x[i])
+			i++
+			j++
+		case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			add('-', x[i])
+			i++
+		default:
+			add('+', y[j])
+			j++
+		}
+	}
+	return ops, true
+}
+
+// RenderWordDiff shows removed text struck through in red and added text in
+// green. Without colors, and for screen readers, the changes are marked
+// [-removed-] and {+added+} instead.
+func RenderWordDiff(ops []diffOp, style lipgloss.Style) string {
+	deleted := StyleFromColor(diffDeleteColor).Strikethrough(true)
+	inserted := StyleFromColor(diffInsertColor).Bold(true)
+	marked := cfg.Accessible || lipgloss.ColorProfile() == termenv.Ascii
+
+	var b strings.Builder
+	for _, op := range ops {
+		switch {
+		case op.kind == '=':
+			b.WriteString(style.Render(op.text))
+		case marked && op.kind == '-':
+			b.WriteString("[-" + op.text + "-]")
+		case marked:
+			b.WriteString("{+" + op.text + "+}")
+		case op.kind == '-':
+			b.WriteString(deleted.Render(op.text))
+		default:
+			b.WriteString(inserted.Render(op.text))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	providerOpenAI   = "openai"
+	providerLMStudio = "lmstudio"
+	providerLlamaCpp = "llamacpp"
+	providerOllama   = "ollama"
+	providerFake     = "fake"
+
+	// Local servers answer quickly when up, a slow probe means a stuck one
+	healthTimeout = 5 * time.Second
+)
+
+// errModelLoading is a server that is up but still loading its model.
+var errModelLoading = errors.New("the server is still loading the model")
+
+// providerPreset describes an OpenAI compatible server and its quirks.
+type providerPreset struct {
+	name    string
+	baseURL string
+
+	// Local servers take any key, or none
+	noAuth bool
+
+	// Path below the server root checked for status instead of listing
+	// models, when the server has a cheaper health endpoint
+	health string
+
+	// The model is loaded on the first request, which can take a while
+	slowStart bool
+}
+
+var providerPresets = map[string]providerPreset{
+	providerOpenAI: {
+		name:    "OpenAI",
+		baseURL: "https://api.openai.com/v1",
+	},
+	providerLMStudio: {
+		name:      "LM Studio",
+		baseURL:   "http://localhost:1234/v1",
+		noAuth:    true,
+		slowStart: true,
+	},
+	providerLlamaCpp: {
+		name:      "llama.cpp",
+		baseURL:   "http://localhost:8080/v1",
+		noAuth:    true,
+		health:    "/health",
+		slowStart: true,
+	},
+	providerOllama: {
+		name:      "Ollama",
+		baseURL:   "http://localhost:11434/v1",
+		noAuth:    true,
+		health:    "/api/version",
+		slowStart: true,
+	},
+	providerFake: {
+		name:    "Fake",
+		baseURL: "http://fake.invalid/v1",
+		noAuth:  true,
+	},
+}
+
+// provider is the preset in use, OpenAI unless the config picks another.
+var provider = providerPresets[providerOpenAI]
+
+// slowStartNoticed is set once the slow first reply has been explained.
+var slowStartNoticed bool
+
+// LoadProvider picks the configured preset. A base_url overrides the preset's
+// address, with /v1 added for presets that serve the API below it.
+func LoadProvider() error {
+	if cfg.Provider != "" {
+		preset, ok := providerPresets[cfg.Provider]
+		if !ok {
+			return fmt.Errorf("unknown provider %q", cfg.Provider)
+		}
+		provider = preset
+	}
+
+	if cfg.BaseURL != "" {
+		url := strings.TrimSuffix(cfg.BaseURL, "/")
+		if cfg.Provider != "" && !strings.HasSuffix(url, "/v1") {
+			url += "/v1"
+		}
+		provider.baseURL = url
+	}
+	return nil
+}
+
+// serverRoot is the base URL without the API's /v1 path.
+func serverRoot() string {
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// ProbeHealth checks the provider's health endpoint. llama.cpp answers 503
+// until its model is loaded.
+func ProbeHealth() error {
+	resp, err := (&http.Client{Timeout: healthTimeout}).Get(serverRoot() + provider.health)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return errModelLoading
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s returned %s", provider.health, resp.Status)
+	}
+	return nil
+}
+
+// SlowStartNotice explains, once, why the first reply from a local server
+// takes long.
+func SlowStartNotice(m *model) {
+	if !provider.slowStart || slowStartNoticed {
+		return
+	}
+	slowStartNoticed = true
+	AddNotice(m, provider.name+" loads the model on the first request, the first reply can take a while")
+}
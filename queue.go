@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SendQueued sends the oldest message written while a request was pending.
+func (m *model) SendQueued() tea.Cmd {
+	if len(m.queue) == 0 {
+		return nil
+	}
+
+	message := m.queue[0]
+	m.queue = m.queue[1:]
+
+	return RequestSend(m, message)
+}
+
+// DropQueued drops the messages written while a request was pending, when
+// it did not get a reply for them to follow.
+func (m *model) DropQueued() {
+	if len(m.queue) > 0 {
+		AddNotice(m, fmt.Sprintf("Dropped %d queued messages", len(m.queue)))
+		m.queue = nil
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/reflow/wordwrap"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The raw text view shows the transcript as plain text filling the terminal,
+// without borders, prefixes or colors, and releases the mouse so the
+// terminal's own selection copies clean text.
+
+var rawTextClose = key.NewBinding(key.WithKeys("esc", "q"))
+
+// ToggleRawText switches between the raw text view and the normal layout.
+func (m *model) ToggleRawText() tea.Cmd {
+	m.raw = !m.raw
+	if !m.raw {
+		return tea.EnableMouseCellMotion
+	}
+
+	width, height := m.windowWidth, m.windowHeight
+	if width == 0 {
+		width, height = viewportWidth, viewportHeight
+	}
+	m.rawView = viewport.New(width, height-1)
+	m.rawView.KeyMap = ViewportKeyMap(m.keys)
+	m.rawView.SetContent(RawTranscript(chatMessages, width))
+	m.rawView.GotoBottom()
+
+	return tea.DisableMouse
+}
+
+// HandleRawTextKey scrolls the raw text view. Its toggle, esc and q return to
+// the normal layout.
+func HandleRawTextKey(m *model, msg tea.KeyMsg) tea.Cmd {
+	if key.Matches(msg, m.keys.RawText, rawTextClose) {
+		return m.ToggleRawText()
+	}
+
+	var cmd tea.Cmd
+	m.rawView, cmd = m.rawView.Update(msg)
+	return cmd
+}
+
+// RawTranscript is the conversation as plain text, each message under a line
+// naming its author.
+func RawTranscript(history []openai.ChatCompletionMessage, width int) string {
+	var b strings.Builder
+	for _, msg := range history {
+		var author string
+		switch {
+		case msg.Role == openai.ChatMessageRoleUser:
+			author = "User"
+		case msg.Role == openai.ChatMessageRoleAssistant && msg.Content != "":
+			author = "Assistant"
+		default:
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(author + ":\n")
+		b.WriteString(wordwrap.String(msg.Content, width))
+	}
+	return b.String()
+}
+
+func (m model) RawTextView() string {
+	footer := m.noticeStyle.Render("raw text · " + m.keys.RawText.Help().Key + ", esc or q to return")
+	return m.rawView.View() + "\n" + footer
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const recoveryFileName = "recovery.json"
+
+// A snapshot is the state of all open tabs, written when bubblechat ends
+// without the user quitting: a panic, SIGTERM or the terminal closing.
+type snapshot struct {
+	Time   time.Time `json:"time"`
+	Tabs   []session `json:"tabs"`
+	Active int       `json:"active"`
+	Draft  string    `json:"draft"`
+}
+
+type restoreMsg struct {
+	snapshot snapshot
+}
+
+var (
+	stateMu   sync.Mutex
+	lastState snapshot
+)
+
+// recoveryModel wraps the model to remember its state after every update, so
+// it can still be written once the program is gone.
+type recoveryModel struct {
+	model
+}
+
+func (r recoveryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := r.model.Update(msg)
+	m := next.(model)
+	RememberState(m)
+	return recoveryModel{m}, cmd
+}
+
+func recoveryPath() string {
+	return filepath.Join(configDir(), recoveryFileName)
+}
+
+func RememberState(m model) {
+	s := snapshot{Active: m.activeTab, Draft: m.textarea.Value()}
+	for i, t := range m.tabs {
+		tab, history := t.session, t.history
+		if i == m.activeTab {
+			tab, history = currentSession, chatMessages
+		}
+		tab.Messages = history
+		s.Tabs = append(s.Tabs, tab)
+	}
+
+	stateMu.Lock()
+	lastState = s
+	stateMu.Unlock()
+}
+
+// WriteSnapshot stores the last remembered state for the next launch.
+func WriteSnapshot() {
+	stateMu.Lock()
+	s := lastState
+	stateMu.Unlock()
+
+	empty := s.Draft == ""
+	for _, t := range s.Tabs {
+		empty = empty && len(t.Messages) == 0
+	}
+	if empty {
+		return
+	}
+
+	s.Time = time.Now()
+	data, err := json.Marshal(s)
+	if err == nil {
+		data, err = SealBytes(data)
+	}
+	if err == nil {
+		err = os.MkdirAll(configDir(), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(recoveryPath(), data, 0o600)
+	}
+	if err != nil {
+		log.Printf("Error writing recovery snapshot: %v", err)
+	}
+}
+
+// TakeSnapshot reads and removes the snapshot left by the last run.
+func TakeSnapshot() (snapshot, bool) {
+	var s snapshot
+
+	data, err := os.ReadFile(recoveryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return s, false
+	}
+	os.Remove(recoveryPath())
+
+	if err == nil {
+		data, err = OpenBytes(data)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &s)
+	}
+	if err != nil {
+		log.Printf("Error reading recovery snapshot: %v", err)
+		return s, false
+	}
+	return s, len(s.Tabs) > 0
+}
+
+// WatchHangup writes a snapshot when the terminal is closed, which bubbletea
+// does not handle itself.
+func WatchHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		<-hangup
+		WriteSnapshot()
+		os.Exit(1)
+	}()
+}
+
+func RestorePrompt(s snapshot) string {
+	return fmt.Sprintf(
+		"bubblechat did not exit cleanly\n\n%d tabs were open at %s\n\nPress y to restore them, any other key to start fresh.",
+		len(s.Tabs), s.Time.Format("2006-01-02 15:04"),
+	)
+}
+
+func RestoreCmd(s snapshot) tea.Cmd {
+	return func() tea.Msg {
+		return restoreMsg{s}
+	}
+}
+
+// RestoreSnapshot replaces the open tabs with those of a snapshot.
+func (m *model) RestoreSnapshot(s snapshot) {
+	m.tabs = nil
+	for _, tab := range s.Tabs {
+		m.tabs = append(m.tabs, conversation{id: m.nextTabID, session: tab, history: tab.Messages})
+		m.nextTabID++
+	}
+
+	for i := range m.tabs {
+		m.loadTab(i)
+		RebuildTranscript(m)
+		m.stashTab()
+	}
+	m.loadTab(min(s.Active, len(m.tabs)-1))
+
+	m.textarea.SetValue(s.Draft)
+	m.sidebar.Refresh()
+	AddNotice(m, fmt.Sprintf("Restored %d tabs", len(m.tabs)))
+}
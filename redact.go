@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const redactionColor = "#e88388"
+
+type redactionRule struct {
+	label   string
+	pattern *regexp.Regexp
+}
+
+var builtinRedactions = []redactionRule{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"api key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{"github token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`)},
+	{"aws access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws secret", regexp.MustCompile(`(?i)aws_secret_access_key\s*[:=]\s*\S+`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// RedactionRules returns the built-in rules followed by the custom patterns
+// from the config. Invalid patterns are skipped.
+func RedactionRules() []redactionRule {
+	rules := append([]redactionRule{}, builtinRedactions...)
+	for _, pattern := range cfg.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Invalid redaction pattern %q: %v", pattern, err)
+			continue
+		}
+		rules = append(rules, redactionRule{"custom", re})
+	}
+	return rules
+}
+
+// Redact masks secrets and personal data in text. It returns the masked text
+// and how many matches each rule had.
+func Redact(text string) (string, map[string]int) {
+	counts := map[string]int{}
+	for _, rule := range RedactionRules() {
+		text = rule.pattern.ReplaceAllStringFunc(text, func(string) string {
+			counts[rule.label]++
+			return "[REDACTED:" + rule.label + "]"
+		})
+	}
+	return text, counts
+}
+
+// RedactionNotice says what Redact masked, empty when it found nothing.
+func RedactionNotice(counts map[string]int) string {
+	var found []string
+	for label, n := range counts {
+		if n > 0 {
+			found = append(found, fmt.Sprintf("%d %s", n, label))
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	sort.Strings(found)
+	return "Redacted before sending: " + strings.Join(found, ", ")
+}
+
+// RedactionSpans returns the start and end of each span of text that Redact
+// masks, in order and without overlaps.
+func RedactionSpans(text string) [][]int {
+	var spans [][]int
+	for _, rule := range RedactionRules() {
+		spans = append(spans, rule.pattern.FindAllStringIndex(text, -1)...)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var merged [][]int
+	for _, span := range spans {
+		if last := len(merged) - 1; last >= 0 && span[0] < merged[last][1] {
+			merged[last][1] = max(merged[last][1], span[1])
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// HighlightRedactions renders text with style, marking the spans that are
+// redacted when it is sent.
+func HighlightRedactions(text string, style lipgloss.Style) string {
+	if !cfg.Redact {
+		return style.Render(text)
+	}
+	markStyle := lipgloss.NewStyle().Foreground(Color(redactionColor)).Bold(true)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range RedactionSpans(text) {
+		b.WriteString(style.Render(text[last:loc[0]]))
+		b.WriteString(markStyle.Render(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(style.Render(text[last:]))
+
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// The config file is watched with fsnotify. Its directory is watched rather
+// than the file itself, as many editors save by replacing the file, and a
+// change is read once the file has been quiet for configSettleDelay, so a
+// save in several writes is read whole.
+const configSettleDelay = 100 * time.Millisecond
+
+// loadedConfig is the config as last read from the file, before the theme
+// and accessibility changes applied at runtime.
+var loadedConfig Config
+
+type configChangedMsg struct{}
+
+type configReloadMsg struct {
+	config Config
+	err    error
+}
+
+// WatchConfig starts watching the config file. It returns nil, and the
+// config is not reloaded, when its directory cannot be watched.
+func WatchConfig() *fsnotify.Watcher {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Error watching the config: %v", err)
+		return nil
+	}
+	if err := watcher.Add(filepath.Dir(configPath())); err != nil {
+		log.Printf("Error watching the config: %v", err)
+		watcher.Close()
+		return nil
+	}
+	return watcher
+}
+
+// WaitConfigChange waits for the config file to change and settle.
+func WaitConfigChange(watcher *fsnotify.Watcher) tea.Cmd {
+	if watcher == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		var settled <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+				changed := event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0
+				if changed && filepath.Clean(event.Name) == configPath() {
+					settled = time.After(configSettleDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				log.Printf("Error watching the config: %v", err)
+			case <-settled:
+				return configChangedMsg{}
+			}
+		}
+	}
+}
+
+// HandleConfigChange reads the config again and keeps watching.
+func (m *model) HandleConfigChange() tea.Cmd {
+	return tea.Batch(WaitConfigChange(m.configWatcher), func() tea.Msg {
+		config, err := ReadConfig()
+		return configReloadMsg{config, err}
+	})
+}
+
+// ApplyConfig makes a reloaded config current. The theme, keys and aliases
+// are applied right away and settings read on use take effect with the next
+// request. Settings that set up the client or the terminal need a restart,
+// their old values stay in place.
+func (m *model) ApplyConfig(msg configReloadMsg) {
+	if msg.err != nil {
+		AddNotice(m, "Could not reload the config, keeping the current one: "+msg.err.Error())
+		return
+	}
+
+	next, previous := msg.config, loadedConfig
+	if reflect.DeepEqual(next, previous) {
+		return
+	}
+	loadedConfig = next
+
+	var restart []string
+	needsRestart := func(name string, changed bool) {
+		if changed {
+			restart = append(restart, name)
+		}
+	}
+	needsRestart("provider", next.Provider != previous.Provider)
+	needsRestart("base_url", next.BaseURL != previous.BaseURL)
+	needsRestart("api_keys", !reflect.DeepEqual(next.APIKeys, previous.APIKeys))
+	needsRestart("headers", !reflect.DeepEqual(next.Headers, previous.Headers))
+	needsRestart("oauth", next.OAuth != previous.OAuth)
+	needsRestart("encryption", next.Encryption != previous.Encryption)
+	needsRestart("accessible", next.Accessible != previous.Accessible)
+	needsRestart("quiet", next.Quiet != previous.Quiet)
+	needsRestart("icons", next.Icons != previous.Icons)
+	needsRestart("input_mode", next.InputMode != previous.InputMode)
+
+	// Only the settings changed in the file are taken, so those changed at
+	// runtime, with /set, /reseed, /lang or /theme, stay otherwise
+	current := cfg
+	merged, changed, unchanged := reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(next), reflect.ValueOf(previous)
+	for i := range merged.NumField() {
+		if !reflect.DeepEqual(changed.Field(i).Interface(), unchanged.Field(i).Interface()) {
+			merged.Field(i).Set(changed.Field(i))
+		}
+	}
+	cfg.Provider, cfg.BaseURL, cfg.APIKeys, cfg.Headers = current.Provider, current.BaseURL, current.APIKeys, current.Headers
+	cfg.OAuth, cfg.Encryption = current.OAuth, current.Encryption
+	cfg.Accessible, cfg.Icons, cfg.InputMode = current.Accessible, current.Icons, current.InputMode
+	cfg.Quiet = current.Quiet
+
+	if !reflect.DeepEqual(next.Theme, previous.Theme) {
+		if m.request.InFlight() {
+			cfg.Theme = current.Theme
+			restart = append(restart, "theme (while waiting for a reply)")
+		} else {
+			m.SwitchTheme(next.Theme)
+		}
+	}
+
+	if !reflect.DeepEqual(next.Keys, previous.Keys) {
+		m.keys = NewKeyMap(cfg.Keys)
+		m.viewport.KeyMap = ViewportKeyMap(m.keys)
+	}
+	LoadAliases()
+
+	notice := "Reloaded the config"
+	if len(restart) > 0 {
+		notice += ", restart to apply " + strings.Join(restart, ", ")
+	}
+	AddNotice(m, notice)
+}
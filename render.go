@@ -0,0 +1,120 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const autoStyle = "auto"
+
+// NewRenderer builds the markdown renderer wrapping at width. style is empty
+// for no styling, "auto", a standard glamour style name (dark, light, notty,
+// ...) or a path to a JSON stylesheet.
+func NewRenderer(style string, width int) (*glamour.TermRenderer, error) {
+	options := []glamour.TermRendererOption{
+		glamour.WithWordWrap(width),
+		glamour.WithColorProfile(lipgloss.ColorProfile()),
+	}
+
+	switch style {
+	case "":
+	case autoStyle:
+		options = append(options, glamour.WithAutoStyle())
+	default:
+		options = append(options, glamour.WithStylePath(style))
+	}
+
+	return glamour.NewTermRenderer(options...)
+}
+
+// SetRenderWidth rebuilds the renderer when the viewport text width changes.
+func (m *model) SetRenderWidth(width int) {
+	if width == m.renderWidth && m.renderer != nil {
+		return
+	}
+
+	renderer, err := NewRenderer(cfg.GlamourStyle, width)
+	if err != nil {
+		log.Printf("Error loading glamour style %q: %v", cfg.GlamourStyle, err)
+		renderer, _ = NewRenderer("", width)
+	}
+
+	m.renderer = renderer
+	m.renderWidth = width
+	m.renderCache = renderCache{}
+}
+
+func viewportContentWidth(vp viewport.Model) int {
+	return vp.Width - vp.Style.GetHorizontalFrameSize()
+}
+
+// renderCache maps the hash of a styled message to its rendered block, so
+// each message goes through the markdown renderer only once.
+type renderCache map[uint64]string
+
+func hashMessage(message string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(message))
+	return h.Sum64()
+}
+
+// Render returns the rendered block for message, rendering it on a miss.
+func (c renderCache) Render(m *model, message string) string {
+	key := hashMessage(message)
+	if block, ok := c[key]; ok {
+		return block
+	}
+
+	block, err := m.renderer.Render(message)
+	if err != nil {
+		block = message
+	}
+	block = strings.Trim(block, "\n")
+
+	c[key] = block
+	return block
+}
+
+// RenderMessages renders every message and drops cache entries that are no
+// longer displayed, e.g. old spinner frames. The line each message starts on
+// is kept in m.messageOffsets.
+func RenderMessages(m *model) string {
+	used := make(renderCache, len(m.messages))
+	blocks := make([]string, len(m.messages))
+	m.messageOffsets = make([]int, len(m.messages))
+
+	line := 0
+	exchange := 0
+	for i, msg := range m.messages {
+		message := msg.Rendered
+		block := message
+		// Images are drawn over their placeholders as they are
+		if msg.Kind != kindImage {
+			block = m.renderCache.Render(m, message)
+			used[hashMessage(message)] = block
+		}
+
+		if m.IsFolded(msg, block) {
+			block = m.FoldBlock(block)
+		}
+		if m.selecting {
+			block = SelectionGutter(m, block, i == m.selected)
+		}
+		if m.jumping && msg.Kind == kindPrompt {
+			exchange++
+			block = JumpAnchor(m, exchange) + "\n" + block
+		}
+
+		blocks[i] = block
+		m.messageOffsets[i] = line
+		line += strings.Count(block, "\n") + 1
+	}
+	m.renderCache = used
+
+	return strings.Join(blocks, "\n")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// /replay plays a saved session back one message at a time, typing out the
+// replies, for demos, screencasts and reading through long sessions. Not to
+// be confused with --replay, which serves recorded API responses.
+
+const (
+	defaultReplayDelaySeconds = 1
+	defaultReplayTypingSpeed  = 60
+)
+
+// sessionReplay is a replay in progress. blocks are the rendered transcript
+// of the session, shown up to next.
+type sessionReplay struct {
+	id      int
+	session session
+	blocks  []Message
+	next    int
+
+	// The runes of the reply being typed and how many are shown
+	source []rune
+	typed  int
+}
+
+// Each replay has its own id so the ticks of a stopped one are dropped.
+var replayCount int
+
+type replayTickMsg struct {
+	id int
+}
+
+var replayKeys = struct {
+	Skip key.Binding
+	Stop key.Binding
+}{
+	Skip: key.NewBinding(key.WithKeys(" ", "enter")),
+	Stop: key.NewBinding(key.WithKeys("esc")),
+}
+
+func init() {
+	registerCommand(command{
+		name:        "replay",
+		usage:       "/replay [session id]",
+		description: "Play a session back message by message, the current one by default",
+		complete: func(prefix string) []string {
+			var ids []string
+			for _, s := range ListSessions() {
+				if strings.HasPrefix(s.ID, prefix) {
+					ids = append(ids, s.ID)
+				}
+			}
+			return ids
+		},
+		run: func(m *model, args string) tea.Cmd {
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the current reply before replaying")
+				return nil
+			}
+
+			s := currentSession
+			s.Messages = chatMessages
+			if args != "" {
+				var err error
+				if s, err = LoadSession(args); err != nil {
+					AddNotice(m, "Could not open session: "+err.Error())
+					return nil
+				}
+			}
+			return m.StartReplay(s)
+		},
+	})
+}
+
+// StartReplay clears the transcript and plays s back into it.
+func (m *model) StartReplay(s session) tea.Cmd {
+	blocks := m.RenderTranscript(s, s.Messages)
+	if len(blocks) == 0 {
+		AddNotice(m, "Nothing to replay")
+		return nil
+	}
+
+	replayCount++
+	m.replay = &sessionReplay{id: replayCount, session: s, blocks: blocks}
+	m.messages = nil
+	UpdateViewport(m)
+	return replayTick(replayCount, 0)
+}
+
+func replayTick(id int, delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return replayTickMsg{id: id}
+	})
+}
+
+// HandleReplayTick shows the next message, or types more of a reply.
+func HandleReplayTick(m *model, msg replayTickMsg) tea.Cmd {
+	r := m.replay
+	if r == nil || msg.id != r.id {
+		return nil
+	}
+
+	block := r.blocks[r.next]
+	if block.Kind == kindReply && cfg.ReplayTypingSpeed > 0 && Animated() {
+		if r.source == nil {
+			r.source = []rune(block.Content)
+			m.messages = append(m.messages, BlockMessage(""))
+		}
+
+		r.typed = min(r.typed+typingStep(cfg.ReplayTypingSpeed), len(r.source))
+		if r.typed < len(r.source) {
+			m.messages[len(m.messages)-1] = BlockMessage(m.RenderPartialReply(string(r.source[:r.typed])))
+			UpdateViewport(m)
+			m.viewport.GotoBottom()
+			return replayTick(r.id, typingFrameInterval)
+		}
+		m.messages = m.messages[:len(m.messages)-1]
+	}
+
+	m.messages = append(m.messages, block)
+	r.next++
+	r.source, r.typed = nil, 0
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+
+	if r.next == len(r.blocks) {
+		m.FinishReplay("Replay finished")
+		return nil
+	}
+	delay := time.Duration(cfg.ReplayDelaySeconds * float64(time.Second))
+	return replayTick(r.id, delay)
+}
+
+// HandleReplayKey skips typing out the current reply, or stops the replay.
+// Other keys but quitting wait for the replay to end.
+func HandleReplayKey(m *model, msg tea.KeyMsg) tea.Cmd {
+	r := m.replay
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return Quit(m)
+	case key.Matches(msg, replayKeys.Stop):
+		m.FinishReplay("Replay stopped")
+	case key.Matches(msg, replayKeys.Skip) && r.source != nil:
+		r.typed = len(r.source)
+	}
+	return nil
+}
+
+// FinishReplay opens the replayed session in full, so it can be continued.
+func (m *model) FinishReplay(notice string) {
+	s := m.replay.session
+	m.replay = nil
+
+	OpenSession(m, s)
+	m.sidebar.Refresh()
+	AddNotice(m, notice)
+}
+
+// RenderPartialReply renders the start of a reply as it is typed out, to be
+// replaced with the whole reply.
+func (m model) RenderPartialReply(text string) string {
+	prefix := ResponsePrefix()
+	text = wordwrap.String(text, messageWidth(prefix))
+	return m.responseStyle.Render(prefix) + m.responseTextStyle.Render(text)
+}
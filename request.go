@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Each conversation has a request state, moving from idle to sending when a
+// prompt is sent, to typing while the reply is typed out, and on to done,
+// failed or cancelled, from where the next prompt sends again. The state
+// decides whether prompts are queued, whether the last block is the
+// placeholder, what the header shows and which keys the help lists.
+
+type requestState int
+
+const (
+	requestIdle requestState = iota
+	requestSending
+	requestTyping
+	requestDone
+	requestFailed
+	requestCancelled
+)
+
+var requestStateNames = map[requestState]string{
+	requestIdle:      "idle",
+	requestSending:   "sending",
+	requestTyping:    "typing",
+	requestDone:      "done",
+	requestFailed:    "failed",
+	requestCancelled: "cancelled",
+}
+
+// requestTransitions lists the states each state may move to. Any state
+// may also go back to idle, when the conversation is switched or cleared.
+var requestTransitions = map[requestState][]requestState{
+	requestIdle:      {requestSending},
+	requestSending:   {requestTyping, requestDone, requestFailed, requestCancelled},
+	requestTyping:    {requestDone, requestSending},
+	requestDone:      {requestSending},
+	requestFailed:    {requestSending},
+	requestCancelled: {requestSending},
+}
+
+func (s requestState) String() string {
+	return requestStateNames[s]
+}
+
+// InFlight reports whether a request awaits its response, with the
+// placeholder as the last block of the transcript.
+func (s requestState) InFlight() bool {
+	return s == requestSending
+}
+
+// CanBecome reports whether the state may move to next.
+func (s requestState) CanBecome(next requestState) bool {
+	if next == requestIdle || next == s {
+		return true
+	}
+	for _, allowed := range requestTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Set moves the state to next and reports whether it did. Changes the
+// transitions do not allow are logged and refused.
+func (s *requestState) Set(next requestState) bool {
+	if !s.CanBecome(next) {
+		log.Printf("Refused request state change from %s to %s", *s, next)
+		return false
+	}
+	*s = next
+	return true
+}
+
+// HeaderLabel is shown in the header while a request is under way or after
+// it went wrong.
+func (s requestState) HeaderLabel() string {
+	switch s {
+	case requestSending:
+		return "sending…"
+	case requestTyping:
+		return "typing…"
+	case requestFailed:
+		return "failed"
+	case requestCancelled:
+		return "cancelled"
+	}
+	return ""
+}
+
+// resultState is the state a request ends in with err.
+func resultState(err error) requestState {
+	if err != nil {
+		return requestFailed
+	}
+	return requestDone
+}
+
+// SetRequest moves the request state of the active conversation to next.
+func (m *model) SetRequest(next requestState) {
+	m.request.Set(next)
+	m.header.request = m.request
+}
+
+// requestKeyMap lists the keys that apply in a request state in the help:
+// cancel and undo while sending, cancel to show the reply at once while it
+// is typed out, neither otherwise.
+type requestKeyMap struct {
+	keys  keyMap
+	state requestState
+}
+
+func (k requestKeyMap) filter(bindings []key.Binding) []key.Binding {
+	var kept []key.Binding
+	for _, binding := range bindings {
+		switch {
+		case slices.Equal(binding.Keys(), k.keys.Cancel.Keys()):
+			if k.state == requestTyping {
+				binding.SetHelp(binding.Help().Key, "show reply")
+			} else if k.state != requestSending {
+				continue
+			}
+		case slices.Equal(binding.Keys(), k.keys.Undo.Keys()):
+			if k.state != requestSending {
+				continue
+			}
+		}
+		kept = append(kept, binding)
+	}
+	return kept
+}
+
+func (k requestKeyMap) ShortHelp() []key.Binding {
+	return k.filter(k.keys.ShortHelp())
+}
+
+func (k requestKeyMap) FullHelp() [][]key.Binding {
+	var groups [][]key.Binding
+	for _, group := range k.keys.FullHelp() {
+		groups = append(groups, k.filter(group))
+	}
+	return groups
+}
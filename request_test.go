@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequestStateSet(t *testing.T) {
+	tests := []struct {
+		from, to requestState
+		ok       bool
+	}{
+		{requestIdle, requestSending, true},
+		{requestIdle, requestTyping, false},
+		{requestIdle, requestDone, false},
+		{requestSending, requestTyping, true},
+		{requestSending, requestDone, true},
+		{requestSending, requestFailed, true},
+		{requestSending, requestCancelled, true},
+		{requestTyping, requestDone, true},
+		{requestTyping, requestSending, true},
+		{requestTyping, requestFailed, false},
+		{requestDone, requestSending, true},
+		{requestDone, requestTyping, false},
+		{requestFailed, requestSending, true},
+		{requestFailed, requestDone, false},
+		{requestCancelled, requestSending, true},
+		{requestCancelled, requestTyping, false},
+		// Any state may go back to idle or stay where it is
+		{requestTyping, requestIdle, true},
+		{requestFailed, requestIdle, true},
+		{requestDone, requestDone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"→"+tt.to.String(), func(t *testing.T) {
+			state := tt.from
+			if got := state.Set(tt.to); got != tt.ok {
+				t.Errorf("Set = %v, want %v", got, tt.ok)
+			}
+			want := tt.from
+			if tt.ok {
+				want = tt.to
+			}
+			if state != want {
+				t.Errorf("state = %s, want %s", state, want)
+			}
+		})
+	}
+}
+
+func TestResultState(t *testing.T) {
+	if got := resultState(nil); got != requestDone {
+		t.Errorf("resultState(nil) = %s, want done", got)
+	}
+	if got := resultState(errors.New("boom")); got != requestFailed {
+		t.Errorf("resultState(err) = %s, want failed", got)
+	}
+}
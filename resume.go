@@ -0,0 +1,43 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var continueLast bool
+
+func init() {
+	registerCommand(command{
+		name:        "last",
+		usage:       "/last",
+		description: "Reopen the most recently used saved session",
+		run: func(m *model, args string) tea.Cmd {
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the current reply before switching sessions")
+				return nil
+			}
+
+			s, ok := LastSession()
+			if !ok {
+				AddNotice(m, "No saved sessions")
+				return nil
+			}
+
+			OpenSession(m, s)
+			m.sidebar.Refresh()
+			AddNotice(m, "Resumed: "+s.Title)
+			return nil
+		},
+	})
+}
+
+// LastSession returns the most recently updated saved session other than the
+// current one.
+func LastSession() (session, bool) {
+	for _, s := range ListSessions() {
+		if s.ID != currentSession.ID {
+			return s, true
+		}
+	}
+	return session{}, false
+}
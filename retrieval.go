@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	chunkSize         = 2000
+	retrievedChunks   = 6
+	minTermLength     = 3
+	contextPromptHead = "The user has attached the following files. Use them to answer when relevant. " +
+		"Cite the numbered excerpts you draw on by their number, like [1]."
+)
+
+type chunk struct {
+	source string
+	text   string
+	terms  map[string]int
+}
+
+// docIndex is only appended to in Update. Commands get a snapshot of it.
+var docIndex []chunk
+
+// ChunkText splits text into pieces of roughly chunkSize bytes, breaking on
+// line boundaries where possible.
+func ChunkText(source, text string) []chunk {
+	var chunks []chunk
+	for _, piece := range SplitText(text, chunkSize) {
+		chunks = append(chunks, newChunk(source, piece))
+	}
+	return chunks
+}
+
+// SplitText splits text into pieces of at most size bytes, breaking on line
+// boundaries where possible. Blank pieces are dropped.
+func SplitText(text string, size int) []string {
+	var pieces []string
+	var current strings.Builder
+
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			pieces = append(pieces, current.String())
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if current.Len()+len(line) > size && current.Len() > 0 {
+			flush()
+		}
+		// Hard split lines longer than a piece, without cutting a rune
+		for len(line) > size {
+			cut := size
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(line)
+			}
+			current.WriteString(line[:cut])
+			flush()
+			line = line[cut:]
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return pieces
+}
+
+func newChunk(source, text string) chunk {
+	terms := map[string]int{}
+	for _, term := range tokenize(text) {
+		terms[term]++
+	}
+	// Path components are good search terms too
+	for _, term := range tokenize(source) {
+		terms[term] += 2
+	}
+	return chunk{source: source, text: text, terms: terms}
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	terms := fields[:0]
+	for _, f := range fields {
+		if len(f) >= minTermLength {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// RetrieveChunks returns the k chunks of index sharing the most terms with the
+// query.
+func RetrieveChunks(index []chunk, query string, k int) []chunk {
+	type scored struct {
+		index int
+		score int
+	}
+
+	var results []scored
+	queryTerms := tokenize(query)
+	for i, c := range index {
+		score := 0
+		for _, term := range queryTerms {
+			score += c.terms[term]
+		}
+		if score > 0 {
+			results = append(results, scored{i, score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	chunks := make([]chunk, len(results))
+	for i, r := range results {
+		chunks[i] = index[r.index]
+	}
+	return chunks
+}
+
+func indexedSources(index []chunk) []string {
+	seen := map[string]bool{}
+	var sources []string
+	for _, c := range index {
+		if !seen[c.source] {
+			seen[c.source] = true
+			sources = append(sources, c.source)
+		}
+	}
+	return sources
+}
+
+// WithRetrievedContext returns the messages to send for a prompt, with a
+// system message holding the most relevant indexed chunks inserted before the
+// latest user message, and the chunks numbered as they are in it. The stored
+// history is left untouched. Chunks come from index, a snapshot of docIndex
+// taken in Update, or from the vector store when one is configured and holds
+// any.
+func WithRetrievedContext(ctx context.Context, index []chunk, messages []openai.ChatCompletionMessage, prompt string) ([]openai.ChatCompletionMessage, []chunk) {
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	chunks := RetrieveChunks(index, prompt, retrievedChunks)
+	if vectors != nil {
+		found, err := SearchVectors(ctx, prompt, retrievedChunks)
+		if err != nil {
+			log.Printf("Error searching the vector store: %v", err)
+		} else if len(found) > 0 {
+			chunks = found
+		}
+	}
+	if len(index) == 0 && len(chunks) == 0 {
+		return messages, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(contextPromptHead)
+	if kbRoot != "" {
+		b.WriteString(" " + kbPromptHead)
+	}
+	if sources := indexedSources(index); len(sources) > 0 {
+		b.WriteString("\n\nIndexed files:\n")
+		b.WriteString(strings.Join(sources, "\n"))
+	}
+
+	for i, c := range chunks {
+		fmt.Fprintf(&b, "\n\n--- [%d] %s ---\n%s", i+1, c.source, c.text)
+	}
+
+	contextMessage := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: b.String(),
+	}
+
+	last := len(messages) - 1
+	withContext := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	withContext = append(withContext, messages[:last]...)
+	withContext = append(withContext, contextMessage, messages[last])
+	return withContext, chunks
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		size int
+		want []string
+	}{
+		{"fits", "one\ntwo\n", 20, []string{"one\ntwo\n"}},
+		{"breaks on lines", "one\ntwo\nthree\n", 9, []string{"one\ntwo\n", "three\n"}},
+		{"drops blank pieces", "one\n\n\n\ntwo", 4, []string{"one\n", "two"}},
+		{"cuts long lines", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"cuts on rune boundaries", "ååååå", 3, []string{"å", "å", "å", "å", "å"}},
+		{"rune wider than a piece", "日本", 2, []string{"日", "本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.size)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("SplitText(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+			}
+			for _, piece := range got {
+				if !utf8.ValidString(piece) {
+					t.Errorf("piece %q is not valid UTF-8", piece)
+				}
+			}
+		})
+	}
+}
+
+func TestRetrieveChunks(t *testing.T) {
+	index := []chunk{
+		newChunk("docs/install.md", "Run the installer, then restart the shell."),
+		newChunk("docs/config.md", "The config file sets the model and the theme."),
+		newChunk("src/theme.go", "Themes set colors of the config theme."),
+	}
+
+	got := RetrieveChunks(index, "theme colors", 2)
+	if len(got) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(got))
+	}
+	if got[0].source != "src/theme.go" || got[1].source != "docs/config.md" {
+		t.Errorf("got %s, %s, want the chunks mentioning the theme, best first", got[0].source, got[1].source)
+	}
+
+	if got := RetrieveChunks(index, "unrelated words", 2); len(got) != 0 {
+		t.Errorf("got %d chunks for an unrelated query, want none", len(got))
+	}
+}
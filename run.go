@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+// runTemplate is a prompt pipeline for `bubblechat run`, read from YAML or
+// JSON. Each step is a single request; its prompt, system prompt and output
+// path are Go templates over the variables and the answers of the steps
+// before it, by step name: {{.topic}}, {{.outline}}.
+type runTemplate struct {
+	Name      string         `json:"name"`
+	Model     string         `json:"model"`
+	System    string         `json:"system"`
+	Variables map[string]any `json:"variables"`
+	Steps     []runStep      `json:"steps"`
+
+	// File the last step's answer is written to, stdout when no step has an
+	// output
+	Output string `json:"output"`
+}
+
+type runStep struct {
+	Name        string   `json:"name"`
+	Model       string   `json:"model"`
+	System      string   `json:"system"`
+	Prompt      string   `json:"prompt"`
+	Temperature *float32 `json:"temperature"`
+
+	// File this step's answer is written to
+	Output string `json:"output"`
+}
+
+// runVars is the -var flag, which can be repeated.
+type runVars map[string]string
+
+func (v runVars) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v runVars) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return errors.New("expected name=value")
+	}
+	v[name] = val
+	return nil
+}
+
+// RunTemplate runs the steps of a template file in order, for
+// `bubblechat run <template.yaml>`.
+func RunTemplate(args []string) error {
+	vars := runVars{}
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Var(vars, "var", "set a variable, name=value (repeatable)")
+	out := flags.String("out", "", "write the last step's answer to this file, replacing the template's output")
+	dryRun := flags.Bool("dry-run", false, "print the steps with the variables filled in where known, without sending")
+
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return errors.New("usage: bubblechat run [--var name=value] [--out file] [--dry-run] <template.yaml>")
+	}
+
+	t, err := LoadRunTemplate(files[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", files[0], err)
+	}
+	if *out != "" {
+		t.Output = *out
+	}
+
+	values := map[string]string{}
+	for name, value := range t.Variables {
+		values[name] = fmt.Sprint(value)
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+
+	if !*dryRun {
+		initializeClient()
+	}
+	daily := LoadDailyUsage()
+
+	var answer string
+	var cost float64
+	for i, step := range t.Steps {
+		req, err := t.StepRequest(step, values)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+
+		if *dryRun {
+			fmt.Printf("# %d. %s (%s)\n", i+1, step.Name, req.Model)
+			for _, message := range req.Messages {
+				if message.Role == openai.ChatMessageRoleSystem {
+					fmt.Printf("system: %s\n", message.Content)
+				} else {
+					fmt.Printf("%s\n\n", message.Content)
+				}
+			}
+			values[step.Name] = "{{." + step.Name + "}}"
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s (%s)…", i+1, len(t.Steps), step.Name, req.Model)
+		result := CompleteOnce(req)
+		daily.Add(result.Cost)
+		cost += result.Cost
+		if result.Error != "" {
+			fmt.Fprintln(os.Stderr, " failed")
+			return fmt.Errorf("step %s: %s", step.Name, result.Error)
+		}
+		fmt.Fprintf(os.Stderr, " %s\n", time.Duration(result.LatencyMs)*time.Millisecond)
+
+		answer = result.Answer
+		values[step.Name] = answer
+
+		if step.Output != "" {
+			if err := writeRunOutput(step.Output, answer, values); err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%d steps, $%.4f\n", len(t.Steps), cost)
+
+	if t.Output != "" {
+		return writeRunOutput(t.Output, answer, values)
+	}
+	for _, step := range t.Steps {
+		if step.Output != "" {
+			return nil
+		}
+	}
+	fmt.Println(answer)
+	return nil
+}
+
+// StepRequest is the request of a step, with the variables and the answers
+// of the steps before it filled in.
+func (t runTemplate) StepRequest(step runStep, values map[string]string) (openai.ChatCompletionRequest, error) {
+	system, err := expandRunTemplate(cmp.Or(step.System, t.System), values)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, fmt.Errorf("system: %w", err)
+	}
+	prompt, err := expandRunTemplate(step.Prompt, values)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, fmt.Errorf("prompt: %w", err)
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if system != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: system})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: prompt})
+
+	req := openai.ChatCompletionRequest{Model: cmp.Or(step.Model, t.Model, modelName), Messages: messages}
+	if step.Temperature != nil {
+		req.Temperature = nonZero(*step.Temperature)
+	}
+	return req, nil
+}
+
+// LoadRunTemplate reads a template and checks that its steps can run.
+func LoadRunTemplate(path string) (runTemplate, error) {
+	var t runTemplate
+	if err := ReadTemplateFile(path, &t); err != nil {
+		return t, err
+	}
+
+	if len(t.Steps) == 0 {
+		return t, errors.New("no steps")
+	}
+	names := map[string]bool{}
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if names[step.Name] {
+			return t, fmt.Errorf("two steps are named %s", step.Name)
+		}
+		if _, ok := t.Variables[step.Name]; ok {
+			return t, fmt.Errorf("step %s has the name of a variable", step.Name)
+		}
+		if strings.TrimSpace(step.Prompt) == "" {
+			return t, fmt.Errorf("step %s has no prompt", step.Name)
+		}
+		names[step.Name] = true
+	}
+	return t, nil
+}
+
+// ReadTemplateFile decodes a YAML or JSON file into v, rejecting fields v
+// does not have.
+func ReadTemplateFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// JSON is read as is, YAML through its JSON form
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// expandRunTemplate fills in the variables and earlier answers. Names that
+// are not set yet are an error rather than an empty string.
+func expandRunTemplate(text string, values map[string]string) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, values); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeRunOutput(path, answer string, values map[string]string) error {
+	path, err := expandRunTemplate(path, values)
+	if err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(strings.TrimRight(answer, "\n")+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+// Re-rendering the transcript changes the lines above what is on screen when
+// a reply is labeled, a message folded or a notice added. The scroll position
+// is kept as the message at the top of the viewport and the line within it,
+// so the same text stays on screen. At the bottom the viewport keeps
+// following new messages.
+
+// ScrollAnchor returns the message at the top of the viewport and how many
+// of its lines are scrolled past, or following when the viewport is at the
+// bottom.
+func (m model) ScrollAnchor() (anchor, offset int, following bool) {
+	if m.viewport.AtBottom() || len(m.messageOffsets) == 0 {
+		return 0, 0, true
+	}
+
+	top := m.viewport.YOffset - m.contentTop
+	for i, start := range m.messageOffsets {
+		if start > top {
+			break
+		}
+		anchor, offset = i, top-start
+	}
+	return anchor, offset, false
+}
+
+// RestoreScroll puts the anchored line back at the top of the viewport, after
+// the content was set again.
+func (m *model) RestoreScroll(anchor, offset int, following bool) {
+	if following || anchor >= len(m.messageOffsets) {
+		m.viewport.GotoBottom()
+		return
+	}
+	m.viewport.SetYOffset(m.contentTop + m.messageOffsets[anchor] + offset)
+}
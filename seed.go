@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A seed makes the model sample the same reply for the same request, as far
+// as the server supports it. The system fingerprint returned with a reply
+// identifies the backend configuration; replies are only reproducible while
+// it stays the same.
+
+// Largest seed /reseed picks, so it fits the servers' 32 bit seeds.
+const maxRandomSeed = 1<<31 - 1
+
+// generation is how a reply was sampled, kept with the session by the reply's
+// index.
+type generation struct {
+	Seed        *int   `json:"seed,omitempty"`
+	Fingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+func init() {
+	registerCommand(command{
+		name:        "reseed",
+		usage:       "/reseed",
+		description: "Use a new random seed for the next requests",
+		run: func(m *model, args string) tea.Cmd {
+			seed := rand.IntN(maxRandomSeed)
+			cfg.Seed = &seed
+			AddNotice(m, fmt.Sprintf("Seed: %d", seed))
+			return nil
+		},
+	})
+}
+
+// RecordGeneration keeps the seed and fingerprint of the reply at index i.
+func (s *session) RecordGeneration(i int, g generation) {
+	if g.Seed == nil && g.Fingerprint == "" {
+		return
+	}
+	if s.Generations == nil {
+		s.Generations = map[int]generation{}
+	}
+	s.Generations[i] = g
+}
+
+// GenerationInspectorLines list the seed and fingerprint of a reply.
+func GenerationInspectorLines(g generation) []string {
+	var lines []string
+	if g.Seed != nil {
+		lines = append(lines, fmt.Sprintf("seed: %d", *g.Seed))
+	}
+	if g.Fingerprint != "" {
+		lines = append(lines, "system_fingerprint: "+g.Fingerprint)
+	}
+	return lines
+}
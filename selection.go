@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	selectionMarker           = "▌"
+	accessibleSelectionMarker = ">"
+
+	// Quotes have to fit the textarea's character limit next to the reply
+	maxQuoteLength = 200
+)
+
+// Selection mode picks a message in the transcript to act on. Keys go to the
+// selection until it is left, except for global bindings.
+type selectionKeyMap struct {
+	Up    key.Binding
+	Down  key.Binding
+	Fold  key.Binding
+	Quote key.Binding
+	Edit  key.Binding
+	Exit  key.Binding
+}
+
+var selectionKeys = selectionKeyMap{
+	Up:    key.NewBinding(key.WithKeys("up", "k")),
+	Down:  key.NewBinding(key.WithKeys("down", "j")),
+	Fold:  key.NewBinding(key.WithKeys("enter", " ")),
+	Quote: key.NewBinding(key.WithKeys("q")),
+	Edit:  key.NewBinding(key.WithKeys("e")),
+	Exit:  key.NewBinding(key.WithKeys("esc")),
+}
+
+// selectable is the number of messages that can be selected, which excludes
+// the placeholder of a pending reply.
+func (m model) selectable() int {
+	if m.request.InFlight() {
+		return len(m.messages) - 1
+	}
+	return len(m.messages)
+}
+
+func (m *model) StartSelection() {
+	m.FinishTyping()
+	if m.selectable() == 0 {
+		AddNotice(m, "Nothing to select yet")
+		return
+	}
+
+	m.selecting = true
+	m.selected = m.selectable() - 1
+	UpdateViewport(m)
+	m.ScrollToMessage(m.selected)
+}
+
+func (m *model) StopSelection() {
+	m.selecting = false
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+}
+
+// HandleSelectionKey runs selection mode bindings. The select binding leaves
+// selection mode, like esc.
+func HandleSelectionKey(m *model, msg tea.KeyMsg) (tea.Cmd, bool) {
+	// Switching tabs can leave the selection past the end
+	m.selected = min(m.selected, m.selectable()-1)
+	if m.selected < 0 {
+		m.StopSelection()
+		return nil, true
+	}
+
+	switch {
+	case key.Matches(msg, selectionKeys.Up):
+		m.selected = max(m.selected-1, 0)
+	case key.Matches(msg, selectionKeys.Down):
+		m.selected = min(m.selected+1, m.selectable()-1)
+	case key.Matches(msg, selectionKeys.Fold):
+		m.ToggleFold(m.selected)
+	case key.Matches(msg, selectionKeys.Quote):
+		QuoteReply(m, m.selected)
+		return nil, true
+	case key.Matches(msg, selectionKeys.Edit):
+		return EditReply(m, m.selected), true
+	case key.Matches(msg, citationKey):
+		n, _ := strconv.Atoi(msg.String())
+		OpenCitation(m, m.selected, n)
+		return nil, true
+	case key.Matches(msg, selectionKeys.Exit, m.keys.Select):
+		m.StopSelection()
+		return nil, true
+	default:
+		return nil, false
+	}
+
+	UpdateViewport(m)
+	m.ScrollToMessage(m.selected)
+	return nil, true
+}
+
+// QuoteReply puts message i into the textarea as a markdown blockquote and
+// leaves selection mode to write the follow-up.
+func QuoteReply(m *model, i int) {
+	if m.messages[i].Kind != kindReply {
+		AddNotice(m, "Only replies can be quoted")
+		return
+	}
+
+	source := strings.TrimSpace(m.messages[i].Content)
+	if runes := []rune(source); len(runes) > maxQuoteLength {
+		source = strings.TrimSpace(string(runes[:maxQuoteLength-1])) + "…"
+	}
+
+	quote := "> " + strings.ReplaceAll(source, "\n", "\n> ") + "\n\n"
+	m.textarea.SetValue(quote + m.textarea.Value())
+
+	m.StopSelection()
+	m.SetFocus(focusInput)
+}
+
+// SelectionGutter marks the lines of the selected block. Every block gets the
+// gutter while selecting so the text does not shift.
+func SelectionGutter(m *model, block string, selected bool) string {
+	marker := " "
+	if selected {
+		marker = selectionMarker
+		if cfg.Accessible {
+			marker = accessibleSelectionMarker
+		}
+		marker = StyleFromColor(focusColor).Render(marker)
+	}
+
+	lines := strings.Split(block, "\n")
+	for i := range lines {
+		lines[i] = marker + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ScrollToMessage scrolls the viewport so message i starts in view.
+func (m *model) ScrollToMessage(i int) {
+	if i < 0 || i >= len(m.messageOffsets) {
+		return
+	}
+
+	start := m.contentTop + m.messageOffsets[i]
+	visible := m.viewport.Height - m.viewport.Style.GetVerticalFrameSize()
+	if start < m.viewport.YOffset || start >= m.viewport.YOffset+visible {
+		m.viewport.SetYOffset(start)
+	}
+}
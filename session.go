@@ -0,0 +1,229 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	sessionsDirName = "sessions"
+	sessionIDLayout = "20060102-150405.000"
+	maxTitleLength  = 40
+)
+
+// A session is one saved conversation. Sessions are stored as one JSON file
+// each in the sessions directory next to the config.
+type session struct {
+	ID       string                         `json:"id"`
+	Title    string                         `json:"title"`
+	Model    string                         `json:"model"`
+	Created  time.Time                      `json:"created"`
+	Updated  time.Time                      `json:"updated"`
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+	Tags     []string                       `json:"tags,omitempty"`
+	// Encrypted content of a session read without decrypting it, see Unlock
+	sealed []byte
+	// Files attached ahead of the pending prompt, dropped along with it
+	pendingAttachments int
+
+	// When each message was first saved, for merging copies of the session
+	MessageTimes []time.Time  `json:"message_times,omitempty"`
+	Stats        sessionStats `json:"stats,omitempty"`
+	// Model of each reply by its index in Messages
+	ReplyModels map[int]string `json:"reply_models,omitempty"`
+	// Parameters prompts were sent with, by index, see requestOverrides
+	PromptOverrides map[int]requestOverrides `json:"prompt_overrides,omitempty"`
+	// Seed and system fingerprint of each reply by its index
+	Generations map[int]generation `json:"generations,omitempty"`
+	// Replies changed by the user, by index
+	EditedReplies map[int]bool `json:"edited_replies,omitempty"`
+	// Messages added with /inject rather than sent or received, by index
+	InjectedMessages map[int]bool `json:"injected_messages,omitempty"`
+	// Chunks retrieved for each reply, which it cites by number
+	Citations map[int][]citation `json:"citations,omitempty"`
+	// Timings of replies, by index
+	Metrics map[int]replyMetrics `json:"metrics,omitempty"`
+	// Replies answered from the response cache, by index
+	CachedReplies map[int]bool `json:"cached_replies,omitempty"`
+	// Verdicts of the grounding check on replies, by index
+	Groundings map[int]grounding `json:"groundings,omitempty"`
+	// Few-shot example set the session starts with, and its message count
+	Examples     string `json:"examples,omitempty"`
+	ExampleCount int    `json:"example_count,omitempty"`
+}
+
+var currentSession = NewSession()
+
+func NewSession() session {
+	now := time.Now()
+	return session{
+		ID:      now.Format(sessionIDLayout),
+		Model:   modelName,
+		Created: now,
+	}
+}
+
+func sessionsDir() string {
+	return filepath.Join(configDir(), sessionsDirName)
+}
+
+func sessionPath(id string) string {
+	return filepath.Join(sessionsDir(), id+".json")
+}
+
+// SaveCurrentSession stores chatMessages under the current session.
+func SaveCurrentSession() {
+	SaveHistory(&currentSession, chatMessages)
+}
+
+// SaveHistory stores history under s. Empty conversations are not written.
+func SaveHistory(s *session, history []openai.ChatCompletionMessage) {
+	if len(history) == 0 {
+		return
+	}
+
+	s.Messages = history
+	s.Updated = time.Now()
+	for len(s.MessageTimes) < len(history) {
+		s.MessageTimes = append(s.MessageTimes, s.Updated)
+	}
+	s.MessageTimes = s.MessageTimes[:len(history)]
+	if s.Title == "" {
+		s.Title = sessionTitle(history)
+	}
+
+	if err := SaveSession(*s); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
+}
+
+func SaveSession(s session) error {
+	if err := os.MkdirAll(sessionsDir(), 0o755); err != nil {
+		return err
+	}
+
+	data, err := EncodeSession(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sessionPath(s.ID), data, 0o600)
+}
+
+// LoadSession reads a saved session, decrypted.
+func LoadSession(id string) (session, error) {
+	s, err := readSession(id)
+	if err != nil {
+		return s, err
+	}
+	return s.Unlock()
+}
+
+// readSession reads a saved session, locked if it is encrypted.
+func readSession(id string) (session, error) {
+	data, err := os.ReadFile(sessionPath(id))
+	if err != nil {
+		return session{}, err
+	}
+	return DecodeSession(data)
+}
+
+// ListSessions returns all saved sessions, most recently updated first.
+// Encrypted sessions are locked, see Unlock.
+func ListSessions() []session {
+	entries, err := os.ReadDir(sessionsDir())
+	if err != nil {
+		return nil
+	}
+
+	var sessions []session
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		s, err := readSession(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			log.Printf("Error loading session %s: %v", entry.Name(), err)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+
+	return sessions
+}
+
+func sessionTitle(messages []openai.ChatCompletionMessage) string {
+	for _, msg := range messages {
+		if msg.Role != openai.ChatMessageRoleUser {
+			continue
+		}
+
+		title := strings.Join(strings.Fields(msg.Content), " ")
+		if len([]rune(title)) > maxTitleLength {
+			title = string([]rune(title)[:maxTitleLength-1]) + "…"
+		}
+		return title
+	}
+	return "Untitled"
+}
+
+// OpenSession replaces the conversation with a saved session and rebuilds the
+// transcript from its messages.
+func OpenSession(m *model, s session) {
+	s, err := s.Unlock()
+	if err != nil {
+		AddNotice(m, "Could not open session: "+err.Error())
+		return
+	}
+
+	currentSession = s
+	chatMessages = s.Messages
+	m.SetRequest(requestIdle)
+
+	RebuildTranscript(m)
+	ShareHistory()
+}
+
+// RenderTranscript renders the prompts and replies of a conversation.
+func (m model) RenderTranscript(s session, history []openai.ChatCompletionMessage) []Message {
+	messages := []Message{}
+	if s.ExampleCount > 0 {
+		messages = append(messages, BlockMessage(m.RenderExamples(s)))
+	}
+	for i, msg := range history {
+		if i < s.ExampleCount {
+			continue
+		}
+		switch msg.Role {
+		case openai.ChatMessageRoleUser:
+			messages = append(messages, m.PromptMessage(s, msg.Content, i))
+		case openai.ChatMessageRoleAssistant:
+			if msg.Content != "" {
+				messages = append(messages, m.ReplyMessage(s, msg.Content, i))
+			}
+		case openai.ChatMessageRoleSystem:
+			if s.InjectedMessages[i] {
+				messages = append(messages, m.SystemMessage(msg.Content))
+			}
+		}
+	}
+	return messages
+}
+
+// RebuildTranscript renders the transcript from chatMessages.
+func RebuildTranscript(m *model) {
+	m.messages = m.RenderTranscript(currentSession, chatMessages)
+
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Shared sessions let two bubblechat instances follow the same conversation.
+// The host runs the completions and broadcasts every prompt and response to
+// joined instances, which send their prompts to the host instead of the API.
+// Events are newline delimited JSON over a unix socket or TCP address, so an
+// SSH forward (ssh -L) works as a relay. A joining instance first sends the
+// host's token, and is dropped unless it matches.
+
+const (
+	shareHello    = "hello"
+	shareHistory  = "history"
+	sharePrompt   = "prompt"
+	shareResponse = "response"
+	shareError    = "error"
+)
+
+const (
+	shareTokenEnv = "BUBBLECHAT_SHARE_TOKEN"
+
+	// Time a joining instance has to send the token
+	shareHelloTimeout = 10 * time.Second
+	// Time an event may take to be written before the peer is dropped
+	shareWriteTimeout = 10 * time.Second
+	// Events waiting to be written to a peer before it counts as stalled
+	shareQueueSize = 64
+)
+
+type shareEvent struct {
+	Type     string                         `json:"type"`
+	Content  string                         `json:"content,omitempty"`
+	Messages []openai.ChatCompletionMessage `json:"messages,omitempty"`
+}
+
+// sharePeer is a connection to another instance. Events to it are written
+// by a goroutine of its own, so a stalled peer never blocks the UI.
+type sharePeer struct {
+	conn net.Conn
+	out  chan []byte
+}
+
+type shareEventMsg struct {
+	event shareEvent
+	peer  *sharePeer
+}
+
+type shareJoinedMsg struct {
+	peer *sharePeer
+}
+
+type shareClosedMsg struct {
+	err error
+}
+
+var (
+	hostAddr   string
+	joinAddr   string
+	shareToken string
+
+	shareMu    sync.Mutex
+	sharePeers []*sharePeer
+)
+
+func shareNetwork(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "tcp"
+	}
+	return "unix"
+}
+
+// shareListenAddr is addr with TCP addresses that name no host, like :7000,
+// bound to loopback, so sharing beyond this machine takes an explicit host.
+func shareListenAddr(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// LoadShareToken sets the token of a shared session from --token or the
+// environment, or for a host without either to a random one.
+func LoadShareToken() error {
+	shareToken = cmp.Or(shareToken, os.Getenv(shareTokenEnv))
+	if shareToken != "" || hostAddr == "" {
+		return nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	shareToken = hex.EncodeToString(b)
+	return nil
+}
+
+// ShareInstructions tells the host how other terminals join.
+func ShareInstructions() string {
+	return fmt.Sprintf("Sharing on %s, join with: bubblechat --join %s --token %s",
+		shareListenAddr(hostAddr), hostAddr, shareToken)
+}
+
+// StartHosting listens for joining instances and forwards their events to
+// the program.
+func StartHosting(program *tea.Program, addr string) error {
+	// A socket left behind by an earlier host would block the address
+	if shareNetwork(addr) == "unix" {
+		if info, err := os.Stat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+	}
+
+	listener, err := net.Listen(shareNetwork(addr), shareListenAddr(addr))
+	if err != nil {
+		return err
+	}
+	if shareNetwork(addr) == "unix" {
+		if err := os.Chmod(addr, 0o600); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Printf("Error accepting shared session: %v", err)
+				return
+			}
+			go acceptPeer(program, conn)
+		}
+	}()
+
+	return nil
+}
+
+// acceptPeer adds conn as a peer once it sent the token.
+func acceptPeer(program *tea.Program, conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(shareHelloTimeout))
+	line, err := reader.ReadBytes('\n')
+	conn.SetReadDeadline(time.Time{})
+
+	var hello shareEvent
+	if err == nil {
+		err = json.Unmarshal(line, &hello)
+	}
+	if err != nil || hello.Type != shareHello ||
+		subtle.ConstantTimeCompare([]byte(hello.Content), []byte(shareToken)) != 1 {
+		log.Printf("Rejected a terminal joining from %s", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+
+	peer := addSharePeer(conn)
+	program.Send(shareJoinedMsg{peer})
+	readShareEvents(program, peer, reader)
+}
+
+// JoinSession connects to a hosting instance.
+func JoinSession(program *tea.Program, addr string) error {
+	conn, err := net.Dial(shareNetwork(addr), addr)
+	if err != nil {
+		return err
+	}
+
+	peer := addSharePeer(conn)
+	sendShareEvent(peer, shareEvent{Type: shareHello, Content: shareToken})
+	go readShareEvents(program, peer, bufio.NewReader(conn))
+
+	return nil
+}
+
+func addSharePeer(conn net.Conn) *sharePeer {
+	peer := &sharePeer{conn: conn, out: make(chan []byte, shareQueueSize)}
+	go peer.writeEvents()
+
+	shareMu.Lock()
+	sharePeers = append(sharePeers, peer)
+	shareMu.Unlock()
+	return peer
+}
+
+// writeEvents writes the events queued for the peer until it is removed,
+// closing the connection when a write fails or times out.
+func (p *sharePeer) writeEvents() {
+	for data := range p.out {
+		p.conn.SetWriteDeadline(time.Now().Add(shareWriteTimeout))
+		if _, err := p.conn.Write(data); err != nil {
+			log.Printf("Error sending shared event: %v", err)
+			p.conn.Close()
+		}
+	}
+}
+
+func readShareEvents(program *tea.Program, peer *sharePeer, reader *bufio.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var event shareEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.Printf("Error decoding shared event: %v", err)
+			continue
+		}
+		program.Send(shareEventMsg{event, peer})
+	}
+
+	removeSharePeer(peer)
+	program.Send(shareClosedMsg{scanner.Err()})
+}
+
+func removeSharePeer(peer *sharePeer) {
+	shareMu.Lock()
+	defer shareMu.Unlock()
+
+	peer.conn.Close()
+	for i, p := range sharePeers {
+		if p == peer {
+			sharePeers = append(sharePeers[:i], sharePeers[i+1:]...)
+			close(peer.out)
+			return
+		}
+	}
+}
+
+// sendShareEvent queues an event for the peer. A peer too far behind to take
+// it is disconnected instead of waited for.
+func sendShareEvent(peer *sharePeer, event shareEvent) {
+	data, _ := json.Marshal(event)
+
+	shareMu.Lock()
+	defer shareMu.Unlock()
+	if !slices.Contains(sharePeers, peer) {
+		return
+	}
+	select {
+	case peer.out <- append(data, '\n'):
+	default:
+		log.Printf("Dropping a stalled shared session peer")
+		peer.conn.Close()
+	}
+}
+
+// BroadcastShare sends an event from the host to every joined instance.
+func BroadcastShare(event shareEvent) {
+	if hostAddr == "" {
+		return
+	}
+
+	shareMu.Lock()
+	peers := slices.Clone(sharePeers)
+	shareMu.Unlock()
+
+	for _, peer := range peers {
+		sendShareEvent(peer, event)
+	}
+}
+
+// ShareHistory sends the whole conversation, after joining or when the host
+// switches to another one.
+func ShareHistory() {
+	BroadcastShare(shareEvent{Type: shareHistory, Messages: chatMessages})
+}
+
+// SendToHost hands a prompt to the host, which echoes it back to everyone
+// once the request starts.
+func SendToHost(m *model, message string) {
+	shareMu.Lock()
+	peers := slices.Clone(sharePeers)
+	shareMu.Unlock()
+
+	if len(peers) == 0 {
+		AddNotice(m, "Not connected to the host")
+		m.textarea.SetValue(message)
+		return
+	}
+	sendShareEvent(peers[0], shareEvent{Type: sharePrompt, Content: message})
+}
+
+// HandleShareEvent applies an event from another instance to the model.
+func HandleShareEvent(m *model, msg shareEventMsg) tea.Cmd {
+	event := msg.event
+
+	// The host only takes prompts, everything else comes from it
+	if hostAddr != "" {
+		if event.Type != sharePrompt {
+			return nil
+		}
+		if m.request.InFlight() {
+			m.queue = append(m.queue, event.Content)
+			AddNotice(m, "Queued: "+event.Content)
+			return nil
+		}
+		return RequestSend(m, event.Content)
+	}
+
+	switch event.Type {
+	case shareHistory:
+		chatMessages = event.Messages
+		RebuildTranscript(m)
+	case sharePrompt:
+		chatMessages = append(chatMessages, UserMessage(event.Content))
+		m.messages = append(m.messages, m.PromptMessage(currentSession, event.Content, -1), m.PlaceholderMessage())
+		m.SetRequest(requestSending)
+		UpdateViewport(m)
+		return m.StartSpinner()
+	case shareResponse, shareError:
+		// Joining mid request means there is no placeholder to replace
+		if m.request.InFlight() {
+			if event.Type == shareError {
+				m.SetRequest(requestFailed)
+			} else {
+				m.SetRequest(requestDone)
+			}
+			m.messages = m.messages[:len(m.messages)-1]
+		}
+
+		if event.Type == shareError {
+			AddNotice(m, "Host request failed: "+event.Content)
+			return m.SendQueued()
+		}
+
+		chatMessages = append(chatMessages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: event.Content,
+		})
+		m.messages = append(m.messages, m.ReplyMessage(currentSession, event.Content, -1))
+		UpdateViewport(m)
+
+		return m.SendQueued()
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	sidebarWidth  = 28
+	sidebarHeight = viewportHeight + 7
+
+	sidebarHeadingColor  = "#636363"
+	sidebarSelectedColor = "#cda9d6"
+)
+
+type sidebarItemKind int
+
+const (
+	sidebarSession sidebarItemKind = iota
+	sidebarPrompt
+)
+
+type sidebarItem struct {
+	kind      sidebarItemKind
+	label     string
+	sessionID string
+	prompt    string
+}
+
+type sidebarSelectMsg struct {
+	item sidebarItem
+}
+
+// sidebarModel lists saved sessions and pinned prompts from the config.
+// Sessions can be narrowed down to those with a tag.
+type sidebarModel struct {
+	visible bool
+	tag     string
+	items   []sidebarItem
+	cursor  int
+	style   lipgloss.Style
+	keys    sidebarKeyMap
+}
+
+type sidebarKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+}
+
+func NewSidebar() sidebarModel {
+	return sidebarModel{
+		style: lipgloss.NewStyle().
+			Border(Border()).
+			Width(sidebarWidth).
+			Height(sidebarHeight),
+		keys: sidebarKeyMap{
+			Up:     key.NewBinding(key.WithKeys("up", "k")),
+			Down:   key.NewBinding(key.WithKeys("down", "j")),
+			Select: key.NewBinding(key.WithKeys("enter")),
+		},
+	}
+}
+
+// Refresh reloads the session list from disk.
+func (s *sidebarModel) Refresh() {
+	s.items = nil
+	for _, session := range ListSessions() {
+		if s.tag != "" && !session.HasTag(s.tag) {
+			continue
+		}
+		s.items = append(s.items, sidebarItem{
+			kind:      sidebarSession,
+			label:     session.Updated.Format("Jan 02") + " " + session.Title,
+			sessionID: session.ID,
+		})
+	}
+	for _, prompt := range cfg.PinnedPrompts {
+		s.items = append(s.items, sidebarItem{
+			kind:   sidebarPrompt,
+			label:  strings.Join(strings.Fields(prompt), " "),
+			prompt: prompt,
+		})
+	}
+
+	if s.cursor >= len(s.items) {
+		s.cursor = max(len(s.items)-1, 0)
+	}
+}
+
+func (s *sidebarModel) SetFocused(focused bool) {
+	color := unfocusColor
+	if focused {
+		color = focusColor
+	}
+	s.style = s.style.BorderForeground(Color(color))
+}
+
+func (s sidebarModel) Update(msg tea.Msg) (sidebarModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(s.items) == 0 {
+		return s, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, s.keys.Up):
+		s.cursor = max(s.cursor-1, 0)
+	case key.Matches(keyMsg, s.keys.Down):
+		s.cursor = min(s.cursor+1, len(s.items)-1)
+	case key.Matches(keyMsg, s.keys.Select):
+		item := s.items[s.cursor]
+		return s, func() tea.Msg { return sidebarSelectMsg{item} }
+	}
+
+	return s, nil
+}
+
+func (s sidebarModel) View() string {
+	heading := lipgloss.NewStyle().Foreground(Color(sidebarHeadingColor)).Bold(true)
+	selected := lipgloss.NewStyle().Foreground(Color(sidebarSelectedColor))
+
+	var lines []string
+	cursorLine := 0
+	section := sidebarItemKind(-1)
+	for i, item := range s.items {
+		if item.kind != section {
+			section = item.kind
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			if section == sidebarSession && s.tag != "" {
+				lines = append(lines, heading.Render("Sessions "+tagPrefix+s.tag))
+			} else if section == sidebarSession {
+				lines = append(lines, heading.Render("Sessions"))
+			} else {
+				lines = append(lines, heading.Render("Pinned"))
+			}
+		}
+
+		label := truncate(item.label, sidebarWidth-2)
+		if i == s.cursor {
+			cursorLine = len(lines)
+			label = selected.Render("▸ " + label)
+		} else {
+			label = "  " + label
+		}
+		lines = append(lines, label)
+	}
+
+	if len(lines) == 0 {
+		if s.tag != "" {
+			lines = append(lines, heading.Render("No sessions tagged "+tagPrefix+s.tag))
+		} else {
+			lines = append(lines, heading.Render("No saved sessions"))
+		}
+	}
+
+	// Keep the cursor in view
+	offset := 0
+	if cursorLine >= sidebarHeight {
+		offset = cursorLine - sidebarHeight + 1
+	}
+	lines = lines[offset:]
+	if len(lines) > sidebarHeight {
+		lines = lines[:sidebarHeight]
+	}
+
+	return s.style.Render(strings.Join(lines, "\n"))
+}
+
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
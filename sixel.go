@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	colorpalette "image/color/palette"
+	"strings"
+)
+
+// Sixel images are drawn in pixels rather than cells, six rows of pixels to
+// a line of the sequence, with colors from a palette defined up front.
+
+// Width in pixels a terminal cell is assumed to have when sizing images
+const sixelCellWidth = 10
+
+// SixelImage encodes img scaled to width pixels, or its own width when
+// smaller, with its colors reduced to the Plan 9 palette.
+func SixelImage(img image.Image, width int) string {
+	bounds := img.Bounds()
+	width = min(width, bounds.Dx())
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	// Nearest neighbor scaling, each pixel mapped to the closest color
+	paletted := image.NewPaletted(image.Rect(0, 0, width, height), colorpalette.Plan9)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			paletted.Set(x, y, img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bPq\"1;1;%d;%d", width, height)
+
+	var used [256]bool
+	for _, i := range paletted.Pix {
+		used[i] = true
+	}
+	for i, c := range colorpalette.Plan9 {
+		if used[i] {
+			r, g, bl, _ := c.RGBA()
+			fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+		}
+	}
+
+	for top := 0; top < height; top += 6 {
+		var inBand [256]bool
+		for y := top; y < min(top+6, height); y++ {
+			for _, i := range paletted.Pix[y*paletted.Stride : y*paletted.Stride+width] {
+				inBand[i] = true
+			}
+		}
+
+		first := true
+		for i := range inBand {
+			if !inBand[i] {
+				continue
+			}
+			// Each color is drawn over the band again from its start
+			if !first {
+				b.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&b, "#%d", i)
+
+			var last byte
+			run := 0
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if paletted.ColorIndexAt(x, top+dy) == uint8(i) {
+						bits |= 1 << dy
+					}
+				}
+				if sixel := '?' + bits; run > 0 && sixel == last {
+					run++
+				} else {
+					writeSixelRun(&b, last, run)
+					last, run = sixel, 1
+				}
+			}
+			writeSixelRun(&b, last, run)
+		}
+		b.WriteByte('-')
+	}
+
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeSixelRun writes run repeats of sixel, compressed when that is shorter.
+func writeSixelRun(b *strings.Builder, sixel byte, run int) {
+	if run > 3 {
+		fmt.Fprintf(b, "!%d%c", run, sixel)
+		return
+	}
+	for range run {
+		b.WriteByte(sixel)
+	}
+}
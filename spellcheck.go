@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultDictionary = "/usr/share/dict/words"
+
+	// Shorter words are mostly abbreviations and typed too fast to flag
+	minSpellcheckLength = 3
+
+	underlineStart = "\x1b[4m"
+	underlineEnd   = "\x1b[24m"
+)
+
+var (
+	dictionaryOnce sync.Once
+	dictionary     map[string]bool
+)
+
+// loadDictionary reads the word list on first use. A missing list turns
+// spellchecking off rather than flagging every word.
+func loadDictionary() map[string]bool {
+	dictionaryOnce.Do(func() {
+		data, err := os.ReadFile(cfg.Dictionary)
+		if err != nil {
+			log.Printf("Error loading dictionary, spellcheck disabled: %v", err)
+			return
+		}
+
+		dictionary = map[string]bool{}
+		for _, word := range strings.Fields(string(data)) {
+			dictionary[strings.ToLower(word)] = true
+		}
+	})
+	return dictionary
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '\''
+}
+
+// Misspelled reports whether word is missing from the dictionary. Short
+// words, acronyms and words with capitals inside, like identifiers, are not
+// checked.
+func Misspelled(word string) bool {
+	words := loadDictionary()
+	if words == nil || utf8.RuneCountInString(word) < minSpellcheckLength {
+		return false
+	}
+
+	_, first := utf8.DecodeRuneInString(word)
+	if strings.IndexFunc(word[first:], unicode.IsUpper) >= 0 {
+		return false
+	}
+
+	word = strings.ToLower(word)
+	return !words[word] && !words[strings.TrimSuffix(word, "'s")]
+}
+
+// MisspelledWords returns the misspelled words of a draft. The word at the
+// end is still being typed and is left alone until it is finished.
+func MisspelledWords(draft string) map[string]bool {
+	words := strings.FieldsFunc(draft, func(r rune) bool { return !isWordRune(r) })
+
+	last, _ := utf8.DecodeLastRuneInString(draft)
+	if len(words) > 0 && isWordRune(last) {
+		words = words[:len(words)-1]
+	}
+
+	misspelled := map[string]bool{}
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		if Misspelled(word) {
+			misspelled[word] = true
+		}
+	}
+	return misspelled
+}
+
+// UnderlineMisspelled underlines the misspelled words of draft in the
+// rendered textarea. Escape sequences are copied as is and end a word, so the
+// word under the cursor is never matched.
+func UnderlineMisspelled(view, draft string) string {
+	if !cfg.Spellcheck {
+		return view
+	}
+
+	misspelled := MisspelledWords(draft)
+	if len(misspelled) == 0 {
+		return view
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(view); {
+		if view[i] == '\x1b' {
+			end := escapeEnd(view, i)
+			b.WriteString(view[i:end])
+			i = end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(view[i:])
+		if !isWordRune(r) {
+			b.WriteString(view[i : i+size])
+			i += size
+			continue
+		}
+
+		start := i
+		for i < len(view) {
+			r, size := utf8.DecodeRuneInString(view[i:])
+			if !isWordRune(r) {
+				break
+			}
+			i += size
+		}
+
+		// Quotes around a word are not part of it
+		wordStart, wordEnd := start, i
+		for wordStart < wordEnd && view[wordStart] == '\'' {
+			wordStart++
+		}
+		for wordEnd > wordStart && view[wordEnd-1] == '\'' {
+			wordEnd--
+		}
+
+		word := view[wordStart:wordEnd]
+		if !misspelled[word] {
+			b.WriteString(view[start:i])
+			continue
+		}
+		b.WriteString(view[start:wordStart] + underlineStart + word + underlineEnd + view[wordEnd:i])
+	}
+
+	return b.String()
+}
+
+// escapeEnd returns the index after the escape sequence starting at i.
+func escapeEnd(s string, i int) int {
+	j := i + 1
+	if j < len(s) && s[j] == '[' {
+		// CSI sequences end with a byte in @ to ~
+		for j++; j < len(s); j++ {
+			if s[j] >= '@' && s[j] <= '~' {
+				return j + 1
+			}
+		}
+		return len(s)
+	}
+	return min(j+1, len(s))
+}
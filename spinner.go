@@ -0,0 +1,87 @@
+package main
+
+import (
+	"log"
+
+	"github.com/charmbracelet/bubbles/spinner"
+)
+
+const (
+	// No animation, for distraction free writing
+	spinnerNone = "none"
+
+	spinnerColor = "#FF00FF"
+
+	// Shown in place of a spinner that does not animate
+	staticPlaceholder = "…"
+
+	// Shown in place of the spinner in quiet mode
+	quietPlaceholder = "…thinking"
+)
+
+// quietMode is the --quiet flag, which sets the quiet config.
+var quietMode bool
+
+// Spinners by the names used in the theme.
+var spinnerTypes = map[string]spinner.Spinner{
+	"line":      spinner.Line,
+	"dot":       spinner.Dot,
+	"minidot":   spinner.MiniDot,
+	"jump":      spinner.Jump,
+	"pulse":     spinner.Pulse,
+	"points":    spinner.Points,
+	"globe":     spinner.Globe,
+	"moon":      spinner.Moon,
+	"monkey":    spinner.Monkey,
+	"meter":     spinner.Meter,
+	"hamburger": spinner.Hamburger,
+	"ellipsis":  spinner.Ellipsis,
+}
+
+// spinnerType looks up a spinner of the theme. Empty or unknown names get the
+// fallback.
+func spinnerType(name string, fallback spinner.Spinner) spinner.Spinner {
+	if name == "" || name == spinnerNone {
+		return fallback
+	}
+	s, ok := spinnerTypes[name]
+	if !ok {
+		log.Printf("Unknown spinner %q", name)
+		return fallback
+	}
+	return s
+}
+
+// NewSpinner is the reply placeholder's spinner. Without a theme spinner it
+// is the one of the icon set.
+func NewSpinner() spinner.Model {
+	return spinner.New(
+		spinner.WithSpinner(spinnerType(cfg.Theme.Spinner, icons.Spinner)),
+		spinner.WithStyle(StyleFromColor(cfg.Theme.SpinnerColor)),
+	)
+}
+
+// NewStatusSpinner is the spinner in the header while the endpoint is checked.
+func NewStatusSpinner() spinner.Model {
+	return spinner.New(
+		spinner.WithSpinner(spinnerType(cfg.Theme.StatusSpinner, spinner.Line)),
+		spinner.WithStyle(StyleFromColor(cfg.Theme.StatusSpinnerColor)),
+	)
+}
+
+// Animated reports whether anything may move on its own: spinners, the
+// cursor blinking and replies typed out. Accessibility and quiet mode keep
+// the screen still.
+func Animated() bool {
+	return !cfg.Accessible && !cfg.Quiet
+}
+
+// AnimateSpinner reports whether the reply placeholder animates. Otherwise it
+// shows a static text.
+func AnimateSpinner() bool {
+	return Animated() && cfg.Theme.Spinner != spinnerNone
+}
+
+func AnimateStatusSpinner() bool {
+	return Animated() && cfg.Theme.StatusSpinner != spinnerNone
+}
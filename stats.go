@@ -0,0 +1,165 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// modelStats adds up the requests made with one model in a session.
+type modelStats struct {
+	Requests         int           `json:"requests"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Cost             float64       `json:"cost"`
+	Latency          time.Duration `json:"latency"`
+
+	// Timings of the requests since they are recorded, and the provider of
+	// the latest
+	Timed           int           `json:"timed,omitempty"`
+	FirstByte       time.Duration `json:"first_byte,omitempty"`
+	Generation      time.Duration `json:"generation,omitempty"`
+	GeneratedTokens int           `json:"generated_tokens,omitempty"`
+	Provider        string        `json:"provider,omitempty"`
+}
+
+type sessionStats map[string]modelStats
+
+func init() {
+	registerCommand(command{
+		name:        "stats",
+		usage:       "/stats",
+		description: "Show token, cost and latency statistics",
+		run: func(m *model, args string) tea.Cmd {
+			current := currentSession
+			current.Messages = chatMessages
+
+			AddNotice(m, "Statistics\n\n"+
+				StatsReport("This session", []session{current})+"\n"+
+				StatsReport("All time", allSessions(current)))
+			return nil
+		},
+	})
+}
+
+// Record adds a finished request to the stats.
+func (s *sessionStats) Record(model string, usage openai.Usage, latency time.Duration) {
+	if *s == nil {
+		*s = sessionStats{}
+	}
+
+	stats := (*s)[model]
+	stats.Requests++
+	stats.PromptTokens += usage.PromptTokens
+	stats.CompletionTokens += usage.CompletionTokens
+	stats.Cost += RequestCost(model, usage)
+	stats.Latency += latency
+	(*s)[model] = stats
+}
+
+// sessionForTab returns the session of the tab with id, which for the active
+// tab lives in currentSession.
+func (m *model) sessionForTab(id int) *session {
+	if id == m.ActiveTabID() {
+		return &currentSession
+	}
+	for i := range m.tabs {
+		if m.tabs[i].id == id {
+			return &m.tabs[i].session
+		}
+	}
+	return nil
+}
+
+// allSessions returns the saved sessions with current in place of its saved
+// copy, which may be behind.
+func allSessions(current session) []session {
+	sessions := []session{current}
+	for _, s := range ListSessions() {
+		if s.ID != current.ID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions
+}
+
+func StatsReport(title string, sessions []session) string {
+	var prompts, replies int
+	total := modelStats{}
+	byModel := sessionStats{}
+
+	for _, s := range sessions {
+		for _, msg := range s.Messages {
+			switch msg.Role {
+			case openai.ChatMessageRoleUser:
+				prompts++
+			case openai.ChatMessageRoleAssistant:
+				if msg.Content != "" {
+					replies++
+				}
+			}
+		}
+
+		for model, stats := range s.Stats {
+			sum := byModel[model]
+			sum.Requests += stats.Requests
+			sum.PromptTokens += stats.PromptTokens
+			sum.CompletionTokens += stats.CompletionTokens
+			sum.Cost += stats.Cost
+			sum.Latency += stats.Latency
+			sum.Timed += stats.Timed
+			sum.FirstByte += stats.FirstByte
+			sum.Generation += stats.Generation
+			sum.GeneratedTokens += stats.GeneratedTokens
+			sum.Provider = cmp.Or(sum.Provider, stats.Provider)
+			byModel[model] = sum
+
+			total.Requests += stats.Requests
+			total.PromptTokens += stats.PromptTokens
+			total.CompletionTokens += stats.CompletionTokens
+			total.Cost += stats.Cost
+			total.Latency += stats.Latency
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d sessions, %d prompts, %d replies\n", title, len(sessions), prompts, replies)
+	fmt.Fprintf(&b, "tokens %s in · %s out · $%.4f · avg %s\n",
+		formatCount(total.PromptTokens), formatCount(total.CompletionTokens), total.Cost, averageLatency(total))
+
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return byModel[models[i]].Requests > byModel[models[j]].Requests
+	})
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, model := range models {
+		stats := byModel[model]
+		name := model
+		if stats.Provider != "" {
+			name += " (" + stats.Provider + ")"
+		}
+		fmt.Fprintf(w, "  %s\t%d req\t%s in\t%s out\t$%.4f\tavg %s\tTTFB %s\t%s\n", name, stats.Requests,
+			formatCount(stats.PromptTokens), formatCount(stats.CompletionTokens), stats.Cost, averageLatency(stats),
+			averageFirstByte(stats), averageTokenRate(stats))
+	}
+	w.Flush()
+
+	return b.String()
+}
+
+func averageLatency(stats modelStats) string {
+	if stats.Requests == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", (stats.Latency / time.Duration(stats.Requests)).Seconds())
+}
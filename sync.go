@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const syncGit = "git"
+
+// SyncConfig selects where sessions are synced to. Only "git" exists so far:
+// Dir is a clone of a repository the sessions are committed to and pushed.
+type SyncConfig struct {
+	Backend string `json:"backend"`
+	Dir     string `json:"dir"`
+}
+
+// A syncBackend stores sessions where other machines can reach them.
+type syncBackend interface {
+	// Fetch returns every session stored remotely.
+	Fetch() ([]session, error)
+	// Publish stores new and changed sessions remotely.
+	Publish(sessions []session) error
+}
+
+type syncMsg struct {
+	pulled int
+	pushed int
+	err    error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "sync",
+		usage:       "/sync",
+		description: "Sync saved sessions with the configured backend",
+		run: func(m *model, args string) tea.Cmd {
+			if cfg.Sync.Backend == "" {
+				AddNotice(m, "No sync backend, set sync in the config")
+				return nil
+			}
+			AddNotice(m, "Syncing sessions…")
+			return SyncCmd()
+		},
+	})
+}
+
+func NewSyncBackend(c SyncConfig) (syncBackend, error) {
+	switch c.Backend {
+	case syncGit:
+		if c.Dir == "" {
+			return nil, fmt.Errorf("sync backend %q needs a dir", c.Backend)
+		}
+		dir := c.Dir
+		if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			dir = filepath.Join(home, rest)
+		}
+		return gitBackend{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown sync backend %q", c.Backend)
+	}
+}
+
+// SyncCmd merges the local and remote sessions in both directions.
+func SyncCmd() tea.Cmd {
+	if cfg.Sync.Backend == "" {
+		return nil
+	}
+
+	return func() tea.Msg {
+		backend, err := NewSyncBackend(cfg.Sync)
+		if err != nil {
+			return syncMsg{err: err}
+		}
+		return SyncSessions(backend)
+	}
+}
+
+func SyncSessions(backend syncBackend) syncMsg {
+	var result syncMsg
+
+	remote, err := backend.Fetch()
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	remoteByID := map[string]session{}
+	for _, s := range remote {
+		remoteByID[s.ID] = s
+	}
+
+	var publish []session
+	for _, local := range ListSessions() {
+		local, err := local.Unlock()
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		r, ok := remoteByID[local.ID]
+		delete(remoteByID, local.ID)
+		if !ok {
+			publish = append(publish, local)
+			continue
+		}
+
+		merged := MergeSessions(local, r)
+		if !reflect.DeepEqual(merged.Messages, local.Messages) {
+			if err := SaveSession(merged); err != nil {
+				result.err = err
+				return result
+			}
+			result.pulled++
+		}
+		if !reflect.DeepEqual(merged.Messages, r.Messages) {
+			publish = append(publish, merged)
+		}
+	}
+
+	// What is left was only saved on other machines
+	for _, s := range remoteByID {
+		if err := SaveSession(s); err != nil {
+			result.err = err
+			return result
+		}
+		result.pulled++
+	}
+
+	if len(publish) > 0 {
+		result.err = backend.Publish(publish)
+		result.pushed = len(publish)
+	}
+	return result
+}
+
+// MergeSessions combines two copies of a session message by message. Where
+// they differ the message written last wins, by the times in MessageTimes.
+func MergeSessions(a, b session) session {
+	if b.Updated.After(a.Updated) {
+		a, b = b, a
+	}
+	merged := a
+
+	n := max(len(a.Messages), len(b.Messages))
+	merged.Messages = make([]openai.ChatCompletionMessage, n)
+	copy(merged.Messages, a.Messages)
+	merged.MessageTimes = make([]time.Time, n)
+	merged.ReplyModels = map[int]string{}
+	for i, model := range a.ReplyModels {
+		merged.ReplyModels[i] = model
+	}
+
+	takeB := func(i int) {
+		merged.Messages[i] = b.Messages[i]
+		merged.MessageTimes[i] = messageTime(b, i)
+		delete(merged.ReplyModels, i)
+		if model, ok := b.ReplyModels[i]; ok {
+			merged.ReplyModels[i] = model
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(a.Messages):
+			takeB(i)
+		case i < len(b.Messages) && messageTime(b, i).After(messageTime(a, i)) && !reflect.DeepEqual(a.Messages[i], b.Messages[i]):
+			takeB(i)
+		default:
+			merged.MessageTimes[i] = messageTime(a, i)
+		}
+	}
+
+	return merged
+}
+
+// messageTime is when message i was saved, or the session's last update for
+// sessions saved before messages had times.
+func messageTime(s session, i int) time.Time {
+	if i < len(s.MessageTimes) {
+		return s.MessageTimes[i]
+	}
+	return s.Updated
+}
+
+// gitBackend keeps sessions as JSON files in a git clone and pulls and pushes
+// its remote, when it has one.
+type gitBackend struct {
+	dir string
+}
+
+func (g gitBackend) git(args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", g.dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g gitBackend) hasRemote() bool {
+	remotes, err := g.git("remote")
+	return err == nil && remotes != ""
+}
+
+func (g gitBackend) Fetch() ([]session, error) {
+	if g.hasRemote() {
+		if _, err := g.git("pull", "--rebase", "--autostash"); err != nil {
+			return nil, err
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(g.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []session
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := DecodeSession(data)
+		if err == nil {
+			s, err = s.Unlock()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+func (g gitBackend) Publish(sessions []session) error {
+	for _, s := range sessions {
+		data, err := EncodeSession(s)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(g.dir, s.ID+".json"), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if _, err := g.git("add", "--all"); err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	if _, err := g.git("commit", "--quiet", "-m", fmt.Sprintf("Sync %d sessions from %s", len(sessions), host)); err != nil {
+		return err
+	}
+
+	if g.hasRemote() {
+		_, err := g.git("push", "--quiet")
+		return err
+	}
+	return nil
+}
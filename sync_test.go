@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestMergeSessions(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	user := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content}
+	}
+	reply := func(content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}
+	}
+
+	local := session{
+		ID:           "s",
+		Title:        "local",
+		Updated:      t0.Add(3 * time.Minute),
+		Messages:     []openai.ChatCompletionMessage{user("hi"), reply("hello"), user("more")},
+		MessageTimes: []time.Time{t0, t0.Add(time.Minute), t0.Add(3 * time.Minute)},
+		ReplyModels:  map[int]string{1: "gpt-4o"},
+	}
+	remote := session{
+		ID:           "s",
+		Title:        "remote",
+		Updated:      t0.Add(2 * time.Minute),
+		Messages:     []openai.ChatCompletionMessage{user("hi"), reply("hello, edited")},
+		MessageTimes: []time.Time{t0, t0.Add(2 * time.Minute)},
+		ReplyModels:  map[int]string{1: "gpt-4o-mini"},
+	}
+
+	for _, order := range []struct {
+		name string
+		a, b session
+	}{
+		{"local first", local, remote},
+		{"remote first", remote, local},
+	} {
+		t.Run(order.name, func(t *testing.T) {
+			merged := MergeSessions(order.a, order.b)
+
+			if merged.Title != "local" {
+				t.Errorf("title = %q, want the newer copy's", merged.Title)
+			}
+			want := []string{"hi", "hello, edited", "more"}
+			if len(merged.Messages) != len(want) {
+				t.Fatalf("got %d messages, want %d", len(merged.Messages), len(want))
+			}
+			for i, content := range want {
+				if merged.Messages[i].Content != content {
+					t.Errorf("message %d = %q, want %q", i, merged.Messages[i].Content, content)
+				}
+			}
+			if !merged.MessageTimes[1].Equal(t0.Add(2 * time.Minute)) {
+				t.Errorf("message 1 time = %v, want the edit's", merged.MessageTimes[1])
+			}
+			if merged.ReplyModels[1] != "gpt-4o-mini" {
+				t.Errorf("reply model = %q, want the edited reply's", merged.ReplyModels[1])
+			}
+		})
+	}
+}
+
+func TestMergeSessionsWithoutMessageTimes(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	older := session{Updated: t0, Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "old"}}}
+	newer := session{Updated: t0.Add(time.Hour), Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "new"}}}
+
+	merged := MergeSessions(older, newer)
+	if got := merged.Messages[0].Content; got != "new" {
+		t.Errorf("message = %q, want the one from the session updated last", got)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+)
+
+const minColumnWidth = 3
+
+var tableSeparator = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// FormatTables replaces markdown tables in text with box drawn tables that
+// fit within width, shrinking and wrapping the widest columns first.
+func FormatTables(text string, width int) string {
+	lines := strings.Split(text, "\n")
+
+	var out []string
+	inCode := false
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			inCode = !inCode
+		}
+
+		if inCode || !isTableRow(lines[i]) || i+1 >= len(lines) || !tableSeparator.MatchString(lines[i+1]) {
+			out = append(out, lines[i])
+			continue
+		}
+
+		header := splitRow(lines[i])
+		aligns := parseAligns(lines[i+1])
+		var rows [][]string
+
+		j := i + 2
+		for ; j < len(lines) && isTableRow(lines[j]); j++ {
+			rows = append(rows, splitRow(lines[j]))
+		}
+
+		out = append(out, renderTable(header, aligns, rows, width))
+		i = j - 1
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func isTableRow(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func parseAligns(separator string) []lipgloss.Position {
+	var aligns []lipgloss.Position
+	for _, cell := range splitRow(separator) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, lipgloss.Center)
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, lipgloss.Right)
+		default:
+			aligns = append(aligns, lipgloss.Left)
+		}
+	}
+	return aligns
+}
+
+func renderTable(header []string, aligns []lipgloss.Position, rows [][]string, width int) string {
+	columns := len(header)
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	widths := make([]int, columns)
+	measure := func(row []string) {
+		for c, cell := range row {
+			widths[c] = max(widths[c], lipgloss.Width(cell), minColumnWidth)
+		}
+	}
+	measure(header)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	// Borders take one column each, plus one space of padding per side
+	available := width - (columns + 1) - 2*columns
+	for sum(widths) > available {
+		widest := 0
+		for c := range widths {
+			if widths[c] > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+
+	border := func(left, middle, right string) string {
+		parts := make([]string, columns)
+		for c, w := range widths {
+			parts[c] = strings.Repeat("─", w+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+
+	renderRow := func(row []string, bold bool) []string {
+		cells := make([][]string, columns)
+		height := 1
+		for c := 0; c < columns; c++ {
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			wrapped := wrap.String(wordwrap.String(cell, widths[c]), widths[c])
+			cells[c] = strings.Split(wrapped, "\n")
+			height = max(height, len(cells[c]))
+		}
+
+		lines := make([]string, height)
+		for l := range lines {
+			parts := make([]string, columns)
+			for c := 0; c < columns; c++ {
+				text := ""
+				if l < len(cells[c]) {
+					text = cells[c][l]
+				}
+
+				align := lipgloss.Left
+				if c < len(aligns) {
+					align = aligns[c]
+				}
+				style := lipgloss.NewStyle().Width(widths[c]).Align(align).Bold(bold)
+				parts[c] = " " + style.Render(text) + " "
+			}
+			lines[l] = "│" + strings.Join(parts, "│") + "│"
+		}
+		return lines
+	}
+
+	table := []string{border("┌", "┬", "┐")}
+	table = append(table, renderRow(header, true)...)
+	table = append(table, border("├", "┼", "┤"))
+	for _, row := range rows {
+		table = append(table, renderRow(row, false)...)
+	}
+	table = append(table, border("└", "┴", "┘"))
+
+	return strings.Join(table, "\n")
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	tabColor       = "#636363"
+	activeTabColor = "#cda9d6"
+	unreadMarker   = "●"
+	waitingMarker  = "…"
+)
+
+// A conversation is the state of one tab. The active tab lives in the model
+// fields and globals (m.messages, chatMessages, currentSession...) and is only
+// written back here when switching away from it.
+type conversation struct {
+	id            int
+	session       session
+	history       []openai.ChatCompletionMessage
+	messages      []Message
+	request       requestState
+	cancelRequest context.CancelFunc
+	queue         []string
+	unread        bool
+}
+
+func (m model) ActiveTabID() int {
+	return m.tabs[m.activeTab].id
+}
+
+func (m *model) stashTab() {
+	t := &m.tabs[m.activeTab]
+	t.session = currentSession
+	t.history = chatMessages
+	t.messages = m.messages
+	t.request = m.request
+	// Typing out stops in the background
+	if t.request == requestTyping {
+		t.request = requestDone
+	}
+	t.cancelRequest = m.cancelRequest
+	t.queue = m.queue
+}
+
+func (m *model) loadTab(i int) tea.Cmd {
+	m.activeTab = i
+	t := &m.tabs[i]
+	t.unread = false
+
+	currentSession = t.session
+	chatMessages = t.history
+	m.messages = t.messages
+	m.request = t.request
+	m.header.request = t.request
+	m.cancelRequest = t.cancelRequest
+	m.queue = t.queue
+	m.undoUntil = time.Time{}
+
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+	ShareHistory()
+
+	// Messages queued while the tab was in the background go through the
+	// same checks as any other once it is active
+	if !m.request.InFlight() {
+		return tea.Batch(m.StartSpinner(), m.SendQueued())
+	}
+	return m.StartSpinner()
+}
+
+func (m *model) NewTab() tea.Cmd {
+	m.stashTab()
+
+	m.tabs = append(m.tabs, conversation{
+		id:       m.nextTabID,
+		session:  NewSession(),
+		messages: []Message{},
+	})
+	m.nextTabID++
+
+	return m.loadTab(len(m.tabs) - 1)
+}
+
+func (m *model) SwitchTab(delta int) tea.Cmd {
+	if len(m.tabs) < 2 {
+		return nil
+	}
+
+	m.stashTab()
+	return m.loadTab((m.activeTab + delta + len(m.tabs)) % len(m.tabs))
+}
+
+// BackgroundResponse applies a finished request to a tab that is not active
+// and marks it unread. Its queued messages wait for the tab to be active.
+func (m *model) BackgroundResponse(msg responseMsg) {
+	for i := range m.tabs {
+		t := &m.tabs[i]
+		if t.id != msg.tab || !t.request.InFlight() {
+			continue
+		}
+
+		t.request.Set(resultState(msg.err))
+		t.cancelRequest = nil
+		t.unread = true
+
+		if msg.err != nil {
+			t.messages[len(t.messages)-1] = ErrorMessage(msg.err)
+			if len(t.queue) > 0 {
+				t.messages = append(t.messages, m.NoticeMessage(fmt.Sprintf("Dropped %d queued messages", len(t.queue))))
+				t.queue = nil
+			}
+			return
+		}
+
+		t.history = append(t.history, msg.replies...)
+		reply := len(t.history) - 1
+		t.session.RecordReplyModel(reply, msg.model)
+		t.session.RecordGeneration(reply, msg.generation)
+		t.session.RecordCitations(reply, msg.retrieved)
+		t.session.RecordMetrics(reply, msg.metrics)
+		if msg.cached {
+			t.session.MarkCached(reply)
+		}
+		t.messages[len(t.messages)-1] = m.ReplyMessage(t.session, msg.message, reply)
+		SaveHistory(&t.session, t.history)
+		return
+	}
+}
+
+// ShowResult replaces the placeholder of a request made outside the
+// conversation with its result, or the error, in the tab it was made in. It
+// reports whether that is the active tab.
+func (m *model) ShowResult(tab int, result Message, err error) bool {
+	if err != nil {
+		result = ErrorMessage(err)
+	}
+
+	if tab == m.ActiveTabID() {
+		m.SetRequest(resultState(err))
+		m.cancelRequest = nil
+		m.messages[len(m.messages)-1] = result
+		UpdateViewport(m)
+		m.viewport.GotoBottom()
+		return true
+	}
+
+	for i := range m.tabs {
+		t := &m.tabs[i]
+		if t.id == tab && t.request.InFlight() {
+			t.request.Set(resultState(err))
+			t.cancelRequest = nil
+			t.unread = true
+			t.messages[len(t.messages)-1] = result
+		}
+	}
+	return false
+}
+
+func (m model) TabBarView() string {
+	if len(m.tabs) < 2 {
+		return ""
+	}
+
+	style := lipgloss.NewStyle().Foreground(Color(tabColor)).Padding(0, 1)
+	activeStyle := style.Copy().Foreground(Color(activeTabColor)).Bold(true)
+
+	var tabs []string
+	for i, t := range m.tabs {
+		title := t.session.Title
+		if i == m.activeTab {
+			title = currentSession.Title
+		}
+		if title == "" {
+			title = "New chat"
+		}
+
+		label := fmt.Sprintf("%d %s", i+1, truncate(title, 16))
+		switch {
+		case i != m.activeTab && t.request.InFlight():
+			label += " " + waitingMarker
+		case t.unread:
+			label += " " + unreadMarker
+		}
+
+		if i == m.activeTab {
+			tabs = append(tabs, activeStyle.Render(label))
+		} else {
+			tabs = append(tabs, style.Render(label))
+		}
+	}
+
+	separator := "│"
+	if cfg.Accessible {
+		separator = accessibleTabSeparator
+	}
+	return strings.Join(tabs, separator)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const tagPrefix = "#"
+
+func init() {
+	registerCommand(command{
+		name:        "tag",
+		usage:       "/tag <tag>... | -<tag>",
+		description: "Tag the current session, a leading - removes the tag",
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				if len(currentSession.Tags) == 0 {
+					AddNotice(m, "Usage: /tag <tag>... | -<tag>")
+				} else {
+					AddNotice(m, "Tags: "+FormatTags(currentSession.Tags))
+				}
+				return nil
+			}
+
+			for _, tag := range strings.Fields(args) {
+				if name, ok := strings.CutPrefix(tag, "-"); ok {
+					currentSession.RemoveTag(name)
+				} else {
+					currentSession.AddTag(tag)
+				}
+			}
+
+			SaveCurrentSession()
+			m.sidebar.Refresh()
+
+			if len(chatMessages) == 0 {
+				AddNotice(m, "Tags: "+FormatTags(currentSession.Tags)+", saved with the first reply")
+			} else {
+				AddNotice(m, "Tags: "+FormatTags(currentSession.Tags))
+			}
+			return nil
+		},
+	})
+
+	registerCommand(command{
+		name:        "tags",
+		usage:       "/tags [tag]",
+		description: "List tags, or show only sessions with a tag in the sidebar",
+		run: func(m *model, args string) tea.Cmd {
+			m.sidebar.tag = normalizeTag(args)
+			m.sidebar.Refresh()
+
+			if m.sidebar.tag == "" {
+				AddNotice(m, TagCounts(ListSessions()))
+				return nil
+			}
+
+			if !m.sidebar.visible {
+				ToggleSidebar(m)
+			}
+			AddNotice(m, fmt.Sprintf("Showing sessions tagged %s, /tags shows all again", tagPrefix+m.sidebar.tag))
+			return nil
+		},
+	})
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), tagPrefix))
+}
+
+func (s *session) AddTag(tag string) {
+	tag = normalizeTag(tag)
+	if tag != "" && !s.HasTag(tag) {
+		s.Tags = append(s.Tags, tag)
+		sort.Strings(s.Tags)
+	}
+}
+
+func (s *session) RemoveTag(tag string) {
+	tag = normalizeTag(tag)
+	s.Tags = slices.DeleteFunc(s.Tags, func(t string) bool { return t == tag })
+}
+
+func (s session) HasTag(tag string) bool {
+	return slices.Contains(s.Tags, normalizeTag(tag))
+}
+
+func FormatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = tagPrefix + tag
+	}
+	return strings.Join(formatted, " ")
+}
+
+// TagCounts lists every tag used in sessions with the number of sessions
+// carrying it, most used first.
+func TagCounts(sessions []session) string {
+	counts := map[string]int{}
+	for _, s := range sessions {
+		for _, tag := range s.Tags {
+			counts[tag]++
+		}
+	}
+	if len(counts) == 0 {
+		return "No tagged sessions, tag this one with /tag <tag>"
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	lines := make([]string, len(tags))
+	for i, tag := range tags {
+		lines[i] = fmt.Sprintf("%s (%d)", tagPrefix+tag, counts[tag])
+	}
+	return strings.Join(lines, "\n")
+}
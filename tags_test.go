@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestTagCounts(t *testing.T) {
+	if got := TagCounts([]session{{ID: "a"}}); got != "No tagged sessions, tag this one with /tag <tag>" {
+		t.Errorf("TagCounts without tags = %q", got)
+	}
+
+	sessions := []session{
+		{Tags: []string{"work", "go"}},
+		{Tags: []string{"go"}},
+		{Tags: []string{"home"}},
+		{},
+	}
+	want := "#go (2)\n#home (1)\n#work (1)"
+	if got := TagCounts(sessions); got != want {
+		t.Errorf("TagCounts = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme sets the role indicators and colors of the transcript. A prefix can
+// be any text, e.g. "You: ", or empty for none.
+type Theme struct {
+	PromptPrefix      string `json:"prompt_prefix"`
+	PromptColor       string `json:"prompt_color"`
+	PromptTextColor   string `json:"prompt_text_color"`
+	ResponsePrefix    string `json:"response_prefix"`
+	ResponseColor     string `json:"response_color"`
+	ResponseTextColor string `json:"response_text_color"`
+
+	// Spinners by name, see spinnerTypes, or "none" for no animation. An
+	// empty spinner is the icon set's
+	Spinner            string `json:"spinner"`
+	SpinnerColor       string `json:"spinner_color"`
+	StatusSpinner      string `json:"status_spinner"`
+	StatusSpinnerColor string `json:"status_spinner_color"`
+}
+
+func DefaultTheme() Theme {
+	return Theme{
+		PromptPrefix:      promptPrefix,
+		PromptColor:       promptColor,
+		PromptTextColor:   promptTextColor,
+		ResponsePrefix:    responsePrefix,
+		ResponseColor:     responseColor,
+		ResponseTextColor: responseTextColor,
+		SpinnerColor:      spinnerColor,
+		StatusSpinner:     "line",
+	}
+}
+
+// messageWidth is the width left for message text after prefix, keeping one
+// column free at the edge.
+func messageWidth(prefix string) int {
+	return textWidth() - 1 - lipgloss.Width(prefix)
+}
+
+const themesDirName = "themes"
+
+func init() {
+	registerCommand(command{
+		name:        "theme",
+		usage:       "/theme [name]",
+		description: "Switch the theme, or list the installed ones",
+		complete:    ThemeNames,
+		run: func(m *model, args string) tea.Cmd {
+			if args == "" {
+				AddNotice(m, "Themes: "+strings.Join(ThemeNames(""), ", "))
+				return nil
+			}
+			if m.request.InFlight() {
+				AddNotice(m, "Wait for the current reply before switching themes")
+				return nil
+			}
+
+			t, err := LoadTheme(args)
+			if err != nil {
+				AddNotice(m, err.Error())
+				return nil
+			}
+			m.SwitchTheme(t)
+			AddNotice(m, "Switched to the "+args+" theme")
+			return nil
+		},
+	})
+}
+
+// Themes shipped with bubblechat. Installed themes are JSON files in the
+// themes directory, holding the Theme fields to change from the default.
+func builtinThemes() map[string]Theme {
+	themes := map[string]Theme{}
+	add := func(name string, prompt, promptText, response, responseText, spinner string) {
+		t := DefaultTheme()
+		t.PromptColor, t.PromptTextColor = prompt, promptText
+		t.ResponseColor, t.ResponseTextColor = response, responseText
+		t.SpinnerColor = spinner
+		themes[name] = t
+	}
+
+	themes["default"] = DefaultTheme()
+	add("nord", "#b48ead", "#eceff4", "#88c0d0", "#d8dee9", "#81a1c1")
+	add("gruvbox", "#d3869b", "#fbf1c7", "#8ec07c", "#ebdbb2", "#fe8019")
+	add("light", "#8839ef", "#4c4f69", "#179299", "#5c5f77", "#d20f39")
+	// The terminal's own colors
+	add("mono", "", "", "", "", "")
+
+	return themes
+}
+
+func themesDir() string {
+	return filepath.Join(configDir(), themesDirName)
+}
+
+// ThemeNames lists the built-in and installed themes starting with prefix.
+func ThemeNames(prefix string) []string {
+	var names []string
+	for name := range builtinThemes() {
+		names = append(names, name)
+	}
+	paths, _ := filepath.Glob(filepath.Join(themesDir(), "*.json"))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	names = slices.DeleteFunc(names, func(name string) bool { return !strings.HasPrefix(name, prefix) })
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme finds a theme by name. Installed themes win over built-in ones.
+func LoadTheme(name string) (Theme, error) {
+	data, err := os.ReadFile(filepath.Join(themesDir(), name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		if t, ok := builtinThemes()[name]; ok {
+			return t, nil
+		}
+		return Theme{}, fmt.Errorf("no theme %q, installed themes are %s", name, strings.Join(ThemeNames(""), ", "))
+	}
+	if err != nil {
+		return Theme{}, err
+	}
+
+	t := DefaultTheme()
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// ApplyThemeStyles builds the transcript styles and spinners from the theme.
+func (m *model) ApplyThemeStyles() {
+	m.promptStyle = StyleFromColor(cfg.Theme.PromptColor).Bold(cfg.Accessible)
+	m.promptTextStyle = StyleFromColor(cfg.Theme.PromptTextColor)
+	m.responseStyle = StyleFromColor(cfg.Theme.ResponseColor).Bold(cfg.Accessible)
+	m.responseTextStyle = StyleFromColor(cfg.Theme.ResponseTextColor)
+	m.spinner = NewSpinner()
+	m.header.statusSpinner.Style = StyleFromColor(cfg.Theme.StatusSpinnerColor)
+}
+
+// SwitchTheme makes t the theme and renders every tab's transcript again.
+func (m *model) SwitchTheme(t Theme) {
+	cfg.Theme = t
+	ApplyIcons()
+	ApplyAccessibility()
+	m.ApplyThemeStyles()
+	m.RerenderTabs()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// Rough average for English text, close enough for a gauge
+	charsPerToken = 4
+	// Role and separators of each message in the chat format
+	tokensPerMessage = 4
+)
+
+// EstimateTokens guesses the token count of text without a tokenizer.
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// SizeTokens guesses the token count of a text file of size bytes.
+func SizeTokens(size int64) int {
+	return int((size + charsPerToken - 1) / charsPerToken)
+}
+
+// ContextTokens estimates the prompt size of sending draft after history.
+func ContextTokens(history []openai.ChatCompletionMessage, draft string) int {
+	total := 0
+	for _, msg := range history {
+		total += tokensPerMessage + EstimateTokens(msg.Content)
+	}
+	if draft != "" {
+		total += tokensPerMessage + EstimateTokens(draft)
+	}
+	return total
+}
+
+// contextWindow returns the context window of model, 0 when unknown.
+func contextWindow(model string) int {
+	info, _ := LookupModel(model)
+	return info.ContextWindow
+}
+
+// Overflows reports whether tokens exceed the context window of model. Unknown
+// models never do.
+func Overflows(model string, tokens int) bool {
+	window := contextWindow(model)
+	return window > 0 && tokens > window
+}
+
+func TokenGauge(model string, tokens int) string {
+	window := contextWindow(model)
+	if window == 0 {
+		return fmt.Sprintf("~%s tokens", formatCount(tokens))
+	}
+	return fmt.Sprintf("~%s / %s", formatCount(tokens), shortCount(window))
+}
+
+// formatCount adds thousands separators, e.g. 3,214.
+func formatCount(n int) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// OverflowWarning is shown when a message is sent that likely does not fit.
+func OverflowWarning(model string, tokens int) string {
+	return fmt.Sprintf("This message brings the conversation to ~%s tokens, over the %s context window of %s",
+		formatCount(tokens), shortCount(contextWindow(model)), model)
+}
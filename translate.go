@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Translation mode sends each input to be translated on its own, with a
+// system prompt for translating and none of the conversation, and keeps the
+// translations out of it too. The detected source language labels each
+// result.
+
+const translateSystemPrompt = "You are a translator. Translate the text the user sends into %s. " +
+	"Keep its meaning, tone and formatting, and leave code, names and URLs as they are. " +
+	`Reply with a JSON object: {"source_language": "<English name of the language of the text>", "translation": "<the translation>"}.`
+
+type translationMsg struct {
+	tab         int
+	language    string
+	source      string
+	translation string
+	latency     time.Duration
+	err         error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "translate",
+		usage:       "/translate <language> | off",
+		description: "Translate the following inputs instead of chatting",
+		complete: func(prefix string) []string {
+			return commands["lang"].complete(prefix)
+		},
+		run: func(m *model, args string) tea.Cmd {
+			switch args {
+			case "":
+				if m.translateTo == "" {
+					AddNotice(m, "Usage: /translate <language>, e.g. /translate sv")
+				} else {
+					AddNotice(m, "Translating to "+languageName(m.translateTo)+", /translate off leaves")
+				}
+			case replyLanguageOff:
+				m.translateTo = ""
+				AddNotice(m, "Translation mode off")
+			default:
+				m.translateTo = args
+				AddNotice(m, "Translating inputs to "+languageName(args)+", /translate off leaves")
+			}
+			return nil
+		},
+	})
+}
+
+// Translate shows text with a placeholder for its translation and requests
+// it.
+func Translate(m *model, text string) tea.Cmd {
+	m.messages = append(m.messages, m.PromptMessage(currentSession, text, -1), m.PlaceholderMessage())
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+
+	m.SetRequest(requestSending)
+	requestCtx, cancel := context.WithCancel(ctx)
+	m.cancelRequest = cancel
+
+	return tea.Batch(m.StartSpinner(), TranslateCmd(requestCtx, m.ActiveTabID(), text, m.translateTo))
+}
+
+// TranslateCmd asks the model for the translation of text into language.
+func TranslateCmd(ctx context.Context, tab int, text, language string) tea.Cmd {
+	model := modelName
+
+	return func() tea.Msg {
+		req := openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: fmt.Sprintf(translateSystemPrompt, languageName(language))},
+				UserMessage(text),
+			},
+		}
+		if SupportsJSONMode(model) {
+			req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		}
+
+		start := time.Now()
+		resp, err := callChat(ctx, req)
+		if err == nil && len(resp.Choices) == 0 {
+			err = errNoChoices
+		}
+		msg := translationMsg{tab: tab, language: language, latency: time.Since(start), err: err}
+		if err != nil {
+			return msg
+		}
+
+		msg.source, msg.translation = parseTranslation(resp.Choices[0].Message.Content)
+		return msg
+	}
+}
+
+// parseTranslation reads the JSON reply. Models that answer with the bare
+// translation get it back without a source language.
+func parseTranslation(content string) (source, translation string) {
+	var reply struct {
+		SourceLanguage string `json:"source_language"`
+		Translation    string `json:"translation"`
+	}
+	trimmed := strings.TrimSpace(content)
+	trimmed = strings.TrimPrefix(strings.TrimSuffix(trimmed, "```"), "```json")
+	if err := json.Unmarshal([]byte(trimmed), &reply); err != nil || reply.Translation == "" {
+		return "", content
+	}
+	return reply.SourceLanguage, reply.Translation
+}
+
+// TranslationLabel names the source and target language of a translation.
+func TranslationLabel(msg translationMsg) string {
+	return cmp.Or(msg.source, "unknown language") + " → " + languageName(msg.language)
+}
+
+// RenderTranslation renders a translation with its label below it.
+func (m model) RenderTranslation(translation, label string) string {
+	indent := strings.Repeat(" ", lipgloss.Width(ResponsePrefix()))
+	return m.RenderResponse(translation) + "\n" + m.noticeStyle.Render(indent+label)
+}
+
+// HandleTranslation replaces the placeholder with the translation, in the
+// tab it was requested in.
+func HandleTranslation(m *model, msg translationMsg) tea.Cmd {
+	if errors.Is(msg.err, context.Canceled) {
+		return nil
+	}
+
+	var result Message
+	if msg.err == nil {
+		result = m.TranslationMessage(msg.translation, TranslationLabel(msg))
+	}
+	if !m.ShowResult(msg.tab, result, msg.err) {
+		return nil
+	}
+	m.header.RecordLatency(msg.latency)
+	return m.SendQueued()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Replies arrive whole, so with typewriter_speed set they are revealed a few
+// characters at a time instead, like a streamed reply. The cancel key shows
+// the rest at once.
+
+const typingFrameInterval = 30 * time.Millisecond
+
+// typewriter is a reply being typed out at index at of m.messages. shown is
+// what the block there was last rendered as, so a transcript rebuilt in
+// between, or another tab, stops the typing rather than being overwritten.
+type typewriter struct {
+	id     int
+	at     int
+	source []rune
+	typed  int
+	shown  string
+	block  Message
+}
+
+// Each typewriter has its own id so the ticks of a finished one are dropped.
+var typewriterCount int
+
+type typewriterTickMsg struct {
+	id int
+}
+
+// typingStep is the number of characters typed per frame at speed characters
+// per second.
+func typingStep(speed int) int {
+	return max(1, int(float64(speed)*typingFrameInterval.Seconds()))
+}
+
+func typingTick(id int) tea.Cmd {
+	return tea.Tick(typingFrameInterval, func(time.Time) tea.Msg {
+		return typewriterTickMsg{id: id}
+	})
+}
+
+// TypeOut starts typing out the reply rendered at index at of m.messages,
+// whose markdown is source.
+func (m *model) TypeOut(at int, source string) tea.Cmd {
+	m.FinishTyping()
+	if cfg.TypewriterSpeed <= 0 || !Animated() {
+		return nil
+	}
+
+	typewriterCount++
+	m.typing = &typewriter{id: typewriterCount, at: at, source: []rune(source), block: m.messages[at]}
+	return m.typeMore()
+}
+
+// HandleTypewriterTick types the next characters of the reply.
+func HandleTypewriterTick(m *model, msg typewriterTickMsg) tea.Cmd {
+	t := m.typing
+	if t == nil || msg.id != t.id {
+		return nil
+	}
+	if t.at >= len(m.messages) || m.messages[t.at].Rendered != t.shown {
+		m.typing = nil
+		if m.request == requestTyping {
+			m.SetRequest(requestDone)
+		}
+		return nil
+	}
+	return m.typeMore()
+}
+
+func (m *model) typeMore() tea.Cmd {
+	t := m.typing
+	t.typed = min(t.typed+typingStep(cfg.TypewriterSpeed), len(t.source))
+	if t.typed == len(t.source) {
+		m.FinishTyping()
+		return nil
+	}
+
+	following := m.viewport.AtBottom()
+	t.shown = m.RenderPartialReply(string(t.source[:t.typed]))
+	m.messages[t.at].Rendered = t.shown
+	UpdateViewport(m)
+	if following {
+		m.viewport.GotoBottom()
+	}
+	return typingTick(t.id)
+}
+
+// FinishTyping shows the whole reply being typed, if any.
+func (m *model) FinishTyping() {
+	t := m.typing
+	if t == nil {
+		return
+	}
+	m.typing = nil
+	if m.request == requestTyping {
+		m.SetRequest(requestDone)
+	}
+	if t.at >= len(m.messages) || m.messages[t.at].Rendered != t.shown {
+		return
+	}
+
+	following := m.viewport.AtBottom()
+	m.messages[t.at] = t.block
+	UpdateViewport(m)
+	if following {
+		m.viewport.GotoBottom()
+	}
+}
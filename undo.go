@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DelaySend holds back the request of cmd for the configured grace period,
+// during which the send can be undone.
+func (m *model) DelaySend(requestCtx context.Context, cmd tea.Cmd) tea.Cmd {
+	grace := time.Duration(cfg.UndoSendSeconds * float64(time.Second))
+	m.undoUntil = time.Now().Add(grace)
+	tab := m.ActiveTabID()
+
+	return func() tea.Msg {
+		select {
+		case <-time.After(grace):
+			return cmd()
+		case <-requestCtx.Done():
+			return responseMsg{tab: tab, err: requestCtx.Err()}
+		}
+	}
+}
+
+// UndoSend takes back a message still within its grace period and puts it
+// back into the textarea.
+func UndoSend(m *model) {
+	if !m.request.InFlight() || m.cancelRequest == nil || time.Now().After(m.undoUntil) {
+		return
+	}
+
+	m.cancelRequest()
+	m.cancelRequest = nil
+	m.SetRequest(requestIdle)
+	m.undoUntil = time.Time{}
+
+	if message, ok := DropExchange(m); ok {
+		m.textarea.SetValue(message)
+	}
+	UpdateViewport(m)
+	m.viewport.GotoBottom()
+}
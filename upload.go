@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	gistsURL      = "https://api.github.com/gists"
+	uploadTimeout = 30 * time.Second
+)
+
+type uploadMsg struct {
+	url string
+	err error
+}
+
+func init() {
+	registerCommand(command{
+		name:        "share",
+		usage:       "/share",
+		description: "Upload the conversation as HTML and copy the link",
+		run: func(m *model, args string) tea.Cmd {
+			if len(chatMessages) == 0 {
+				AddNotice(m, "Nothing to share yet")
+				return nil
+			}
+
+			page, err := ExportHTML(currentSession, chatMessages)
+			if err != nil {
+				AddNotice(m, "Could not export: "+err.Error())
+				return nil
+			}
+
+			m.confirmAction = UploadCmd(currentSession, page)
+			m.modal = UploadConfirmation(currentSession, chatMessages, page)
+			return nil
+		},
+	})
+}
+
+// UploadConfirmation describes what /share is about to upload and where.
+func UploadConfirmation(s session, history []openai.ChatCompletionMessage, page []byte) string {
+	destination := "a secret GitHub gist"
+	if cfg.PasteURL != "" {
+		destination = cfg.PasteURL
+	}
+
+	title := s.Title
+	if title == "" {
+		title = sessionTitle(history)
+	}
+
+	return fmt.Sprintf(
+		"Upload this conversation?\n\n%q\n%d messages, %.1f KB of HTML\nto %s\n\nPress y to upload, any other key to cancel.",
+		title, len(history), float64(len(page))/1024, destination,
+	)
+}
+
+// UploadCmd uploads an exported page to the configured paste service, or a
+// gist by default, and copies the resulting link to the clipboard.
+func UploadCmd(s session, page []byte) tea.Cmd {
+	return func() tea.Msg {
+		var url string
+		var err error
+		if cfg.PasteURL != "" {
+			url, err = uploadPaste(cfg.PasteURL, page)
+		} else {
+			url, err = uploadGist(s, page)
+		}
+		if err != nil {
+			return uploadMsg{err: err}
+		}
+
+		if err := clipboard.WriteAll(url); err != nil {
+			return uploadMsg{url: url, err: fmt.Errorf("uploaded but could not copy link: %w", err)}
+		}
+		return uploadMsg{url: url}
+	}
+}
+
+func uploadGist(s session, page []byte) (string, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return "", errors.New("GITHUB_TOKEN is not set")
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"description": s.Title,
+		"public":      false,
+		"files": map[string]any{
+			"bubblechat-" + s.ID + ".html": map[string]string{"content": string(page)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, gistsURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := (&http.Client{Timeout: uploadTimeout}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil {
+		return "", err
+	}
+	return gist.HTMLURL, nil
+}
+
+// uploadPaste posts the page as the request body. Paste services like this
+// answer with the link as plain text.
+func uploadPaste(url string, page []byte) (string, error) {
+	resp, err := (&http.Client{Timeout: uploadTimeout}).Post(url, "text/html; charset=utf-8", bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	link := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(link, "http") {
+		return "", fmt.Errorf("%s did not return a link", url)
+	}
+	return link, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	usageFileName = "usage.json"
+	dateLayout    = "2006-01-02"
+
+	statusBarColor = "#636363"
+	warningColor   = "#e88388"
+)
+
+func RequestCost(model string, usage openai.Usage) float64 {
+	info, ok := LookupModel(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*info.InputPrice + float64(usage.CompletionTokens)*info.OutputPrice) / 1e6
+}
+
+// dailyUsage is persisted so the budget covers every session of the day.
+type dailyUsage struct {
+	Date string  `json:"date"`
+	Cost float64 `json:"cost"`
+}
+
+func usagePath() string {
+	return filepath.Join(configDir(), usageFileName)
+}
+
+func today() string {
+	return time.Now().Format(dateLayout)
+}
+
+func LoadDailyUsage() dailyUsage {
+	usage := dailyUsage{Date: today()}
+
+	data, err := os.ReadFile(usagePath())
+	if err != nil {
+		return usage
+	}
+
+	var stored dailyUsage
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Date != usage.Date {
+		return usage
+	}
+	return stored
+}
+
+func (u *dailyUsage) Add(cost float64) {
+	if u.Date != today() {
+		*u = dailyUsage{Date: today()}
+	}
+	u.Cost += cost
+
+	data, _ := json.Marshal(u)
+	if err := os.MkdirAll(configDir(), 0o755); err != nil {
+		log.Printf("Error saving usage: %v", err)
+		return
+	}
+	if err := os.WriteFile(usagePath(), data, 0o644); err != nil {
+		log.Printf("Error saving usage: %v", err)
+	}
+}
+
+type statusBarModel struct {
+	rateLimit    openai.RateLimitHeaders
+	sessionCost  float64
+	daily        dailyUsage
+	budgetWarned bool
+	tokens       int
+	style        lipgloss.Style
+	warningStyle lipgloss.Style
+}
+
+func NewStatusBar() statusBarModel {
+	return statusBarModel{
+		daily:        LoadDailyUsage(),
+		style:        lipgloss.NewStyle().Foreground(Color(statusBarColor)).PaddingLeft(1),
+		warningStyle: lipgloss.NewStyle().Foreground(Color(warningColor)),
+	}
+}
+
+// Record adds a finished request and reports whether it brought today's spend
+// to the configured budget for the first time.
+func (s *statusBarModel) Record(model string, usage openai.Usage, rateLimit openai.RateLimitHeaders) bool {
+	if rateLimit.LimitRequests > 0 || rateLimit.LimitTokens > 0 {
+		s.rateLimit = rateLimit
+	}
+
+	cost := RequestCost(model, usage)
+	s.sessionCost += cost
+	s.daily.Add(cost)
+
+	if s.OverBudget() && !s.budgetWarned {
+		s.budgetWarned = true
+		return true
+	}
+	return false
+}
+
+// OverBudget reports whether today's spend reached the configured budget.
+func (s statusBarModel) OverBudget() bool {
+	return cfg.DailyBudget > 0 && s.daily.Cost >= cfg.DailyBudget
+}
+
+func (s statusBarModel) View() string {
+	var parts []string
+
+	gauge := TokenGauge(modelName, s.tokens)
+	if Overflows(modelName, s.tokens) {
+		gauge = s.warningStyle.Render(gauge)
+	}
+	parts = append(parts, gauge)
+
+	if rl := s.rateLimit; rl.LimitRequests > 0 {
+		parts = append(parts, fmt.Sprintf("req %d/%d", rl.RemainingRequests, rl.LimitRequests))
+	}
+	if rl := s.rateLimit; rl.LimitTokens > 0 {
+		parts = append(parts, fmt.Sprintf("tok %s/%s", shortCount(rl.RemainingTokens), shortCount(rl.LimitTokens)))
+	}
+
+	cost := fmt.Sprintf("$%.4f session · $%.4f today", s.sessionCost, s.daily.Cost)
+	if cfg.DailyBudget > 0 {
+		cost += fmt.Sprintf(" / $%.2f", cfg.DailyBudget)
+		if s.OverBudget() {
+			cost = s.warningStyle.Render(cost)
+		}
+	}
+	parts = append(parts, cost)
+
+	return s.style.Render(strings.Join(parts, " · "))
+}
+
+func shortCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%dk", n/1000)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func BudgetWarning(spent float64) string {
+	return fmt.Sprintf(
+		"Daily budget reached\n\nToday's spend is $%.4f, at or above your budget of $%.2f.\n\nPress any key to continue.",
+		spent, cfg.DailyBudget,
+	)
+}
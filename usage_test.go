@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestOverBudget(t *testing.T) {
+	saved := cfg.DailyBudget
+	t.Cleanup(func() { cfg.DailyBudget = saved })
+
+	tests := []struct {
+		budget, spent float64
+		want          bool
+	}{
+		{0, 100, false},
+		{1.5, 0, false},
+		{1.5, 1.49, false},
+		{1.5, 1.5, true},
+		{1.5, 2, true},
+	}
+
+	for _, tt := range tests {
+		cfg.DailyBudget = tt.budget
+		s := statusBarModel{daily: dailyUsage{Cost: tt.spent}}
+		if got := s.OverBudget(); got != tt.want {
+			t.Errorf("OverBudget with $%.2f of $%.2f = %v, want %v", tt.spent, tt.budget, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bubblechat/vectorstore"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// With vector_store set, /add-dir also embeds the chunks it indexes into that
+// store, and prompts are matched to them by embedding rather than by shared
+// words. A store in a file or on a server keeps them across sessions.
+
+var vectors vectorstore.Store
+
+// OpenVectorStore opens the configured vector store, if any.
+func OpenVectorStore() error {
+	if cfg.VectorStore == "" {
+		return nil
+	}
+	store, err := vectorstore.Open(cfg.VectorStore)
+	if err != nil {
+		return fmt.Errorf("vector_store: %w", err)
+	}
+	vectors = store
+	return nil
+}
+
+// EmbedChunks embeds chunks into the vector store, replacing what it held of
+// their sources before.
+func EmbedChunks(ctx context.Context, chunks []chunk) (openai.Usage, error) {
+	var usage openai.Usage
+
+	parts := map[string]int{}
+	docs := make([]vectorstore.Document, len(chunks))
+	for i, c := range chunks {
+		if parts[c.source] == 0 {
+			if err := vectors.Delete(ctx, c.source); err != nil {
+				return usage, err
+			}
+		}
+		docs[i] = vectorstore.Document{ID: fmt.Sprintf("%s#%d", c.source, parts[c.source]), Source: c.source, Text: c.text}
+		parts[c.source]++
+	}
+
+	for start := 0; start < len(docs); start += embeddingBatchSize {
+		batch := docs[start:min(start+embeddingBatchSize, len(docs))]
+		inputs := make([]string, len(batch))
+		for i, doc := range batch {
+			inputs[i] = doc.Text
+		}
+
+		embeddings, batchUsage, err := Embed(ctx, cfg.EmbeddingModel, 0, inputs)
+		usage.PromptTokens += batchUsage.PromptTokens
+		usage.TotalTokens += batchUsage.TotalTokens
+		if err != nil {
+			return usage, err
+		}
+		if len(embeddings) != len(batch) {
+			return usage, fmt.Errorf("%d embeddings were returned for %d chunks", len(embeddings), len(batch))
+		}
+		for i := range batch {
+			batch[i].Embedding = embeddings[i]
+		}
+		if err := vectors.Upsert(ctx, batch); err != nil {
+			return usage, err
+		}
+	}
+	return usage, nil
+}
+
+// SearchVectors returns the k stored chunks closest to query, none when the
+// store is empty. Embedding the query is added to the status bar like any
+// other request.
+func SearchVectors(ctx context.Context, query string, k int) ([]chunk, error) {
+	if n, err := vectors.Count(ctx); err != nil || n == 0 {
+		return nil, err
+	}
+
+	embeddings, usage, err := Embed(ctx, cfg.EmbeddingModel, 0, []string{query})
+	notify(usageMsg{model: cfg.EmbeddingModel, usage: usage})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, errors.New("no embedding was returned for the prompt")
+	}
+	results, err := vectors.Search(ctx, embeddings[0], k)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]chunk, len(results))
+	for i, r := range results {
+		chunks[i] = chunk{source: r.Source, text: r.Text}
+	}
+	return chunks, nil
+}
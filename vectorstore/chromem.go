@@ -0,0 +1,100 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// chromemCollection is the collection documents are kept in.
+const chromemCollection = "bubblechat"
+
+// Chromem is a store in a local chromem-go database, a directory with a file
+// per document that is loaded into memory when opened. Unlike File, each
+// upsert writes only the documents it changes.
+type Chromem struct {
+	db         *chromem.DB
+	collection *chromem.Collection
+}
+
+// errNoEmbedding is returned when chromem-go is asked to embed text itself,
+// which would mean a document came without its embedding.
+var errNoEmbedding = errors.New("documents must be embedded before they are stored")
+
+// OpenChromem opens the database in the directory at a location like
+// chromem:///home/me/.cache/bubblechat, creating it if needed.
+func OpenChromem(u *url.URL) (Store, error) {
+	dir := u.Path
+	if dir == "" {
+		dir = u.Opaque
+	}
+	if dir == "" {
+		return nil, errors.New("chromem location needs a directory, e.g. chromem:///home/me/.cache/bubblechat")
+	}
+
+	db, err := chromem.NewPersistentDB(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	embed := func(context.Context, string) ([]float32, error) { return nil, errNoEmbedding }
+	collection, err := db.GetOrCreateCollection(chromemCollection, nil, embed)
+	if err != nil {
+		return nil, err
+	}
+	return &Chromem{db: db, collection: collection}, nil
+}
+
+func (c *Chromem) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	records := make([]chromem.Document, len(docs))
+	for i, doc := range docs {
+		if len(doc.Embedding) == 0 {
+			return errNoEmbedding
+		}
+		records[i] = chromem.Document{
+			ID:        doc.ID,
+			Metadata:  map[string]string{"source": doc.Source},
+			Embedding: doc.Embedding,
+			Content:   doc.Text,
+		}
+	}
+	return c.collection.AddDocuments(ctx, records, 1)
+}
+
+func (c *Chromem) Search(ctx context.Context, query []float32, k int) ([]Result, error) {
+	// chromem-go refuses to return more results than it holds
+	k = min(k, c.collection.Count())
+	if k == 0 {
+		return nil, nil
+	}
+
+	found, err := c.collection.QueryEmbedding(ctx, query, k, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(found))
+	for i, r := range found {
+		results[i] = Result{
+			Document: Document{ID: r.ID, Source: r.Metadata["source"], Text: r.Content, Embedding: r.Embedding},
+			Score:    r.Similarity,
+		}
+	}
+	return results, nil
+}
+
+func (c *Chromem) Delete(ctx context.Context, source string) error {
+	return c.collection.Delete(ctx, map[string]string{"source": source}, nil)
+}
+
+func (c *Chromem) Count(context.Context) (int, error) {
+	return c.collection.Count(), nil
+}
+
+func (c *Chromem) Close() error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package vectorstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File is a memory store saved to a JSONL file, one document per line, so
+// what was indexed is still there on the next start.
+type File struct {
+	*Memory
+	path string
+}
+
+// OpenFile loads the store saved at path, or starts an empty one there.
+func OpenFile(path string) (*File, error) {
+	f := &File{Memory: NewMemory(), path: path}
+
+	data, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var doc Document
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", path, line, err)
+		}
+		f.docs[doc.ID] = doc
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *File) Upsert(ctx context.Context, docs []Document) error {
+	if err := f.Memory.Upsert(ctx, docs); err != nil {
+		return err
+	}
+	return f.save()
+}
+
+func (f *File) Delete(ctx context.Context, source string) error {
+	if err := f.Memory.Delete(ctx, source); err != nil {
+		return err
+	}
+	return f.save()
+}
+
+// save writes the whole store to a temporary file and moves it in place, so
+// an interrupted save leaves the previous one intact.
+func (f *File) save() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, doc := range f.docs {
+		line, _ := json.Marshal(doc)
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
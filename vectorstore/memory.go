@@ -0,0 +1,60 @@
+package vectorstore
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is a store kept in memory and searched exhaustively, which is fast
+// enough for the few thousand chunks of a project directory.
+type Memory struct {
+	mu   sync.RWMutex
+	docs map[string]Document
+}
+
+func NewMemory() *Memory {
+	return &Memory{docs: map[string]Document{}}
+}
+
+func (m *Memory) Upsert(_ context.Context, docs []Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, doc := range docs {
+		m.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+func (m *Memory) Search(_ context.Context, query []float32, k int) ([]Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]Result, 0, len(m.docs))
+	for _, doc := range m.docs {
+		results = append(results, Result{Document: doc, Score: Cosine(query, doc.Embedding)})
+	}
+	return topK(results, k), nil
+}
+
+func (m *Memory) Delete(_ context.Context, source string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, doc := range m.docs {
+		if doc.Source == source {
+			delete(m.docs, id)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Count(context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs), nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,218 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Qdrant is a store in a collection of a Qdrant server, spoken to over its
+// REST API. The collection is created on the first upsert, sized to the
+// embeddings, and the API key is read from QDRANT_API_KEY.
+type Qdrant struct {
+	base       string
+	collection string
+	apiKey     string
+	client     *http.Client
+
+	mu      sync.Mutex
+	created bool
+}
+
+// errNotFound is a 404 from Qdrant, where the collection does not exist yet.
+var errNotFound = errors.New("not found")
+
+type qdrantPoint struct {
+	ID      string        `json:"id"`
+	Vector  []float32     `json:"vector,omitempty"`
+	Payload qdrantPayload `json:"payload"`
+	Score   float32       `json:"score,omitempty"`
+}
+
+type qdrantPayload struct {
+	DocID  string `json:"doc_id"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// OpenQdrant opens the collection at a location like
+// qdrant://localhost:6333/notes, or qdrant+https:// for TLS.
+func OpenQdrant(u *url.URL) (Store, error) {
+	collection := strings.Trim(u.Path, "/")
+	if collection == "" || u.Host == "" {
+		return nil, errors.New("qdrant location needs a host and a collection, e.g. qdrant://localhost:6333/notes")
+	}
+
+	scheme := "http"
+	if u.Scheme == "qdrant+https" {
+		scheme = "https"
+	}
+	return &Qdrant{
+		base:       scheme + "://" + u.Host,
+		collection: url.PathEscape(collection),
+		apiKey:     os.Getenv("QDRANT_API_KEY"),
+		client:     http.DefaultClient,
+	}, nil
+}
+
+func (q *Qdrant) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := q.ensureCollection(ctx, len(docs[0].Embedding)); err != nil {
+		return err
+	}
+
+	points := make([]qdrantPoint, len(docs))
+	for i, doc := range docs {
+		points[i] = qdrantPoint{
+			ID:      pointID(doc.ID),
+			Vector:  doc.Embedding,
+			Payload: qdrantPayload{DocID: doc.ID, Source: doc.Source, Text: doc.Text},
+		}
+	}
+	return q.do(ctx, http.MethodPut, "/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *Qdrant) Search(ctx context.Context, query []float32, k int) ([]Result, error) {
+	var resp struct {
+		Result []qdrantPoint `json:"result"`
+	}
+	err := q.do(ctx, http.MethodPost, "/points/search", map[string]any{
+		"vector":       query,
+		"limit":        k,
+		"with_payload": true,
+	}, &resp)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(resp.Result))
+	for i, p := range resp.Result {
+		results[i] = Result{
+			Document: Document{ID: p.Payload.DocID, Source: p.Payload.Source, Text: p.Payload.Text},
+			Score:    p.Score,
+		}
+	}
+	return results, nil
+}
+
+func (q *Qdrant) Delete(ctx context.Context, source string) error {
+	err := q.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]any{
+		"filter": map[string]any{
+			"must": []any{map[string]any{"key": "source", "match": map[string]any{"value": source}}},
+		},
+	}, nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (q *Qdrant) Count(ctx context.Context) (int, error) {
+	var resp struct {
+		Result struct {
+			Count int `json:"count"`
+		} `json:"result"`
+	}
+	err := q.do(ctx, http.MethodPost, "/points/count", map[string]any{"exact": true}, &resp)
+	if errors.Is(err, errNotFound) {
+		return 0, nil
+	}
+	return resp.Result.Count, err
+}
+
+func (q *Qdrant) Close() error {
+	return nil
+}
+
+// ensureCollection creates the collection for embeddings of size dimensions
+// unless it exists.
+func (q *Qdrant) ensureCollection(ctx context.Context, size int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.created {
+		return nil
+	}
+
+	err := q.do(ctx, http.MethodGet, "", nil, nil)
+	if errors.Is(err, errNotFound) {
+		err = q.do(ctx, http.MethodPut, "", map[string]any{
+			"vectors": map[string]any{"size": size, "distance": "Cosine"},
+		}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	q.created = true
+	return nil
+}
+
+// do sends a request to path under the collection and decodes the answer
+// into out, if any.
+func (q *Qdrant) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, q.base+"/collections/"+q.collection+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Status struct {
+				Error string `json:"error"`
+			} `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		if failure.Status.Error != "" {
+			return fmt.Errorf("qdrant: %s", failure.Status.Error)
+		}
+		return fmt.Errorf("qdrant returned %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pointID turns a document ID into the UUID Qdrant wants for a point.
+func pointID(id string) string {
+	h := sha1.Sum([]byte(id))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -0,0 +1,115 @@
+// Package vectorstore keeps embedded chunks of text and finds those closest
+// to the embedding of a query. Stores are opened by location: in memory, a
+// local file, a local chromem-go database, or a Qdrant collection. Backends for other databases, such as
+// pgvector, can be added with Register.
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+)
+
+// Document is an embedded chunk of text. Source is what it was taken from,
+// e.g. a file path, and ID is unique among all documents.
+type Document struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Result is a document found by a search, with its cosine similarity to the
+// query.
+type Result struct {
+	Document
+	Score float32
+}
+
+// Store is a collection of documents searchable by embedding.
+type Store interface {
+	// Upsert adds documents, replacing those with the same ID.
+	Upsert(ctx context.Context, docs []Document) error
+
+	// Search returns the k documents most similar to query, most similar
+	// first.
+	Search(ctx context.Context, query []float32, k int) ([]Result, error)
+
+	// Delete removes the documents taken from source.
+	Delete(ctx context.Context, source string) error
+
+	// Count is the number of documents.
+	Count(ctx context.Context) (int, error)
+
+	Close() error
+}
+
+// Opener opens a store from its location.
+type Opener func(location *url.URL) (Store, error)
+
+var backends = map[string]Opener{}
+
+// Register makes a backend available under a URL scheme, e.g. "pgvector" for
+// locations like pgvector://user@host/db.
+func Register(scheme string, open Opener) {
+	backends[scheme] = open
+}
+
+func init() {
+	Register("memory", func(*url.URL) (Store, error) { return NewMemory(), nil })
+	Register("file", func(u *url.URL) (Store, error) { return OpenFile(u.Path) })
+	Register("chromem", OpenChromem)
+	Register("qdrant", OpenQdrant)
+	Register("qdrant+https", OpenQdrant)
+}
+
+// Open opens the store at location: "memory" for one kept in memory, a path
+// for one saved to a file, or a URL whose scheme names a backend, e.g.
+// chromem:///home/me/.cache/bubblechat or qdrant://localhost:6333/notes.
+func Open(location string) (Store, error) {
+	if location == "" || location == "memory" {
+		return NewMemory(), nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" {
+		return OpenFile(location)
+	}
+	open, ok := backends[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown vector store %q", u.Scheme)
+	}
+	return open(u)
+}
+
+// Cosine is the cosine similarity of two vectors, 0 when either is zero or
+// their lengths differ.
+func Cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+// topK keeps the k results with the highest scores, highest first.
+func topK(results []Result, k int) []Result {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const warmUpPrompt = "Hi"
+
+type warmedUpMsg struct {
+	model string
+	err   error
+}
+
+// WarmUpEnabled reports whether models are loaded ahead of the first prompt.
+// Only local servers load models on demand, hosted APIs need no warm-up.
+func WarmUpEnabled() bool {
+	return cfg.WarmUp && provider.slowStart && replayPath == "" && joinAddr == ""
+}
+
+// WarmUpCmd sends a one token completion so the server loads model before
+// the first real prompt pays for it.
+func WarmUpCmd(model string) tea.Cmd {
+	if !WarmUpEnabled() {
+		return nil
+	}
+
+	return func() tea.Msg {
+		_, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model:     model,
+			Messages:  []openai.ChatCompletionMessage{UserMessage(warmUpPrompt)},
+			MaxTokens: 1,
+		})
+		return warmedUpMsg{model: model, err: err}
+	}
+}
+
+func HandleWarmedUp(m *model, msg warmedUpMsg) {
+	// A switch since started another warm-up
+	if msg.model != modelName {
+		return
+	}
+
+	m.header.warming = false
+	if msg.err != nil {
+		AddNotice(m, "Could not warm up "+msg.model+": "+msg.err.Error())
+		return
+	}
+	// The first reply is no slower than any other now
+	slowStartNoticed = true
+}